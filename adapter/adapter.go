@@ -0,0 +1,99 @@
+//Package adapter defines what a chain has to implement to take part in an atomic swap.
+//The swap tools of the different chains register an Adapter so the swap steps can be
+//driven without knowing the chain specifics.
+package adapter
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+//Contract identifies a swap contract on a chain
+type Contract struct {
+	//Address of the contract on the chain, a holding account, contract script address, ...
+	Address string `json:"address"`
+	//Refund contains the chain specific data needed to refund the contract, like a presigned refund transaction
+	Refund string `json:"refund,omitempty"`
+}
+
+//Audit is the verified content of a contract
+type Audit struct {
+	Contract Contract `json:"contract"`
+	//Amount is the decimal amount of Asset the contract holds
+	Amount string `json:"amount"`
+	//Asset is the chain specific name of what the contract holds, empty for the native coin of chains without assets
+	Asset            string    `json:"asset,omitempty"`
+	RecipientAddress string    `json:"recipient"`
+	RefundAddress    string    `json:"refundaddress"`
+	SecretHash       []byte    `json:"secrethash"`
+	Locktime         time.Time `json:"locktime"`
+}
+
+//Adapter performs the steps of an atomic swap on a single chain
+type Adapter interface {
+	//Initiate creates a new secret and locks amount in a contract the participant can redeem with it
+	Initiate(participantAddress string, amount string) (secret []byte, secretHash []byte, contract Contract, err error)
+	//Participate locks amount in a contract the initiator can redeem with the secret of the secret hash
+	Participate(initiatorAddress string, amount string, secretHash []byte) (contract Contract, err error)
+	//Redeem claims the funds of the contract with the secret
+	Redeem(contract Contract, secret []byte) (transactionID string, err error)
+	//Refund returns the funds of the contract after the locktime expired
+	Refund(contract Contract) (transactionID string, err error)
+	//Audit verifies the contract and returns what it contains
+	Audit(contract Contract) (Audit, error)
+	//ExtractSecret finds the secret in the redemption of the contract
+	ExtractSecret(contract Contract, secretHash []byte) (secret []byte, err error)
+}
+
+//Config is passed to the factory of an adapter
+type Config struct {
+	Testnet bool
+	//Key is the chain specific key or key reference used to sign, like a seed or an external signer specification
+	Key string
+	//HTTPClient is used for the requests to the chain backend when set, to configure timeouts, keep-alives or TLS.
+	//Adapters that do not talk http ignore it.
+	HTTPClient *http.Client
+}
+
+//Factory creates an adapter
+type Factory func(config Config) (Adapter, error)
+
+var (
+	factoriesLock sync.RWMutex
+	factories     = make(map[string]Factory)
+)
+
+//Register makes an adapter available under a chain name, it panics if the name is already registered
+func Register(chain string, factory Factory) {
+	factoriesLock.Lock()
+	defer factoriesLock.Unlock()
+	if _, exists := factories[chain]; exists {
+		panic(fmt.Sprintf("adapter: chain %s is already registered", chain))
+	}
+	factories[chain] = factory
+}
+
+//New creates the adapter registered for a chain
+func New(chain string, config Config) (Adapter, error) {
+	factoriesLock.RLock()
+	factory, ok := factories[chain]
+	factoriesLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("No adapter registered for chain %s", chain)
+	}
+	return factory(config)
+}
+
+//Chains returns the sorted names of the registered chains
+func Chains() (chains []string) {
+	factoriesLock.RLock()
+	defer factoriesLock.RUnlock()
+	for chain := range factories {
+		chains = append(chains, chain)
+	}
+	sort.Strings(chains)
+	return
+}
@@ -0,0 +1,261 @@
+package adapter
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/threefoldtech/atomicswap/swapcrypto"
+)
+
+//Leg is one chain of a swap as seen by one of the parties
+type Leg struct {
+	Adapter Adapter
+	//Address of the party on this chain, where it receives the counterparty's funds
+	Address string
+	//Counterparty is the address of the counterparty on this chain
+	Counterparty string
+	//Amount locked on this chain, for the Counter leg the minimum amount the counterparty has to lock
+	Amount string
+	//Asset the counterparty has to lock on the Counter leg, not checked when empty
+	Asset string
+}
+
+//AutoSwap drives both legs of a swap for one party: it locks its own funds on the Own leg and
+//receives the counterparty's funds on the Counter leg.
+type AutoSwap struct {
+	Own     Leg
+	Counter Leg
+	//Send passes a contract to the counterparty
+	Send func(contract Contract) error
+	//Receive returns the contract of the counterparty, ok is false if it is not available yet
+	Receive func() (contract Contract, ok bool, err error)
+	//PollInterval is the time between checks of the counterparty or the chains
+	PollInterval time.Duration
+	//MinimumLocktimeMargin is the minimum time between the locktime of the contract that can be redeemed first
+	//and the locktime of the other one, to make sure there is time to use a revealed secret
+	MinimumLocktimeMargin time.Duration
+	//Logf reports the progress
+	Logf func(format string, args ...interface{})
+	//Persist stores the progress of the swap, like the secret of the initiator that only exists in memory otherwise.
+	//It is called right after the own contract is created and whenever the result changes after that.
+	Persist func(result Result) error
+}
+
+//Result of an automated swap
+type Result struct {
+	OwnContract     Contract `json:"owncontract"`
+	CounterContract Contract `json:"countercontract"`
+	SecretHash      []byte   `json:"secrethash"`
+	//Secret is only known by the initiator until it redeems the counterparty's contract
+	Secret []byte `json:"secret,omitempty"`
+	//RedeemTransaction is set when the counterparty's contract was redeemed
+	RedeemTransaction string `json:"redeemtransaction,omitempty"`
+	//RefundTransaction is set when the own contract had to be refunded
+	RefundTransaction string `json:"refundtransaction,omitempty"`
+}
+
+//ErrRefunded is returned when the swap did not complete and the own contract was refunded
+var ErrRefunded = errors.New("The swap did not complete, the own contract was refunded")
+
+//errRedeemed is returned by refund when the counterparty redeemed the own contract before it could be refunded
+var errRedeemed = errors.New("The own contract was redeemed by the counterparty")
+
+func (s *AutoSwap) logf(format string, args ...interface{}) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+	}
+}
+
+//persist stores the result, a failure is only reported since the own funds are already locked
+func (s *AutoSwap) persist(result Result) {
+	if s.Persist == nil {
+		return
+	}
+	if err := s.Persist(result); err != nil {
+		s.logf("failed to persist the swap: %v", err)
+	}
+}
+
+//Initiate creates the secret, locks the own funds and redeems the counterparty's contract once it is
+//published and audited, revealing the secret. If the counterparty does not participate before the
+//locktime of the own contract, the own contract is refunded.
+func (s *AutoSwap) Initiate() (result Result, err error) {
+	secret, secretHash, ownContract, err := s.Own.Adapter.Initiate(s.Own.Counterparty, s.Own.Amount)
+	if err != nil {
+		return result, fmt.Errorf("Failed to initiate: %v", err)
+	}
+	result.OwnContract = ownContract
+	result.SecretHash = secretHash
+	result.Secret = secret
+	s.persist(result)
+	s.logf("initiated, contract %s", ownContract.Address)
+	if err = s.Send(ownContract); err != nil {
+		return result, fmt.Errorf("Failed to send the contract to the counterparty: %v", err)
+	}
+	ownAudit, err := s.Own.Adapter.Audit(ownContract)
+	if err != nil {
+		return result, fmt.Errorf("Failed to audit the own contract: %v", err)
+	}
+	//the counterparty has to participate in time for us to redeem before our own contract can be refunded
+	deadline := ownAudit.Locktime.Add(-s.MinimumLocktimeMargin)
+	for {
+		counterContract, ok, err := s.Receive()
+		if err != nil {
+			return result, fmt.Errorf("Failed to receive the counterparty's contract: %v", err)
+		}
+		if ok {
+			result.CounterContract = counterContract
+			s.persist(result)
+			break
+		}
+		if time.Now().After(deadline) {
+			s.logf("the counterparty did not participate in time")
+			return s.refund(result, ownAudit.Locktime)
+		}
+		time.Sleep(s.PollInterval)
+	}
+	counterAudit, err := s.Counter.Adapter.Audit(result.CounterContract)
+	if err != nil {
+		s.logf("the counterparty's contract is invalid: %v", err)
+		return s.refund(result, ownAudit.Locktime)
+	}
+	if err = s.checkCounterContract(counterAudit, secretHash); err != nil {
+		s.logf("the counterparty's contract is not acceptable: %v", err)
+		return s.refund(result, ownAudit.Locktime)
+	}
+	if counterAudit.Locktime.Add(s.MinimumLocktimeMargin).After(ownAudit.Locktime) {
+		s.logf("the counterparty's contract locktime %v is too close to ours", counterAudit.Locktime)
+		return s.refund(result, ownAudit.Locktime)
+	}
+	s.logf("counterparty contract %s audited, redeeming", result.CounterContract.Address)
+	result.RedeemTransaction, err = s.Counter.Adapter.Redeem(result.CounterContract, secret)
+	if err != nil {
+		return result, fmt.Errorf("Failed to redeem the counterparty's contract: %v", err)
+	}
+	s.persist(result)
+	s.logf("redeemed in transaction %s", result.RedeemTransaction)
+	return
+}
+
+//Participate audits the initiator's contract, locks the own funds with the same secret hash and waits
+//for the initiator to redeem them. The revealed secret is then used to redeem the initiator's contract.
+//If the initiator does not redeem before the locktime of the own contract, it is refunded.
+func (s *AutoSwap) Participate() (result Result, err error) {
+	s.logf("waiting for the initiator's contract")
+	for {
+		counterContract, ok, err := s.Receive()
+		if err != nil {
+			return result, fmt.Errorf("Failed to receive the initiator's contract: %v", err)
+		}
+		if ok {
+			result.CounterContract = counterContract
+			break
+		}
+		time.Sleep(s.PollInterval)
+	}
+	counterAudit, err := s.Counter.Adapter.Audit(result.CounterContract)
+	if err != nil {
+		return result, fmt.Errorf("The initiator's contract is invalid: %v", err)
+	}
+	if err = s.checkCounterContract(counterAudit, counterAudit.SecretHash); err != nil {
+		return result, fmt.Errorf("The initiator's contract is not acceptable: %v", err)
+	}
+	result.SecretHash = counterAudit.SecretHash
+	ownContract, err := s.Own.Adapter.Participate(s.Own.Counterparty, s.Own.Amount, counterAudit.SecretHash)
+	if err != nil {
+		return result, fmt.Errorf("Failed to participate: %v", err)
+	}
+	result.OwnContract = ownContract
+	s.persist(result)
+	s.logf("participated, contract %s", ownContract.Address)
+	ownAudit, err := s.Own.Adapter.Audit(ownContract)
+	if err != nil {
+		return result, fmt.Errorf("Failed to audit the own contract: %v", err)
+	}
+	if ownAudit.Locktime.Add(s.MinimumLocktimeMargin).After(counterAudit.Locktime) {
+		s.logf("the initiator's locktime %v leaves too little time after ours", counterAudit.Locktime)
+		return s.refund(result, ownAudit.Locktime)
+	}
+	if err = s.Send(ownContract); err != nil {
+		return result, fmt.Errorf("Failed to send the contract to the initiator: %v", err)
+	}
+	for {
+		secret, err := s.Own.Adapter.ExtractSecret(ownContract, counterAudit.SecretHash)
+		if err == nil {
+			s.logf("the initiator redeemed, redeeming the initiator's contract")
+			result.RedeemTransaction, err = s.Counter.Adapter.Redeem(result.CounterContract, secret)
+			if err != nil {
+				return result, fmt.Errorf("Failed to redeem the initiator's contract: %v", err)
+			}
+			result.Secret = secret
+			s.persist(result)
+			s.logf("redeemed in transaction %s", result.RedeemTransaction)
+			return result, nil
+		}
+		if time.Now().After(ownAudit.Locktime) {
+			s.logf("the initiator did not redeem in time")
+			result, err = s.refund(result, ownAudit.Locktime)
+			if err == errRedeemed {
+				//redeemed right before the locktime, the secret can be extracted now
+				continue
+			}
+			return result, err
+		}
+		time.Sleep(s.PollInterval)
+	}
+}
+
+//checkCounterContract verifies the counterparty's contract pays us at least the expected amount with the expected secret hash
+func (s *AutoSwap) checkCounterContract(audit Audit, secretHash []byte) error {
+	expected, ok := new(big.Rat).SetString(s.Counter.Amount)
+	if !ok || expected.Sign() <= 0 {
+		return fmt.Errorf("the expected amount %q of the counterparty's contract is invalid", s.Counter.Amount)
+	}
+	audited, ok := new(big.Rat).SetString(audit.Amount)
+	if !ok {
+		return fmt.Errorf("the contract holds an invalid amount %q", audit.Amount)
+	}
+	if audited.Cmp(expected) < 0 {
+		return fmt.Errorf("the contract holds %s instead of at least %s", audit.Amount, s.Counter.Amount)
+	}
+	if s.Counter.Asset != "" && audit.Asset != s.Counter.Asset {
+		return fmt.Errorf("the contract holds %s instead of %s", audit.Asset, s.Counter.Asset)
+	}
+	if audit.RecipientAddress != s.Counter.Address {
+		return fmt.Errorf("the recipient is %s instead of %s", audit.RecipientAddress, s.Counter.Address)
+	}
+	if audit.RefundAddress != s.Counter.Counterparty {
+		return fmt.Errorf("the contract refunds to %s instead of the counterparty %s", audit.RefundAddress, s.Counter.Counterparty)
+	}
+	if len(secretHash) != swapcrypto.SHA256.Size() || !swapcrypto.Equal(audit.SecretHash, secretHash) {
+		return fmt.Errorf("the secret hash is %x instead of %x", audit.SecretHash, secretHash)
+	}
+	if !time.Now().Add(s.MinimumLocktimeMargin).Before(audit.Locktime) {
+		return fmt.Errorf("the locktime %v is too close", audit.Locktime)
+	}
+	return nil
+}
+
+//refund waits for the locktime of the own contract and refunds it
+func (s *AutoSwap) refund(result Result, locktime time.Time) (Result, error) {
+	if wait := time.Until(locktime); wait > 0 {
+		s.logf("waiting %v for the locktime to refund", wait.Truncate(time.Second))
+		time.Sleep(wait)
+	}
+	for {
+		transaction, err := s.Own.Adapter.Refund(result.OwnContract)
+		if err == nil {
+			result.RefundTransaction = transaction
+			s.persist(result)
+			s.logf("refunded in transaction %s", transaction)
+			return result, ErrRefunded
+		}
+		if _, extractErr := s.Own.Adapter.ExtractSecret(result.OwnContract, result.SecretHash); extractErr == nil {
+			return result, errRedeemed
+		}
+		s.logf("refund failed, retrying: %v", err)
+		time.Sleep(s.PollInterval)
+	}
+}
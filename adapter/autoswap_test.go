@@ -0,0 +1,99 @@
+package adapter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/atomicswap/swapcrypto"
+)
+
+//fakeAdapter returns a fixed audit and records the contracts it creates
+type fakeAdapter struct {
+	audit        Audit
+	participated bool
+}
+
+func (a *fakeAdapter) Initiate(participantAddress string, amount string) (secret []byte, secretHash []byte, contract Contract, err error) {
+	secret, secretHash, err = swapcrypto.SHA256.GenerateSecret()
+	return secret, secretHash, Contract{Address: "own"}, err
+}
+
+func (a *fakeAdapter) Participate(initiatorAddress string, amount string, secretHash []byte) (Contract, error) {
+	a.participated = true
+	return Contract{Address: "own"}, nil
+}
+
+func (a *fakeAdapter) Redeem(contract Contract, secret []byte) (string, error) {
+	return "redeem", nil
+}
+
+func (a *fakeAdapter) Refund(contract Contract) (string, error) {
+	return "refund", nil
+}
+
+func (a *fakeAdapter) Audit(contract Contract) (Audit, error) {
+	return a.audit, nil
+}
+
+func (a *fakeAdapter) ExtractSecret(contract Contract, secretHash []byte) ([]byte, error) {
+	return nil, errors.New("not redeemed")
+}
+
+func TestParticipateRejectsUnderfundedContract(t *testing.T) {
+	_, secretHash, err := swapcrypto.SHA256.GenerateSecret()
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, tc := range []struct {
+		amount string
+		asset  string
+		ok     bool
+	}{
+		{"0.0000001", "XLM", false},
+		{"99.9999999", "XLM", false},
+		{"100", "BTC", false},
+		{"100.0000000", "XLM", true},
+	} {
+		own := &fakeAdapter{audit: Audit{Locktime: time.Now().Add(24 * time.Hour)}}
+		counter := &fakeAdapter{audit: Audit{
+			Amount:           tc.amount,
+			Asset:            tc.asset,
+			RecipientAddress: "me",
+			RefundAddress:    "them",
+			SecretHash:       secretHash,
+			Locktime:         time.Now().Add(48 * time.Hour),
+		}}
+		swap := AutoSwap{
+			Own:                   Leg{Adapter: own, Counterparty: "them", Amount: "1"},
+			Counter:               Leg{Adapter: counter, Address: "me", Counterparty: "them", Amount: "100", Asset: "XLM"},
+			Send:                  func(Contract) error { return errors.New("stop") },
+			Receive:               func() (Contract, bool, error) { return Contract{Address: "counter"}, true, nil },
+			MinimumLocktimeMargin: time.Hour,
+		}
+		_, err := swap.Participate()
+		assert.Error(t, err, tc.amount)
+		assert.Equal(t, tc.ok, own.participated, tc.amount)
+	}
+}
+
+func TestInitiatePersistsTheSecret(t *testing.T) {
+	own := &fakeAdapter{audit: Audit{Locktime: time.Now().Add(time.Hour)}}
+	var persisted []Result
+	swap := AutoSwap{
+		Own:     Leg{Adapter: own, Counterparty: "them", Amount: "1"},
+		Counter: Leg{Adapter: &fakeAdapter{}, Amount: "1"},
+		Send:    func(Contract) error { return errors.New("stop") },
+		Persist: func(result Result) error {
+			persisted = append(persisted, result)
+			return nil
+		},
+	}
+	result, err := swap.Initiate()
+	assert.Error(t, err)
+	if assert.Len(t, persisted, 1) {
+		assert.Equal(t, result.Secret, persisted[0].Secret)
+		assert.True(t, swapcrypto.SHA256.Verify(persisted[0].Secret, persisted[0].SecretHash))
+	}
+}
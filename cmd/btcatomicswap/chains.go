@@ -0,0 +1,157 @@
+// Copyright (c) 2018 The Rivine developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// utxoChain describes a bitcoin derived chain. Everything the swap commands
+// need to know about a chain is in here so supporting another chain that has
+// OP_SHA256 and OP_CHECKLOCKTIMEVERIFY only takes a new entry in utxoChains.
+type utxoChain struct {
+	name   string
+	symbol string
+
+	mainNetParams *chaincfg.Params
+	testNetParams *chaincfg.Params
+
+	// default wallet RPC ports
+	mainNetPort string
+	testNetPort string
+
+	// minFeePerKb is the minimum relay fee of the chain, it is used when the
+	// wallet reports a lower fee rate.
+	minFeePerKb btcutil.Amount
+
+	// forkID is set for chains that require SIGHASH_FORKID signatures over
+	// the BIP143 digest, like Bitcoin Cash.
+	forkID bool
+}
+
+var utxoChains = map[string]*utxoChain{
+	"btc": {
+		name:          "Bitcoin",
+		symbol:        "BTC",
+		mainNetParams: &chaincfg.MainNetParams,
+		testNetParams: &chaincfg.TestNet3Params,
+		mainNetPort:   "8332",
+		testNetPort:   "18332",
+		minFeePerKb:   1000,
+	},
+	"ltc": {
+		name:          "Litecoin",
+		symbol:        "LTC",
+		mainNetParams: newChainParams(&chaincfg.MainNetParams, "litecoin-mainnet", 0xdbb6c0fb, 0x30, 0x32, 0xb0, "ltc", 2),
+		testNetParams: newChainParams(&chaincfg.TestNet3Params, "litecoin-testnet4", 0xf1c8d2fd, 0x6f, 0x3a, 0xef, "tltc", 1),
+		mainNetPort:   "9332",
+		testNetPort:   "19332",
+		minFeePerKb:   10000,
+	},
+	"doge": {
+		name:          "Dogecoin",
+		symbol:        "DOGE",
+		mainNetParams: newChainParams(&chaincfg.MainNetParams, "dogecoin-mainnet", 0xc0c0c0c0, 0x1e, 0x16, 0x9e, "", 3),
+		testNetParams: newChainParams(&chaincfg.TestNet3Params, "dogecoin-testnet", 0xdcb7c1fc, 0x71, 0xc4, 0xf1, "", 1),
+		mainNetPort:   "22555",
+		testNetPort:   "44555",
+		minFeePerKb:   1000000,
+	},
+	"bch": {
+		name:          "Bitcoin Cash",
+		symbol:        "BCH",
+		mainNetParams: newChainParams(&chaincfg.MainNetParams, "bitcoincash-mainnet", 0xe8f3e1e3, 0x00, 0x05, 0x80, "", 145),
+		testNetParams: newChainParams(&chaincfg.TestNet3Params, "bitcoincash-testnet", 0xf4f3e5f4, 0x6f, 0xc4, 0xef, "", 1),
+		mainNetPort:   "8332",
+		testNetPort:   "18332",
+		minFeePerKb:   1000,
+		forkID:        true,
+	},
+}
+
+// newChainParams derives the network parameters of a bitcoin fork from the
+// bitcoin parameters, only the fields used for addresses and keys differ.
+func newChainParams(base *chaincfg.Params, name string, net uint32, pubKeyHashAddrID, scriptHashAddrID, privateKeyID byte, bech32HRP string, hdCoinType uint32) *chaincfg.Params {
+	params := *base
+	params.Name = name
+	params.Net = wire.BitcoinNet(net)
+	params.PubKeyHashAddrID = pubKeyHashAddrID
+	params.ScriptHashAddrID = scriptHashAddrID
+	params.PrivateKeyID = privateKeyID
+	params.Bech32HRPSegwit = bech32HRP
+	params.HDCoinType = hdCoinType
+	return &params
+}
+
+// chainNames returns the sorted names of the supported chains.
+func chainNames() string {
+	names := make([]string, 0, len(utxoChains))
+	for name := range utxoChains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// selectChain sets the chain and its network parameters.
+func selectChain(name string, testnet bool) error {
+	c, ok := utxoChains[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("unsupported chain %q, supported chains are %s", name, chainNames())
+	}
+	chain = c
+	chainParams = c.mainNetParams
+	if testnet {
+		chainParams = c.testNetParams
+	}
+	return nil
+}
+
+// walletPort returns the default wallet RPC port of the selected chain.
+func walletPort(params *chaincfg.Params) string {
+	switch params {
+	case chain.mainNetParams:
+		return chain.mainNetPort
+	case chain.testNetParams:
+		return chain.testNetPort
+	default:
+		return ""
+	}
+}
+
+// formatAmount formats an amount in the unit of the selected chain.
+func formatAmount(amount btcutil.Amount) string {
+	return strconv.FormatFloat(amount.ToBTC(), 'f', -1, 64) + " " + chain.symbol
+}
+
+// sigHashType returns the signature hash type used to sign contract inputs.
+func (c *utxoChain) sigHashType() txscript.SigHashType {
+	if c.forkID {
+		return txscript.SigHashAll | sigHashForkID
+	}
+	return txscript.SigHashAll
+}
+
+// sigHashForkID is the Bitcoin Cash replay protection flag.
+const sigHashForkID txscript.SigHashType = 0x40
+
+// signatureHash returns the hash to sign for input idx of tx spending the
+// contract script with the given value.
+func (c *utxoChain) signatureHash(tx *wire.MsgTx, idx int, script []byte, value int64) ([]byte, error) {
+	if c.forkID {
+		// SIGHASH_FORKID signatures use the BIP143 digest, with the fork
+		// id included in the hash type.
+		return txscript.CalcWitnessSigHash(script, txscript.NewTxSigHashes(tx), c.sigHashType(), tx, idx, value)
+	}
+	return txscript.CalcSignatureHash(script, txscript.SigHashAll, tx, idx)
+}
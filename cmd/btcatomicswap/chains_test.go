@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+// forkIDTestTx returns a transaction spending a contract output, like a
+// refund transaction with its locktime set.
+func forkIDTestTx() (tx *wire.MsgTx, script []byte, value int64) {
+	script, _ = hex.DecodeString("76a914" + "0202020202020202020202020202020202020202" + "88ac")
+	outScript, _ := hex.DecodeString("76a914" + "0303030303030303030303030303030303030303" + "88ac")
+	tx = wire.NewMsgTx(2)
+	var prevHash chainhash.Hash
+	copy(prevHash[:], bytes.Repeat([]byte{1}, chainhash.HashSize))
+	in := wire.NewTxIn(wire.NewOutPoint(&prevHash, 0), nil, nil)
+	in.Sequence = 0xfffffffe
+	tx.AddTxIn(in)
+	tx.AddTxOut(wire.NewTxOut(90000, outScript))
+	tx.LockTime = 500000000
+	return tx, script, 100000
+}
+
+func TestForkIDSignatureHash(t *testing.T) {
+	bch := utxoChains["bch"]
+	assert.Equal(t, txscript.SigHashType(0x41), bch.sigHashType())
+	assert.Equal(t, txscript.SigHashAll, utxoChains["btc"].sigHashType())
+
+	tx, script, value := forkIDTestTx()
+	hash, err := bch.signatureHash(tx, 0, script, value)
+	if !assert.NoError(t, err) {
+		return
+	}
+	// BIP143 digest with hash type 0x41, computed independently from the
+	// serialized preimage
+	assert.Equal(t, "1e20ebc84a839e416f2eb72e0f444db7a45f8027239426815258ceec8b58c0bf", hex.EncodeToString(hash))
+
+	// the legacy digest does not commit to the spent value
+	legacy, err := utxoChains["btc"].signatureHash(tx, 0, script, value)
+	if assert.NoError(t, err) {
+		assert.NotEqual(t, hash, legacy)
+	}
+	other, err := bch.signatureHash(tx, 0, script, value+1)
+	if assert.NoError(t, err) {
+		assert.NotEqual(t, hash, other)
+	}
+}
+
+func TestForkIDSignatureRoundTrip(t *testing.T) {
+	bch := utxoChains["bch"]
+	privKey, pubKey := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat([]byte{7}, 32))
+	tx, script, value := forkIDTestTx()
+	hash, err := bch.signatureHash(tx, 0, script, value)
+	if !assert.NoError(t, err) {
+		return
+	}
+	signature, err := privKey.Sign(hash)
+	if !assert.NoError(t, err) {
+		return
+	}
+	sig := append(signature.Serialize(), byte(bch.sigHashType()))
+
+	assert.Equal(t, byte(0x41), sig[len(sig)-1])
+	parsed, err := btcec.ParseDERSignature(sig[:len(sig)-1], btcec.S256())
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, parsed.Verify(hash, pubKey))
+	tx.TxOut[0].Value--
+	changed, err := bch.signatureHash(tx, 0, script, value)
+	if assert.NoError(t, err) {
+		assert.False(t, parsed.Verify(changed, pubKey))
+	}
+}
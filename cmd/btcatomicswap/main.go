@@ -8,7 +8,6 @@ package main
 import (
 	"bufio"
 	"bytes"
-	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -28,17 +27,19 @@ import (
 	"github.com/btcsuite/btcutil"
 	"github.com/btcsuite/btcwallet/wallet/txrules"
 	rpc "github.com/threefoldtech/atomicswap/cmd/btcatomicswap/rpcclient"
+	"github.com/threefoldtech/atomicswap/swapcrypto"
 	"github.com/threefoldtech/atomicswap/timings"
 	"golang.org/x/crypto/ripemd160"
 )
 
 const verify = true
 
-const secretSize = 32
+const secretSize = swapcrypto.SecretSize
 
 const txVersion = 2
 
 var (
+	chain       = utxoChains["btc"]
 	chainParams = &chaincfg.MainNetParams
 )
 
@@ -49,6 +50,7 @@ var (
 	rpcpassFlag   = flagset.String("rpcpass", "", "password for wallet RPC authentication")
 	testnetFlag   = flagset.Bool("testnet", false, "use testnet network")
 	automatedFlag = flagset.Bool("automated", false, "Use automated/unattended version with json output")
+	chainFlag     = flagset.String("chain", "btc", "the `chain` to swap on: "+chainNames())
 )
 
 // There are two directions that the atomic swap can be performed, as the
@@ -75,7 +77,7 @@ var (
 
 func init() {
 	flagset.Usage = func() {
-		fmt.Println("Atomic swaps for Bitcoin using the Electrum wallet")
+		fmt.Println("Atomic swaps for Bitcoin and its forks using an Electrum wallet")
 		fmt.Println("Usage: btcatomicswap [flags] cmd [cmd args]")
 		fmt.Println()
 		fmt.Println("Commands:")
@@ -190,8 +192,8 @@ func run() (showUsage bool, err error) {
 		return true, fmt.Errorf("unexpected argument: %s", flagset.Arg(0))
 	}
 
-	if *testnetFlag {
-		chainParams = &chaincfg.TestNet3Params
+	if err = selectChain(*chainFlag, *testnetFlag); err != nil {
+		return true, err
 	}
 
 	var cmd command
@@ -351,6 +353,7 @@ func run() (showUsage bool, err error) {
 		Pass:         *rpcpassFlag,
 		DisableTLS:   true,
 		HTTPPostMode: true,
+		ChainParams:  chainParams,
 	}
 	client, err := rpc.New(connConfig)
 	if err != nil {
@@ -378,32 +381,28 @@ func normalizeAddress(addr string, defaultPort string) (hostport string, err err
 	return addr, nil
 }
 
-func walletPort(params *chaincfg.Params) string {
-	switch params {
-	case &chaincfg.MainNetParams:
-		return "8332"
-	case &chaincfg.TestNet3Params:
-		return "18332"
-	default:
-		return ""
-	}
-}
-
 // createSig creates and returns the serialized raw signature and compressed
 // pubkey for a transaction input signature.  Due to limitations of the Bitcoin
 // Core RPC API, this requires dumping a private key and signing in the client,
 // rather than letting the wallet sign.
-func createSig(tx *wire.MsgTx, idx int, pkScript []byte, addr btcutil.Address,
+// The value of the spent output is needed for chains that sign over the
+// BIP143 digest.
+func createSig(tx *wire.MsgTx, idx int, pkScript []byte, value int64, addr btcutil.Address,
 	c *rpc.Client) (sig, pubkey []byte, err error) {
 
 	wif, err := c.DumpPrivKey(addr)
 	if err != nil {
 		return nil, nil, err
 	}
-	sig, err = txscript.RawTxInSignature(tx, idx, pkScript, txscript.SigHashAll, wif.PrivKey)
+	hash, err := chain.signatureHash(tx, idx, pkScript, value)
 	if err != nil {
 		return nil, nil, err
 	}
+	signature, err := wif.PrivKey.Sign(hash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot sign tx input: %s", err)
+	}
+	sig = append(signature.Serialize(), byte(chain.sigHashType()))
 	return sig, wif.PrivKey.PubKey().SerializeCompressed(), nil
 }
 
@@ -448,7 +447,11 @@ func payTo(c *rpc.Client, destination btcutil.Address, amount btcutil.Amount) (f
 // getFeePerKb queries the wallet for the current optimal fee rate per kilobyte,
 // according to config settings(static/dynamic).
 func getFeePerKb(c *rpc.Client) (feerate btcutil.Amount, err error) {
-	return c.GetFeeRate()
+	feerate, err = c.GetFeeRate()
+	if err == nil && feerate < chain.minFeePerKb {
+		feerate = chain.minFeePerKb
+	}
+	return
 }
 
 // getUnusedAddress uses the getunusedeaddress JSON-RPC method.
@@ -639,14 +642,14 @@ func buildRefund(c *rpc.Client, contract []byte, contractTx *wire.MsgTx, feePerK
 	refundFee = txrules.FeeForSerializeSize(feePerKb, refundSize)
 	refundTx.TxOut[0].Value = contractTx.TxOut[contractOutPoint.Index].Value - int64(refundFee)
 	if txrules.IsDustOutput(refundTx.TxOut[0], feePerKb) {
-		return nil, 0, fmt.Errorf("refund output value of %v is dust", btcutil.Amount(refundTx.TxOut[0].Value))
+		return nil, 0, fmt.Errorf("refund output value of %v is dust", formatAmount(btcutil.Amount(refundTx.TxOut[0].Value)))
 	}
 
 	txIn := wire.NewTxIn(&contractOutPoint, nil, nil)
 	txIn.Sequence = 0
 	refundTx.AddTxIn(txIn)
 
-	refundSig, refundPubKey, err := createSig(refundTx, 0, contract, refundAddr, c)
+	refundSig, refundPubKey, err := createSig(refundTx, 0, contract, contractTx.TxOut[contractOutPoint.Index].Value, refundAddr, c)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -656,7 +659,8 @@ func buildRefund(c *rpc.Client, contract []byte, contractTx *wire.MsgTx, feePerK
 	}
 	refundTx.TxIn[0].SignatureScript = refundSigScript
 
-	if verify {
+	// btcd's script engine can not validate SIGHASH_FORKID signatures
+	if verify && !chain.forkID {
 		e, err := txscript.NewEngine(contractTx.TxOut[contractOutPoint.Index].PkScript,
 			refundTx, 0, txscript.StandardVerifyFlags, txscript.NewSigCache(10),
 			txscript.NewTxSigHashes(refundTx), contractTx.TxOut[contractOutPoint.Index].Value)
@@ -672,22 +676,16 @@ func buildRefund(c *rpc.Client, contract []byte, contractTx *wire.MsgTx, feePerK
 	return refundTx, refundFee, nil
 }
 
-func sha256Hash(x []byte) []byte {
-	h := sha256.Sum256(x)
-	return h[:]
-}
-
 func calcFeePerKb(absoluteFee btcutil.Amount, serializeSize int) float64 {
 	return float64(absoluteFee) / float64(serializeSize) / 1e5
 }
 
 func (cmd *initiateCmd) runCommand(c *rpc.Client) error {
-	var secret [secretSize]byte
-	_, err := rand.Read(secret[:])
+	secret, secretHash, err := swapcrypto.SHA256.GenerateSecret()
 	if err != nil {
 		return err
 	}
-	secretHash := sha256Hash(secret[:])
+	defer swapcrypto.Zero(secret)
 
 	// locktime after 500,000,000 (Tue Nov  5 00:53:20 1985 UTC) is interpreted
 	// as a unix time rather than a block height.
@@ -716,8 +714,8 @@ func (cmd *initiateCmd) runCommand(c *rpc.Client) error {
 	if !*automatedFlag {
 		fmt.Printf("Secret:      %x\n", secret)
 		fmt.Printf("Secret hash: %x\n\n", secretHash)
-		fmt.Printf("Contract fee: %v (%0.8f BTC/kB)\n", b.contractFee, contractFeePerKb)
-		fmt.Printf("Refund fee:   %v (%0.8f BTC/kB)\n\n", b.refundFee, refundFeePerKb)
+		fmt.Printf("Contract fee: %s (%0.8f %s/kB)\n", formatAmount(b.contractFee), contractFeePerKb, chain.symbol)
+		fmt.Printf("Refund fee:   %s (%0.8f %s/kB)\n\n", formatAmount(b.refundFee), refundFeePerKb, chain.symbol)
 		fmt.Printf("Contract (%v):\n", b.contractP2SH)
 		fmt.Printf("%x\n\n", b.contract)
 		fmt.Printf("Contract transaction (%v):\n", b.contractTxHash)
@@ -740,8 +738,8 @@ func (cmd *initiateCmd) runCommand(c *rpc.Client) error {
 		}{
 			fmt.Sprintf("%x", secret),
 			fmt.Sprintf("%x", secretHash),
-			fmt.Sprintf("%v", b.contractFee),
-			fmt.Sprintf("%v", b.refundFee),
+			formatAmount(b.contractFee),
+			formatAmount(b.refundFee),
 			fmt.Sprintf("%v", b.contractP2SH),
 			fmt.Sprintf("%x", b.contract),
 			fmt.Sprintf("%v", b.contractTxHash),
@@ -786,8 +784,8 @@ func (cmd *participateCmd) runCommand(c *rpc.Client) error {
 	b.refundTx.Serialize(&refundBuf)
 	if !*automatedFlag {
 
-		fmt.Printf("Contract fee: %v (%0.8f BTC/kB)\n", b.contractFee, contractFeePerKb)
-		fmt.Printf("Refund fee:   %v (%0.8f BTC/kB)\n\n", b.refundFee, refundFeePerKb)
+		fmt.Printf("Contract fee: %s (%0.8f %s/kB)\n", formatAmount(b.contractFee), contractFeePerKb, chain.symbol)
+		fmt.Printf("Refund fee:   %s (%0.8f %s/kB)\n\n", formatAmount(b.refundFee), refundFeePerKb, chain.symbol)
 		fmt.Printf("Contract (%v):\n", b.contractP2SH)
 		fmt.Printf("%x\n\n", b.contract)
 		fmt.Printf("Contract transaction (%v):\n", b.contractTxHash)
@@ -802,8 +800,8 @@ func (cmd *participateCmd) runCommand(c *rpc.Client) error {
 			ContractTransaction   string `json:"contractTransaction"`
 			RefundTransactionHash string `json:"refundTransaction"`
 		}{
-			fmt.Sprintf("%v", b.contractFee),
-			fmt.Sprintf("%v", b.refundFee),
+			formatAmount(b.contractFee),
+			formatAmount(b.refundFee),
 			fmt.Sprintf("%v", b.contractP2SH),
 			fmt.Sprintf("%v", b.contractTxHash),
 			fmt.Sprintf("%v", &refundTxHash),
@@ -869,10 +867,10 @@ func (cmd *redeemCmd) runCommand(c *rpc.Client) error {
 	fee := txrules.FeeForSerializeSize(feePerKb, redeemSize)
 	redeemTx.TxOut[0].Value = cmd.contractTx.TxOut[contractOut].Value - int64(fee)
 	if txrules.IsDustOutput(redeemTx.TxOut[0], feePerKb) {
-		return fmt.Errorf("redeem output value of %v is dust", btcutil.Amount(redeemTx.TxOut[0].Value))
+		return fmt.Errorf("redeem output value of %v is dust", formatAmount(btcutil.Amount(redeemTx.TxOut[0].Value)))
 	}
 
-	redeemSig, redeemPubKey, err := createSig(redeemTx, 0, cmd.contract, recipientAddr, c)
+	redeemSig, redeemPubKey, err := createSig(redeemTx, 0, cmd.contract, cmd.contractTx.TxOut[contractOut].Value, recipientAddr, c)
 	if err != nil {
 		return err
 	}
@@ -889,7 +887,7 @@ func (cmd *redeemCmd) runCommand(c *rpc.Client) error {
 	buf.Grow(redeemTx.SerializeSize())
 	redeemTx.Serialize(&buf)
 	if !*automatedFlag {
-		fmt.Printf("Redeem fee: %v (%0.8f BTC/kB)\n\n", fee, redeemFeePerKb)
+		fmt.Printf("Redeem fee: %s (%0.8f %s/kB)\n\n", formatAmount(fee), redeemFeePerKb, chain.symbol)
 		fmt.Printf("Redeem transaction (%v):\n", &redeemTxHash)
 		fmt.Printf("%x\n\n", buf.Bytes())
 	} else {
@@ -897,13 +895,13 @@ func (cmd *redeemCmd) runCommand(c *rpc.Client) error {
 			RedeemFee               string `json:"redeemFee"`
 			RedeemTransactionTxHash string `json:"redeemTransaction"`
 		}{
-			fmt.Sprintf("%v", fee),
+			formatAmount(fee),
 			fmt.Sprintf("%v", &redeemTxHash),
 		}
 		jsonoutput, _ := json.Marshal(output)
 		fmt.Println(string(jsonoutput))
 	}
-	if verify {
+	if verify && !chain.forkID {
 		e, err := txscript.NewEngine(cmd.contractTx.TxOut[contractOutPoint.Index].PkScript,
 			redeemTx, 0, txscript.StandardVerifyFlags, txscript.NewSigCache(10),
 			txscript.NewTxSigHashes(redeemTx), cmd.contractTx.TxOut[contractOut].Value)
@@ -944,7 +942,7 @@ func (cmd *refundCmd) runCommand(c *rpc.Client) error {
 
 	refundFeePerKb := calcFeePerKb(refundFee, refundTx.SerializeSize())
 	if !*automatedFlag {
-		fmt.Printf("Refund fee: %v (%0.8f BTC/kB)\n\n", refundFee, refundFeePerKb)
+		fmt.Printf("Refund fee: %s (%0.8f %s/kB)\n\n", formatAmount(refundFee), refundFeePerKb, chain.symbol)
 		fmt.Printf("Refund transaction (%v):\n", &refundTxHash)
 		fmt.Printf("%x\n\n", buf.Bytes())
 	} else {
@@ -952,7 +950,7 @@ func (cmd *refundCmd) runCommand(c *rpc.Client) error {
 			RefundFee               string `json:"refundFee"`
 			RefundTransactionTxHash string `json:"refundTransaction"`
 		}{
-			fmt.Sprintf("%v", refundFee),
+			formatAmount(refundFee),
 			fmt.Sprintf("%v", &refundTxHash),
 		}
 		jsonoutput, _ := json.Marshal(output)
@@ -977,7 +975,7 @@ func (cmd *extractSecretCmd) runOfflineCommand() error {
 			return err
 		}
 		for _, push := range pushes {
-			if bytes.Equal(sha256Hash(push), cmd.secretHash) {
+			if swapcrypto.SHA256.Verify(push, cmd.secretHash) {
 				fmt.Printf("Secret: %x\n", push)
 				return nil
 			}
@@ -1034,7 +1032,7 @@ func (cmd *auditContractCmd) runOfflineCommand() error {
 	}
 	if !*automatedFlag {
 		fmt.Printf("Contract address:        %v\n", contractAddr)
-		fmt.Printf("Contract value:          %v\n", btcutil.Amount(cmd.contractTx.TxOut[contractOut].Value))
+		fmt.Printf("Contract value:          %v\n", formatAmount(btcutil.Amount(cmd.contractTx.TxOut[contractOut].Value)))
 		fmt.Printf("Recipient address:       %v\n", recipientAddr)
 		fmt.Printf("Refund address: %v\n\n", refundAddr)
 
@@ -1062,7 +1060,7 @@ func (cmd *auditContractCmd) runOfflineCommand() error {
 			Locktime         string `json:"Locktime"`
 		}{
 			fmt.Sprintf("%v", contractAddr),
-			fmt.Sprintf("%v", btcutil.Amount(cmd.contractTx.TxOut[contractOut].Value)),
+			formatAmount(btcutil.Amount(cmd.contractTx.TxOut[contractOut].Value)),
 			fmt.Sprintf("%v", recipientAddr),
 			fmt.Sprintf("%v", refundAddr),
 			fmt.Sprintf("%x", pushes.SecretHash[:]),
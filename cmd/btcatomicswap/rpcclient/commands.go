@@ -22,6 +22,10 @@ type FutureGetUnusedAddressResult chan *response
 // Receive waits for the response promised by the future and returns a new
 // address.
 func (r FutureGetUnusedAddressResult) Receive() (btcutil.Address, error) {
+	return r.receive(&chaincfg.MainNetParams)
+}
+
+func (r FutureGetUnusedAddressResult) receive(params *chaincfg.Params) (btcutil.Address, error) {
 	res, err := receiveFuture(r)
 	if err != nil {
 		return nil, err
@@ -35,7 +39,7 @@ func (r FutureGetUnusedAddressResult) Receive() (btcutil.Address, error) {
 
 	}
 
-	return btcutil.DecodeAddress(addr, &chaincfg.MainNetParams)
+	return btcutil.DecodeAddress(addr, params)
 }
 
 // GetUnusedAddressCmd defines the getunusedaddress JSON-RPC command.
@@ -63,7 +67,7 @@ func (c *Client) GetUnusedAddressAsync() FutureGetUnusedAddressResult {
 // An address is considered as used if it has received a transaction, or if
 //it is used in a payment request.
 func (c *Client) GetUnusedAddress() (btcutil.Address, error) {
-	return c.GetUnusedAddressAsync().Receive()
+	return c.GetUnusedAddressAsync().receive(c.chainParams())
 }
 
 // FutureDumpPrivKeyResult is a future promise to deliver the result of a
@@ -103,7 +107,6 @@ func (c *Client) DumpPrivKeyAsync(address btcutil.Address) FutureDumpPrivKeyResu
 
 // DumpPrivKey gets the private key corresponding to the passed address encoded
 // in the wallet import format (WIF).
-//
 func (c *Client) DumpPrivKey(address btcutil.Address) (*btcutil.WIF, error) {
 	return c.DumpPrivKeyAsync(address).Receive()
 }
@@ -262,6 +265,10 @@ type FutureListUnspentResult chan *response
 
 // Receive waits for the response promised by the future and returns the decode unspent outputs.
 func (r FutureListUnspentResult) Receive() (utxos []*UnspentOutput, err error) {
+	return r.receive(&chaincfg.MainNetParams)
+}
+
+func (r FutureListUnspentResult) receive(params *chaincfg.Params) (utxos []*UnspentOutput, err error) {
 	rawResp, err := receiveFuture(r)
 	if err != nil {
 		return
@@ -294,7 +301,7 @@ func (r FutureListUnspentResult) Receive() (utxos []*UnspentOutput, err error) {
 		if err != nil {
 			return nil, err
 		}
-		utxo.Address, err = btcutil.DecodeAddress(respUtxo.Address, nil)
+		utxo.Address, err = btcutil.DecodeAddress(respUtxo.Address, params)
 		if err != nil {
 			return nil, err
 		}
@@ -325,7 +332,7 @@ func (c *Client) ListUnspentAsync() FutureListUnspentResult {
 //ListUnspent returns the list of unspent transaction outputs in the
 //wallet by issuing a listunspent JSON-RPC command.
 func (c *Client) ListUnspent() ([]*UnspentOutput, error) {
-	return c.ListUnspentAsync().Receive()
+	return c.ListUnspentAsync().receive(c.chainParams())
 }
 
 // FutureBroadcastResult is a future promise to deliver the result of
@@ -16,6 +16,8 @@ import (
 	"reflect"
 	"sync"
 	"sync/atomic"
+
+	"github.com/btcsuite/btcd/chaincfg"
 )
 
 var (
@@ -189,6 +191,18 @@ type ConnConfig struct {
 	//This flag is only here for compatibility with btcsuite's ConnConfig,
 	//Http post is the only supportedmode
 	HTTPPostMode bool
+
+	// ChainParams are the network parameters used to decode the addresses
+	// returned by the wallet. It defaults to the bitcoin main network.
+	ChainParams *chaincfg.Params
+}
+
+// chainParams returns the network parameters of the connected wallet.
+func (c *Client) chainParams() *chaincfg.Params {
+	if c.config.ChainParams == nil {
+		return &chaincfg.MainNetParams
+	}
+	return c.config.ChainParams
 }
 
 // newHTTPClient returns a new http client that is configured according to the
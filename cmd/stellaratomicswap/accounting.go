@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/stellar/go/amount"
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/txnbuild"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+)
+
+//swapOutcome is what a party receives when the holding account is merged
+type swapOutcome struct {
+	Receiver string                   `json:"receiver"`
+	Receives []stellar.CreditedAmount `json:"receives"`
+	//Estimate is set when the XLM received depends on the fee bid when the transaction is built
+	Estimate bool `json:"estimate,omitempty"`
+}
+
+//swapAccounting splits the funds in a holding account in the swap principal and the XLM locked for its reserves
+//and shows who recovers what on a redeem or a refund.
+type swapAccounting struct {
+	Principal stellar.CreditedAmount `json:"principal"`
+	//LockedXLM is all XLM in the holding account, for a native swap this includes the principal
+	LockedXLM string `json:"lockedxlm"`
+	//Reserve is the part of LockedXLM that is the minimum balance of the holding account
+	Reserve string `json:"reserve"`
+	//CloseFee is paid by the holding account for the pre-signed refund transaction
+	CloseFee string `json:"closefee"`
+	//RedeemFee estimates the fee of the redeem transaction at the base fee of the refund transaction,
+	//the redeem bids the base fee of the moment it is built
+	RedeemFee string      `json:"redeemfee"`
+	OnRedeem  swapOutcome `json:"onredeem"`
+	OnRefund  swapOutcome `json:"onrefund"`
+}
+
+//getSwapAccounting calculates the accounting of a holding account that was just set up.
+//The setup already succeeded at this point so a failing lookup is only reported.
+func getSwapAccounting(holdingAccountAddress string, swapAmount string, asset txnbuild.Asset, counterPartyAddress string, refundTransaction txnbuild.Transaction, client horizonclient.ClientInterface) *swapAccounting {
+	accounting, err := calculateSwapAccounting(holdingAccountAddress, swapAmount, asset, counterPartyAddress, refundTransaction, client)
+	if err != nil {
+		logger.WithField("holdingaccount", holdingAccountAddress).Warnf("Failed to get the holding account reserves: %v", err)
+		return nil
+	}
+	return accounting
+}
+
+func calculateSwapAccounting(holdingAccountAddress string, swapAmount string, asset txnbuild.Asset, counterPartyAddress string, refundTransaction txnbuild.Transaction, client horizonclient.ClientInterface) (*swapAccounting, error) {
+	holdingAccount, err := stellar.GetAccount(holdingAccountAddress, client)
+	if err != nil {
+		return nil, err
+	}
+	ledgers, err := client.Ledgers(horizonclient.LedgerRequest{Order: horizonclient.OrderDesc, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(ledgers.Embedded.Records) == 0 {
+		return nil, errors.New("No ledgers found")
+	}
+	baseReserve := int64(ledgers.Embedded.Records[0].BaseReserve)
+	reserve := int64(2+holdingAccount.SubentryCount) * baseReserve
+	closeFee := int64(refundTransaction.BaseFee) * int64(len(refundTransaction.Operations))
+	redeemFee := int64(refundTransaction.BaseFee) * int64(len(createRedeemOperations(holdingAccount, counterPartyAddress)))
+
+	var lockedXLM int64
+	var credits []stellar.CreditedAmount
+	for _, balance := range holdingAccount.Balances {
+		if balance.Asset.Type == stellar.NativeAssetType {
+			if lockedXLM, err = amount.ParseInt64(balance.Balance); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		credits = append(credits, stellar.CreditedAmount{Asset: balance.Code + ":" + balance.Issuer, Amount: balance.Balance})
+	}
+	//The merge credits the remaining XLM after the fee of the closing transaction
+	receives := func(fee int64) []stellar.CreditedAmount {
+		return append([]stellar.CreditedAmount{{Asset: "XLM", Amount: amount.StringFromInt64(lockedXLM - fee)}}, credits...)
+	}
+
+	return &swapAccounting{
+		Principal: stellar.CreditedAmount{Asset: assetName(asset), Amount: swapAmount},
+		LockedXLM: amount.StringFromInt64(lockedXLM),
+		Reserve:   amount.StringFromInt64(reserve),
+		CloseFee:  amount.StringFromInt64(closeFee),
+		RedeemFee: amount.StringFromInt64(redeemFee),
+		OnRedeem:  swapOutcome{Receiver: counterPartyAddress, Receives: receives(redeemFee), Estimate: true},
+		OnRefund:  swapOutcome{Receiver: refundedAddress(&refundTransaction), Receives: receives(closeFee)},
+	}, nil
+}
+
+//refundedAddress returns the destination of the merge in a refund transaction
+func refundedAddress(refundTx *txnbuild.Transaction) string {
+	for _, operation := range refundTx.Operations {
+		if accountMerge, ok := operation.(*txnbuild.AccountMerge); ok {
+			return accountMerge.Destination
+		}
+	}
+	return ""
+}
+
+func printSwapAccounting(accounting *swapAccounting) {
+	if accounting == nil {
+		return
+	}
+	fmt.Printf("swap amount: %s %s\n", accounting.Principal.Amount, accounting.Principal.Asset)
+	fmt.Printf("locked XLM: %s, of which %s XLM reserve\n", accounting.LockedXLM, accounting.Reserve)
+	for _, outcome := range []struct {
+		name string
+		swapOutcome
+	}{{"redeem", accounting.OnRedeem}, {"refund", accounting.OnRefund}} {
+		if outcome.Estimate {
+			fmt.Printf("on %s %s receives about:", outcome.name, outcome.Receiver)
+		} else {
+			fmt.Printf("on %s %s receives:", outcome.name, outcome.Receiver)
+		}
+		for _, received := range outcome.Receives {
+			fmt.Printf(" %s %s", received.Amount, received.Asset)
+		}
+		fmt.Println()
+	}
+}
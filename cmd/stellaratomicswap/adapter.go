@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/network"
+	"github.com/stellar/go/txnbuild"
+	"github.com/threefoldtech/atomicswap/adapter"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+	"github.com/threefoldtech/atomicswap/timings"
+)
+
+func init() {
+	adapter.Register("xlm", newStellarAdapter)
+}
+
+//stellarAdapter implements the swap steps for native XLM on top of the same functions the commands use.
+//The contract address is the holding account, the refund data the base64 encoded refund transaction.
+type stellarAdapter struct {
+	signer stellar.Signer
+	asset  txnbuild.Asset
+	client horizonclient.ClientInterface
+	//network is the passphrase of the network of the adapter, independent of the -testnet flag of the process
+	network string
+}
+
+//newStellarAdapter creates the stellar adapter, the key is anything the seed argument of the commands accepts.
+func newStellarAdapter(config adapter.Config) (adapter.Adapter, error) {
+	a := &stellarAdapter{asset: txnbuild.NativeAsset{}, network: network.PublicNetworkPassphrase}
+	horizonURL := horizonclient.DefaultPublicNetClient.HorizonURL
+	if config.Testnet {
+		a.network = network.TestNetworkPassphrase
+		horizonURL = horizonclient.DefaultTestNetClient.HorizonURL
+	}
+	if config.HTTPClient != nil {
+		a.client = newHorizonClientWithHTTP(horizonURL, config.HTTPClient)
+	} else {
+		client, err := newHorizonClient(horizonURL)
+		if err != nil {
+			return nil, err
+		}
+		a.client = client
+	}
+	if config.Key != "" {
+		signer, err := parseSigner(config.Key)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid key: %v", err)
+		}
+		a.signer = signer
+	}
+	return a, nil
+}
+
+func (a *stellarAdapter) requireSigner() error {
+	if a.signer == nil {
+		return errors.New("No key configured for the stellar adapter")
+	}
+	return nil
+}
+
+//createContract sets up a holding account, the secret is only known by the initiator
+func (a *stellarAdapter) createContract(role string, counterPartyAddress string, amount string, secret []byte, secretHash []byte, locktime time.Time) (contract adapter.Contract, err error) {
+	if err = a.requireSigner(); err != nil {
+		return
+	}
+	if err = parseAddress(counterPartyAddress); err != nil {
+		return
+	}
+	if err = checkSwapAddresses(a.signer.Address(), counterPartyAddress); err != nil {
+		return
+	}
+	holdingAccountKeyPair, err := newHoldingKeyPair(a.signer, secretHash, counterPartyAddress)
+	if err != nil {
+		return
+	}
+	var progress setupProgress
+	record := newSwapRecord(role, holdingAccountKeyPair, a.signer.Address(), counterPartyAddress, amount, a.asset, secret, secretHash, locktime, a.network)
+	refundTransaction, err := createAtomicSwapHoldingAccount(a.signer, holdingAccountKeyPair, counterPartyAddress, amount, secretHash, locktime, a.asset, record, &progress, a.network, a.client)
+	if err != nil {
+		err = progress.wrap(err)
+		return
+	}
+	contract.Address = holdingAccountKeyPair.Address()
+	contract.Refund, err = refundTransaction.Base64()
+	return
+}
+
+func (a *stellarAdapter) Initiate(participantAddress string, amount string) (secret []byte, secretHash []byte, contract adapter.Contract, err error) {
+	secret, secretHash, err = generateSecret()
+	if err != nil {
+		return
+	}
+	contract, err = a.createContract("initiator", participantAddress, amount, secret, secretHash, time.Now().Add(timings.LockTime))
+	return
+}
+
+func (a *stellarAdapter) Participate(initiatorAddress string, amount string, secretHash []byte) (adapter.Contract, error) {
+	return a.createContract("participant", initiatorAddress, amount, nil, secretHash, time.Now().Add(timings.LockTime/2))
+}
+
+func (a *stellarAdapter) Redeem(contract adapter.Contract, secret []byte) (transactionID string, err error) {
+	if err = a.requireSigner(); err != nil {
+		return
+	}
+	holdingAccount, err := stellar.GetAccount(contract.Address, a.client)
+	if err != nil {
+		return
+	}
+	redeemTransaction, err := createRedeemTransaction(holdingAccount, a.signer.Address(), secret, a.network, a.client)
+	if err != nil {
+		return
+	}
+	txe, err := stellar.SignEncode(&redeemTransaction, a.signer)
+	if err != nil {
+		return
+	}
+	txSuccess, err := stellar.SubmitTransaction(txe, a.client)
+	if err != nil {
+		return
+	}
+	recordRedeem(contract.Address, secret, txSuccess.Hash, a.network)
+	return txSuccess.Hash, nil
+}
+
+func (a *stellarAdapter) Refund(contract adapter.Contract) (transactionID string, err error) {
+	txSuccess, err := stellar.SubmitTransaction(contract.Refund, a.client)
+	if err != nil {
+		return
+	}
+	recordRefund(contract.Address, txSuccess.Hash)
+	return txSuccess.Hash, nil
+}
+
+func (a *stellarAdapter) Audit(contract adapter.Contract) (result adapter.Audit, err error) {
+	refundTx, err := txnbuild.TransactionFromXDR(contract.Refund)
+	if err != nil {
+		err = fmt.Errorf("Failed to decode the refund transaction: %v", err)
+		return
+	}
+	audit, err := auditHoldingAccount(contract.Address, &refundTx, a.network, a.client)
+	if err != nil {
+		return
+	}
+	//the amount is the balance of the asset of the adapter, for XLM it includes the reserve of the holding account
+	amount := ""
+	for _, balance := range audit.holdingAccount.Balances {
+		if a.asset.IsNative() && balance.Asset.Type == stellar.NativeAssetType ||
+			!a.asset.IsNative() && balance.Code == a.asset.GetCode() && balance.Issuer == a.asset.GetIssuer() {
+			amount = balance.Balance
+		}
+	}
+	if amount == "" {
+		err = fmt.Errorf("The holding account does not hold %s", assetName(a.asset))
+		return
+	}
+	result = adapter.Audit{
+		Contract:         contract,
+		Amount:           amount,
+		Asset:            assetName(a.asset),
+		RecipientAddress: audit.recipientAddress,
+		RefundAddress:    audit.refundAddress,
+		SecretHash:       audit.secretHash,
+		Locktime:         time.Unix(audit.lockTime, 0),
+	}
+	return
+}
+
+func (a *stellarAdapter) ExtractSecret(contract adapter.Contract, secretHash []byte) (secret []byte, err error) {
+	return extractSecret(contract.Address, hex.EncodeToString(secretHash), a.client)
+}
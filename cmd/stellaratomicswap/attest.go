@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/keypair"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+)
+
+//attestationDomain is prefixed to the terms before hashing so an attestation signature can never be a valid transaction signature
+const attestationDomain = "stellaratomicswap swap terms attestation v1\n"
+
+//swapTerms are the parameters of a swap both parties agreed upon
+type swapTerms struct {
+	InitiatorAddress    string `json:"initiator"`
+	ParticipantAddress  string `json:"participant"`
+	SecretHash          string `json:"hash"`
+	InitiatorAmount     string `json:"initiatoramount"`
+	ParticipantAmount   string `json:"participantamount"`
+	Rate                string `json:"rate,omitempty"`
+	InitiatorLocktime   int64  `json:"initiatorlocktime"`
+	ParticipantLocktime int64  `json:"participantlocktime"`
+}
+
+//digest returns the hash of the canonical json encoding of the terms
+func (t swapTerms) digest() ([]byte, error) {
+	canonical, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.Sum256(append([]byte(attestationDomain), canonical...))
+	return h[:], nil
+}
+
+func (t swapTerms) validate() error {
+	if err := parseAddress(t.InitiatorAddress); err != nil {
+		return fmt.Errorf("invalid initiator address: %v", err)
+	}
+	if t.ParticipantAddress != "" {
+		if err := parseAddress(t.ParticipantAddress); err != nil {
+			return fmt.Errorf("invalid participant address: %v", err)
+		}
+	}
+	secretHash, err := hex.DecodeString(t.SecretHash)
+	if err != nil || len(secretHash) != sha256.Size {
+		return errors.New("the secret hash must be a hex encoded sha256 hash")
+	}
+	if t.InitiatorAmount == "" || t.ParticipantAmount == "" {
+		return errors.New("both amounts are required")
+	}
+	if t.InitiatorLocktime <= 0 || t.ParticipantLocktime <= 0 {
+		return errors.New("both locktimes are required")
+	}
+	return nil
+}
+
+//swapAttestation is a signature of a party over the swap terms
+type swapAttestation struct {
+	Terms     swapTerms `json:"terms"`
+	Digest    string    `json:"digest"`
+	Signer    string    `json:"signer"`
+	Signature string    `json:"signature"`
+}
+
+type attestCmd struct {
+	signer    stellar.Signer
+	termsFile string
+}
+
+type verifyAttestationCmd struct {
+	attestationFile string
+}
+
+//readStrictJSON decodes a json file, rejecting unknown fields so nothing is silently left out of the signed terms
+func readStrictJSON(path string, v interface{}) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(v); err != nil {
+		return fmt.Errorf("Failed to decode %s: %v", path, err)
+	}
+	return nil
+}
+
+func (cmd *attestCmd) runCommand(client horizonclient.ClientInterface) error {
+	return cmd.runOfflineCommand()
+}
+
+func (cmd *attestCmd) runOfflineCommand() error {
+	var terms swapTerms
+	if err := readStrictJSON(cmd.termsFile, &terms); err != nil {
+		return err
+	}
+	if err := terms.validate(); err != nil {
+		return err
+	}
+	signerAddress := cmd.signer.Address()
+	if signerAddress != terms.InitiatorAddress && signerAddress != terms.ParticipantAddress {
+		return fmt.Errorf("%s is not a party of the swap", signerAddress)
+	}
+	digest, err := terms.digest()
+	if err != nil {
+		return err
+	}
+	signature, err := cmd.signer.Sign(digest)
+	if err != nil {
+		return fmt.Errorf("Failed to sign the swap terms: %v", err)
+	}
+	attestation := swapAttestation{
+		Terms:     terms,
+		Digest:    hex.EncodeToString(digest),
+		Signer:    signerAddress,
+		Signature: base64.StdEncoding.EncodeToString(signature),
+	}
+	jsonoutput, _ := json.MarshalIndent(attestation, "", "  ")
+	fmt.Println(string(jsonoutput))
+	return nil
+}
+
+func (cmd *verifyAttestationCmd) runCommand(client horizonclient.ClientInterface) error {
+	return cmd.runOfflineCommand()
+}
+
+func (cmd *verifyAttestationCmd) runOfflineCommand() error {
+	var attestation swapAttestation
+	if err := readStrictJSON(cmd.attestationFile, &attestation); err != nil {
+		return err
+	}
+	if err := attestation.Terms.validate(); err != nil {
+		return err
+	}
+	if attestation.Signer != attestation.Terms.InitiatorAddress && attestation.Signer != attestation.Terms.ParticipantAddress {
+		return fmt.Errorf("%s is not a party of the swap", attestation.Signer)
+	}
+	digest, err := attestation.Terms.digest()
+	if err != nil {
+		return err
+	}
+	if attestation.Digest != hex.EncodeToString(digest) {
+		return errors.New("The digest does not match the terms")
+	}
+	signature, err := base64.StdEncoding.DecodeString(attestation.Signature)
+	if err != nil {
+		return fmt.Errorf("Invalid signature encoding: %v", err)
+	}
+	kp, err := keypair.Parse(attestation.Signer)
+	if err != nil {
+		return fmt.Errorf("Invalid signer: %v", err)
+	}
+	if err = kp.Verify(digest, signature); err != nil {
+		return errors.New("Invalid signature, the terms were not signed by the signer")
+	}
+	if !*automatedFlag {
+		terms := attestation.Terms
+		fmt.Printf("Valid attestation by %s\n\n", attestation.Signer)
+		fmt.Printf("Initiator:            %s\n", terms.InitiatorAddress)
+		fmt.Printf("Participant:          %s\n", terms.ParticipantAddress)
+		fmt.Printf("Secret hash:          %s\n", terms.SecretHash)
+		fmt.Printf("Initiator amount:     %s\n", terms.InitiatorAmount)
+		fmt.Printf("Participant amount:   %s\n", terms.ParticipantAmount)
+		if terms.Rate != "" {
+			fmt.Printf("Rate:                 %s\n", terms.Rate)
+		}
+		fmt.Printf("Initiator locktime:   %v\n", time.Unix(terms.InitiatorLocktime, 0).UTC())
+		fmt.Printf("Participant locktime: %v\n", time.Unix(terms.ParticipantLocktime, 0).UTC())
+	} else {
+		output := struct {
+			Valid  bool   `json:"valid"`
+			Signer string `json:"signer"`
+		}{true, attestation.Signer}
+		jsonoutput, _ := json.Marshal(output)
+		fmt.Println(string(jsonoutput))
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/stellar/go/amount"
+	"github.com/stellar/go/network"
+	hprotocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/txnbuild"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+)
+
+//refundTransactionNetwork returns the other well known network the refund transaction hashes to the signer for, if any.
+//The hash of a transaction includes the network passphrase, so a refund transaction built for another network
+//does not match the signer and could never be submitted on the network of the holding account.
+func refundTransactionNetwork(refundTx *txnbuild.Transaction, signerHash []byte) string {
+	defer func(passphrase string) { refundTx.Network = passphrase }(refundTx.Network)
+	for _, passphrase := range []string{network.PublicNetworkPassphrase, network.TestNetworkPassphrase} {
+		if passphrase == targetNetwork {
+			continue
+		}
+		refundTx.Network = passphrase
+		hash, err := refundTx.Hash()
+		if err == nil && bytes.Equal(hash[:], signerHash) {
+			return passphrase
+		}
+	}
+	return ""
+}
+
+//auditRefundOperations verifies the refund transaction merges the holding account and returns where it refunds to.
+//Before the merge it may only pay out the balances to the refund address and remove the trustlines and data entries,
+//and it has to remove all of them since subentries other than signers make the merge fail.
+func auditRefundOperations(holdingAccount hprotocol.Account, refundTx *txnbuild.Transaction) (refundAddress string, err error) {
+	operations := refundTx.Operations
+	if len(operations) == 0 {
+		return "", errors.New("The refund transaction has no operations")
+	}
+	merge, ok := operations[len(operations)-1].(*txnbuild.AccountMerge)
+	if !ok {
+		return "", fmt.Errorf("Expecting an accountmerge operation at the end of the refund transaction but got a %v", reflect.TypeOf(operations[len(operations)-1]))
+	}
+	source := func(account txnbuild.Account) string {
+		if account == nil {
+			account = refundTx.SourceAccount
+		}
+		if account == nil {
+			return ""
+		}
+		return account.GetAccountID()
+	}
+	if source(merge.SourceAccount) != holdingAccount.AccountID {
+		return "", fmt.Errorf("The refund transaction does not refund from the holding account but from %v", source(merge.SourceAccount))
+	}
+
+	paid := make(map[string]string)
+	removedTrustlines := make(map[string]bool)
+	removedData := make(map[string]bool)
+	for _, operation := range operations[:len(operations)-1] {
+		switch operation := operation.(type) {
+		case *txnbuild.Payment:
+			if source(operation.SourceAccount) != holdingAccount.AccountID || operation.Destination != merge.Destination {
+				return "", fmt.Errorf("The refund transaction pays %s %s from %s to %s instead of from the holding account to the refund address", operation.Amount, assetName(operation.Asset), source(operation.SourceAccount), operation.Destination)
+			}
+			paid[assetName(operation.Asset)] = operation.Amount
+		case *txnbuild.ChangeTrust:
+			limit, err := amount.ParseInt64(operation.Limit)
+			if err != nil || limit != 0 || source(operation.SourceAccount) != holdingAccount.AccountID {
+				return "", fmt.Errorf("The refund transaction changes the trustline to %s instead of removing it", assetName(operation.Line))
+			}
+			removedTrustlines[assetName(operation.Line)] = true
+		case *txnbuild.ManageData:
+			if operation.Value != nil || source(operation.SourceAccount) != holdingAccount.AccountID {
+				return "", fmt.Errorf("The refund transaction sets data entry %s instead of removing it", operation.Name)
+			}
+			removedData[operation.Name] = true
+		default:
+			return "", fmt.Errorf("Unexpected %v operation in the refund transaction", reflect.TypeOf(operation))
+		}
+	}
+	return merge.Destination, checkMergeBlockers(holdingAccount, paid, removedTrustlines, removedData)
+}
+
+//checkMergeBlockers enumerates the subentries of the holding account and fails if the refund transaction does not clear
+//one of them, which would make the merge fail and the contract unrefundable.
+//The redeem transaction is built from the current state of the holding account so it clears trustlines and data entries too,
+//but offers block both.
+func checkMergeBlockers(holdingAccount hprotocol.Account, paid map[string]string, removedTrustlines map[string]bool, removedData map[string]bool) error {
+	trustlines := 0
+	for _, balance := range holdingAccount.Balances {
+		if balance.Asset.Type == stellar.NativeAssetType {
+			continue
+		}
+		trustlines++
+		name := balance.Code + ":" + balance.Issuer
+		if !removedTrustlines[name] {
+			return fmt.Errorf("The refund transaction does not remove the trustline to %s, which blocks the merge of the holding account", name)
+		}
+		held, err := amount.ParseInt64(balance.Balance)
+		if err != nil {
+			return err
+		}
+		var paidOut int64
+		if paidAmount, ok := paid[name]; ok {
+			if paidOut, err = amount.ParseInt64(paidAmount); err != nil {
+				return err
+			}
+		}
+		if held != paidOut {
+			return fmt.Errorf("The holding account holds %s %s but the refund transaction pays out %s, so the trustline can not be removed", balance.Balance, name, amount.StringFromInt64(paidOut))
+		}
+	}
+	for name := range holdingAccount.Data {
+		if !removedData[name] {
+			return fmt.Errorf("The refund transaction does not remove data entry %s, which blocks the merge of the holding account", name)
+		}
+	}
+	signers := 0
+	for _, signer := range holdingAccount.Signers {
+		if signer.Key != holdingAccount.AccountID {
+			signers++
+		}
+	}
+	if other := int(holdingAccount.SubentryCount) - trustlines - len(holdingAccount.Data) - signers; other > 0 {
+		return fmt.Errorf("The holding account has %d subentries besides its trustlines, data entries and signers, like offers, which block the merge of the holding account", other)
+	}
+	return nil
+}
+
+//holdingAccountRedFlags reports settings of the holding account that are unusual but do not prevent the swap
+func holdingAccountRedFlags(holdingAccount hprotocol.Account) (redFlags []string) {
+	if holdingAccount.HomeDomain != "" {
+		redFlags = append(redFlags, fmt.Sprintf("the holding account has home domain %s, make sure the counterparty set it with -homedomain", holdingAccount.HomeDomain))
+	}
+	if holdingAccount.InflationDestination != "" {
+		redFlags = append(redFlags, fmt.Sprintf("the holding account has inflation destination %s", holdingAccount.InflationDestination))
+	}
+	return
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	hprotocol "github.com/stellar/go/protocols/horizon"
+)
+
+//offlineAuditContractCmd audits a contract against a holding account record captured from horizon, without a connection
+type offlineAuditContractCmd struct {
+	auditContractCmd
+	accountFile string
+}
+
+func (cmd *offlineAuditContractCmd) runOfflineCommand() error {
+	content, err := ioutil.ReadFile(cmd.accountFile)
+	if err != nil {
+		return fmt.Errorf("Failed to read the holding account: %v", err)
+	}
+	var holdingAccount hprotocol.Account
+	if err = json.Unmarshal(content, &holdingAccount); err != nil {
+		return fmt.Errorf("Failed to decode the holding account: %v", err)
+	}
+	if holdingAccount.AccountID != cmd.holdingAccountAdress {
+		return fmt.Errorf("The account file is of %s instead of holding account %s", holdingAccount.AccountID, cmd.holdingAccountAdress)
+	}
+	audit, err := auditHoldingAccountState(holdingAccount, &cmd.refundTx, targetNetwork)
+	if err != nil {
+		return err
+	}
+	return cmd.printAudit(audit)
+}
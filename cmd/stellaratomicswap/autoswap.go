@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/threefoldtech/atomicswap/adapter"
+	"github.com/threefoldtech/atomicswap/timings"
+)
+
+//autoSwapConfig describes both legs of an automated swap
+type autoSwapConfig struct {
+	//Role is initiator or participant
+	Role string `json:"role"`
+	//Own is the chain where the own funds are locked
+	Own struct {
+		Chain        string `json:"chain"`
+		Key          string `json:"key"`
+		Counterparty string `json:"counterparty"`
+		Amount       string `json:"amount"`
+	} `json:"own"`
+	//Counter is the chain where the counterparty's funds are received
+	Counter struct {
+		Chain        string `json:"chain"`
+		Key          string `json:"key"`
+		Address      string `json:"address"`
+		Counterparty string `json:"counterparty"`
+		//Amount is the minimum amount the counterparty has to lock
+		Amount string `json:"amount"`
+		//Asset the counterparty has to lock, as the counter chain adapter names it
+		Asset string `json:"asset,omitempty"`
+	} `json:"counter"`
+	//Outbox is the file the own contract is written to for the counterparty
+	Outbox string `json:"outbox"`
+	//Inbox is the file where the counterparty's contract is expected
+	Inbox string `json:"inbox"`
+	//State is the file the progress of the swap, including the secret of the initiator, is written to
+	State          string `json:"state,omitempty"`
+	PollInterval   string `json:"pollinterval,omitempty"`
+	LocktimeMargin string `json:"locktimemargin,omitempty"`
+}
+
+type autoSwapCmd struct {
+	configFile string
+}
+
+func (cmd *autoSwapCmd) newAutoSwap() (swap *adapter.AutoSwap, role string, err error) {
+	var config autoSwapConfig
+	if err = readStrictJSON(cmd.configFile, &config); err != nil {
+		return
+	}
+	if config.Role != "initiator" && config.Role != "participant" {
+		return nil, "", fmt.Errorf("Invalid role %q, it should be initiator or participant", config.Role)
+	}
+	if config.Outbox == "" || config.Inbox == "" {
+		return nil, "", errors.New("Both an outbox and an inbox file are required")
+	}
+	if config.Counter.Amount == "" {
+		return nil, "", errors.New("The amount the counterparty has to lock is required")
+	}
+	if config.State == "" {
+		config.State = cmd.configFile + ".state"
+	}
+	swap = &adapter.AutoSwap{
+		PollInterval:          10 * time.Second,
+		MinimumLocktimeMargin: timings.LockTime / 4,
+	}
+	if config.PollInterval != "" {
+		if swap.PollInterval, err = time.ParseDuration(config.PollInterval); err != nil {
+			return nil, "", fmt.Errorf("Invalid poll interval: %v", err)
+		}
+	}
+	if config.LocktimeMargin != "" {
+		if swap.MinimumLocktimeMargin, err = time.ParseDuration(config.LocktimeMargin); err != nil {
+			return nil, "", fmt.Errorf("Invalid locktime margin: %v", err)
+		}
+	}
+	testnet := *testnetFlag
+	if swap.Own.Adapter, err = adapter.New(config.Own.Chain, adapter.Config{Testnet: testnet, Key: config.Own.Key}); err != nil {
+		return
+	}
+	swap.Own.Counterparty = config.Own.Counterparty
+	swap.Own.Amount = config.Own.Amount
+	if swap.Counter.Adapter, err = adapter.New(config.Counter.Chain, adapter.Config{Testnet: testnet, Key: config.Counter.Key}); err != nil {
+		return
+	}
+	swap.Counter.Address = config.Counter.Address
+	swap.Counter.Counterparty = config.Counter.Counterparty
+	swap.Counter.Amount = config.Counter.Amount
+	swap.Counter.Asset = config.Counter.Asset
+
+	swap.Send = func(contract adapter.Contract) error {
+		content, _ := json.MarshalIndent(contract, "", "  ")
+		return ioutil.WriteFile(config.Outbox, append(content, '\n'), 0644)
+	}
+	swap.Receive = func() (contract adapter.Contract, ok bool, err error) {
+		content, err := ioutil.ReadFile(config.Inbox)
+		if os.IsNotExist(err) {
+			return contract, false, nil
+		}
+		if err != nil {
+			return
+		}
+		//the counterparty may still be writing the file, an undecodable inbox is retried
+		if json.Unmarshal(content, &contract) != nil {
+			return contract, false, nil
+		}
+		return contract, true, nil
+	}
+	swap.Logf = logger.Infof
+	swap.Persist = func(result adapter.Result) error {
+		if result.Secret != nil {
+			registerSecret(hex.EncodeToString(result.Secret))
+		}
+		content, _ := json.MarshalIndent(result, "", "  ")
+		//a crash while writing must not lose the previous state
+		if err := ioutil.WriteFile(config.State+".tmp", append(content, '\n'), 0600); err != nil {
+			return err
+		}
+		return os.Rename(config.State+".tmp", config.State)
+	}
+	return swap, config.Role, nil
+}
+
+func (cmd *autoSwapCmd) runCommand(client horizonclient.ClientInterface) error {
+	swap, role, err := cmd.newAutoSwap()
+	if err != nil {
+		return err
+	}
+	var result adapter.Result
+	if role == "initiator" {
+		result, err = swap.Initiate()
+	} else {
+		result, err = swap.Participate()
+	}
+	if err != nil && err != adapter.ErrRefunded {
+		return err
+	}
+	if !*automatedFlag {
+		fmt.Printf("Secret hash:      %x\n", result.SecretHash)
+		fmt.Printf("Own contract:     %s\n", result.OwnContract.Address)
+		fmt.Printf("Counter contract: %s\n", result.CounterContract.Address)
+		if result.RedeemTransaction != "" {
+			fmt.Printf("Redeemed in:      %s\n", result.RedeemTransaction)
+		}
+		if result.RefundTransaction != "" {
+			fmt.Printf("Refunded in:      %s\n", result.RefundTransaction)
+		}
+	} else {
+		jsonoutput, _ := json.Marshal(result)
+		fmt.Println(string(jsonoutput))
+	}
+	return err
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+)
+
+type balanceCmd struct {
+	address string
+}
+
+//accountSigner is a signer of an account with its weight
+type accountSigner struct {
+	Key    string `json:"key"`
+	Type   string `json:"type"`
+	Weight int32  `json:"weight"`
+}
+
+//accountBalances are the balances and signing configuration of an account
+type accountBalances struct {
+	Address    string                   `json:"address"`
+	Sequence   string                   `json:"sequence"`
+	Balances   []stellar.CreditedAmount `json:"balances"`
+	Signers    []accountSigner          `json:"signers"`
+	Thresholds struct {
+		Low    byte `json:"low"`
+		Medium byte `json:"medium"`
+		High   byte `json:"high"`
+	} `json:"thresholds"`
+}
+
+func (cmd *balanceCmd) runCommand(client horizonclient.ClientInterface) error {
+	account, err := stellar.GetAccount(cmd.address, client)
+	if err != nil {
+		return fmt.Errorf("Failed to get account %s: %v", cmd.address, err)
+	}
+	balances := accountBalances{
+		Address:  account.AccountID,
+		Sequence: account.Sequence,
+		Balances: make([]stellar.CreditedAmount, 0, len(account.Balances)),
+		Signers:  make([]accountSigner, 0, len(account.Signers)),
+	}
+	for _, balance := range account.Balances {
+		asset := "XLM"
+		if balance.Asset.Type != stellar.NativeAssetType {
+			asset = balance.Code + ":" + balance.Issuer
+		}
+		balances.Balances = append(balances.Balances, stellar.CreditedAmount{Asset: asset, Amount: balance.Balance})
+	}
+	for _, signer := range account.Signers {
+		balances.Signers = append(balances.Signers, accountSigner{Key: signer.Key, Type: signer.Type, Weight: signer.Weight})
+	}
+	balances.Thresholds.Low = account.Thresholds.LowThreshold
+	balances.Thresholds.Medium = account.Thresholds.MedThreshold
+	balances.Thresholds.High = account.Thresholds.HighThreshold
+
+	if *automatedFlag {
+		jsonoutput, _ := json.Marshal(balances)
+		fmt.Println(string(jsonoutput))
+		return nil
+	}
+	fmt.Printf("Account: %s\n", balances.Address)
+	fmt.Printf("Sequence: %s\n", balances.Sequence)
+	for _, balance := range balances.Balances {
+		fmt.Printf("Balance: %s %s\n", balance.Amount, balance.Asset)
+	}
+	fmt.Printf("Thresholds: low %d, medium %d, high %d\n", balances.Thresholds.Low, balances.Thresholds.Medium, balances.Thresholds.High)
+	for _, signer := range balances.Signers {
+		fmt.Printf("Signer: %s (%s) weight %d\n", signer.Key, signer.Type, signer.Weight)
+	}
+	return nil
+}
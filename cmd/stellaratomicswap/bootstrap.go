@@ -0,0 +1,238 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"time"
+
+	"github.com/stellar/go/amount"
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/txnbuild"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+	"github.com/threefoldtech/atomicswap/timings"
+)
+
+//readinessDomain is prefixed to the report before hashing so the signature can never be a valid transaction signature
+const readinessDomain = "stellaratomicswap readiness report v1\n"
+
+//maxClockSkew is the maximum difference between the local clock and the close time of the latest ledger
+const maxClockSkew = time.Minute
+
+//maxIngestionLag is the maximum number of ledgers horizon may be behind stellar core
+const maxIngestionLag = 10
+
+//minSpendableXLM covers the reserves and fees of a holding account
+const minSpendableXLM = 10
+
+type bootstrapCmd struct {
+	signer     stellar.Signer
+	asset      txnbuild.Asset
+	reportFile string
+}
+
+type readinessCheck struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+}
+
+type readinessReport struct {
+	Version     string           `json:"version"`
+	Network     string           `json:"network"`
+	Address     string           `json:"address"`
+	GeneratedAt string           `json:"generatedat"`
+	Ready       bool             `json:"ready"`
+	Checks      []readinessCheck `json:"checks"`
+}
+
+type signedReadinessReport struct {
+	Report    readinessReport `json:"report"`
+	Signer    string          `json:"signer"`
+	Signature string          `json:"signature"`
+}
+
+func (r *readinessReport) check(name string, err error, detail string) {
+	check := readinessCheck{Name: name, OK: err == nil, Detail: detail}
+	if err != nil {
+		check.Detail = err.Error()
+	}
+	r.Checks = append(r.Checks, check)
+}
+
+func (cmd *bootstrapCmd) runCommand(client horizonclient.ClientInterface) error {
+	report := readinessReport{
+		Version:     version,
+		Network:     targetNetwork,
+		Address:     cmd.signer.Address(),
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	//the other checks depend on the network and its parameters, they are not run when horizon or the clock fails
+	detail, err := checkHorizon(client)
+	report.check("horizon", err, detail)
+	if err == nil {
+		var baseReserve int32
+		detail, baseReserve, err = checkClock(client)
+		report.check("clock", err, detail)
+		if err == nil {
+			detail, err = checkSigner(cmd.signer)
+			report.check("key", err, detail)
+			detail, err = checkBalances(cmd.signer.Address(), cmd.asset, baseReserve, client)
+			report.check("balance", err, detail)
+			detail, err = checkLocktimes(*timeoutFlag, *txValidityFlag, *alertBeforeFlag)
+			report.check("locktime", err, detail)
+		}
+	}
+
+	report.Ready = true
+	for _, check := range report.Checks {
+		report.Ready = report.Ready && check.OK
+	}
+
+	signed, err := signReadinessReport(report, cmd.signer)
+	if err != nil {
+		return err
+	}
+	content, _ := json.MarshalIndent(signed, "", "  ")
+	if err = ioutil.WriteFile(cmd.reportFile, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("Failed to write the readiness report: %v", err)
+	}
+
+	if !*automatedFlag {
+		for _, check := range report.Checks {
+			status := "ok"
+			if !check.OK {
+				status = "FAILED"
+			}
+			fmt.Printf("%-8s %-6s %s\n", check.Name, status, check.Detail)
+		}
+		fmt.Printf("\nReadiness report written to %s\n", cmd.reportFile)
+	} else {
+		jsonoutput, _ := json.Marshal(signed)
+		fmt.Println(string(jsonoutput))
+	}
+	if !report.Ready {
+		return errors.New("The environment is not ready for swaps")
+	}
+	return nil
+}
+
+func checkHorizon(client horizonclient.ClientInterface) (detail string, err error) {
+	root, err := client.Root()
+	if err != nil {
+		return "", fmt.Errorf("Failed to connect to horizon: %v", err)
+	}
+	if root.NetworkPassphrase != targetNetwork {
+		return "", fmt.Errorf("Horizon is on network %q", root.NetworkPassphrase)
+	}
+	if lag := root.CoreSequence - root.HorizonSequence; lag > maxIngestionLag {
+		return "", fmt.Errorf("Horizon is %d ledgers behind stellar core", lag)
+	}
+	return fmt.Sprintf("horizon %s, protocol %d, ledger %d", root.HorizonVersion, root.CurrentProtocolVersion, root.HorizonSequence), nil
+}
+
+//checkClock compares the local clock with the close time of the latest ledger, locktimes are checked against the ledger time
+func checkClock(client horizonclient.ClientInterface) (detail string, baseReserve int32, err error) {
+	ledgers, err := client.Ledgers(horizonclient.LedgerRequest{Order: horizonclient.OrderDesc, Limit: 1})
+	if err != nil {
+		return "", 0, fmt.Errorf("Failed to get the latest ledger: %v", err)
+	}
+	if len(ledgers.Embedded.Records) == 0 {
+		return "", 0, errors.New("No ledgers found")
+	}
+	latest := ledgers.Embedded.Records[0]
+	baseReserve = latest.BaseReserve
+	if baseReserve <= 0 {
+		return "", 0, fmt.Errorf("Ledger %d has no base reserve", latest.Sequence)
+	}
+	skew := time.Since(latest.ClosedAt)
+	if skew < -maxClockSkew || skew > maxClockSkew {
+		return "", baseReserve, fmt.Errorf("The local clock differs %v from the close time of ledger %d", skew.Truncate(time.Second), latest.Sequence)
+	}
+	return fmt.Sprintf("local clock within %v of ledger %d", maxClockSkew, latest.Sequence), baseReserve, nil
+}
+
+//checkSigner makes sure the key can sign, external signers are asked for a signature that is verified
+func checkSigner(signer stellar.Signer) (detail string, err error) {
+	probe := sha256.Sum256([]byte(readinessDomain + time.Now().String()))
+	if _, err = stellar.SignDecorated(signer, probe[:]); err != nil {
+		return "", fmt.Errorf("Unable to sign: %v", err)
+	}
+	return fmt.Sprintf("%s can sign", signer.Address()), nil
+}
+
+func checkBalances(address string, asset txnbuild.Asset, baseReserve int32, client horizonclient.ClientInterface) (detail string, err error) {
+	if baseReserve <= 0 {
+		return "", errors.New("The base reserve of the network is unknown, the spendable balance can not be checked")
+	}
+	account, err := client.AccountDetail(horizonclient.AccountRequest{AccountID: address})
+	if err != nil {
+		return "", fmt.Errorf("Failed to get account %s: %v", address, err)
+	}
+	nativeBalance, err := account.GetNativeBalance()
+	if err != nil {
+		return
+	}
+	balance, err := amount.ParseInt64(nativeBalance)
+	if err != nil {
+		return
+	}
+	reserve := int64(2+account.SubentryCount) * int64(baseReserve)
+	spendable := balance - reserve
+	if spendable < minSpendableXLM*amount.One {
+		return "", fmt.Errorf("Only %s XLM spendable, at least %d XLM is needed to cover the holding account reserves", amount.StringFromInt64(spendable), minSpendableXLM)
+	}
+	detail = fmt.Sprintf("%s XLM spendable", amount.StringFromInt64(spendable))
+	if creditAsset, ok := asset.(txnbuild.CreditAsset); ok {
+		assetBalance := account.GetCreditBalance(creditAsset.Code, creditAsset.Issuer)
+		if f, _ := strconv.ParseFloat(assetBalance, 64); f <= 0 {
+			return "", fmt.Errorf("No %s balance, is there a trustline to %s?", creditAsset.Code, creditAsset.Issuer)
+		}
+		detail += fmt.Sprintf(", %s %s", assetBalance, creditAsset.Code)
+	}
+	return
+}
+
+//checkLocktimes verifies the participant's refund is possible well before the initiator's,
+//and that the -timeout, -txvalidity and -alertbefore settings fit within the participant locktime
+func checkLocktimes(timeout time.Duration, txValidity time.Duration, alertBefore time.Duration) (detail string, err error) {
+	initiatorLocktime := timings.LockTime
+	participantLocktime := timings.LockTime / 2
+	if participantLocktime < time.Hour {
+		return "", fmt.Errorf("The participant locktime of %v leaves too little time to redeem", participantLocktime)
+	}
+	if timeout != 0 && timeout >= participantLocktime {
+		return "", fmt.Errorf("The command timeout of %v exceeds the participant locktime of %v", timeout, participantLocktime)
+	}
+	if txValidity >= participantLocktime/2 {
+		return "", fmt.Errorf("The setup transactions stay valid for %v, too long for the participant locktime of %v", txValidity, participantLocktime)
+	}
+	if alertBefore >= participantLocktime {
+		return "", fmt.Errorf("The watchtower alerts %v before the locktime, longer than the participant locktime of %v, it would alert as soon as a swap is set up", alertBefore, participantLocktime)
+	}
+	return fmt.Sprintf("initiator %v, participant %v, setup transactions valid for %v, alerts %v before the locktime", initiatorLocktime, participantLocktime, txValidity, alertBefore), nil
+}
+
+func signReadinessReport(report readinessReport, signer stellar.Signer) (signed signedReadinessReport, err error) {
+	canonical, err := json.Marshal(report)
+	if err != nil {
+		return
+	}
+	digest := sha256.Sum256(append([]byte(readinessDomain), canonical...))
+	signature, err := signer.Sign(digest[:])
+	if err != nil {
+		err = fmt.Errorf("Failed to sign the readiness report: %v", err)
+		return
+	}
+	signed = signedReadinessReport{
+		Report:    report,
+		Signer:    signer.Address(),
+		Signature: base64.StdEncoding.EncodeToString(signature),
+	}
+	return
+}
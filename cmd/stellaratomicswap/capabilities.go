@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+	"github.com/threefoldtech/atomicswap/swapcrypto"
+)
+
+//The kinds of assets a holding account can hold
+const (
+	assetKindNative = "native"
+	assetKindCredit = "credit"
+)
+
+//capabilities is what a version of the tool supports, exchanged with the counterparty before a swap
+type capabilities struct {
+	Version       string   `json:"version"`
+	Assets        []string `json:"assets"`
+	HashFunctions []string `json:"hashfunctions"`
+	SecretSizes   []int    `json:"secretsizes"`
+	//ClaimableBalances is true when contracts can be claimable balances instead of holding accounts
+	ClaimableBalances bool `json:"claimablebalances"`
+}
+
+func ownCapabilities() capabilities {
+	return capabilities{
+		Version:       version,
+		Assets:        []string{assetKindNative, assetKindCredit},
+		HashFunctions: []string{swapcrypto.SHA256.String()},
+		SecretSizes:   supportedSecretSizes(),
+	}
+}
+
+//supportedSecretSizes are the secret sizes a hash(x) signer can be unlocked with, too small secrets can be guessed
+func supportedSecretSizes() (sizes []int) {
+	for size := swapcrypto.MinSecretSize; size <= stellar.MaxHashXPreimageSize; size++ {
+		sizes = append(sizes, size)
+	}
+	return
+}
+
+//legacyCapabilities are assumed for a counterparty that does not send its capabilities
+func legacyCapabilities() capabilities {
+	return capabilities{
+		Version:       "unknown",
+		Assets:        []string{assetKindNative, assetKindCredit},
+		HashFunctions: []string{swapcrypto.SHA256.String()},
+		SecretSizes:   []int{swapcrypto.SecretSize},
+	}
+}
+
+//incompatibilityError lists everything the parties do not agree on
+type incompatibilityError struct {
+	peerVersion string
+	problems    []string
+}
+
+func (e incompatibilityError) Error() string {
+	return fmt.Sprintf("The counterparty's tool version %s is incompatible with version %s:\n  %s", e.peerVersion, version, strings.Join(e.problems, "\n  "))
+}
+
+//negotiateCapabilities selects the parameters both parties support
+func negotiateCapabilities(own capabilities, peer capabilities) (agreed capabilities, err error) {
+	incompatibility := incompatibilityError{peerVersion: peer.Version}
+	agreed.Version = peer.Version
+	if agreed.Assets = intersectStrings(own.Assets, peer.Assets); len(agreed.Assets) == 0 {
+		incompatibility.problems = append(incompatibility.problems, fmt.Sprintf("assets: %v, the counterparty supports %v", own.Assets, peer.Assets))
+	}
+	if agreed.HashFunctions = intersectStrings(own.HashFunctions, peer.HashFunctions); len(agreed.HashFunctions) == 0 {
+		incompatibility.problems = append(incompatibility.problems, fmt.Sprintf("hash functions: %v, the counterparty supports %v", own.HashFunctions, peer.HashFunctions))
+	}
+	for _, size := range own.SecretSizes {
+		for _, peerSize := range peer.SecretSizes {
+			if size == peerSize {
+				agreed.SecretSizes = append(agreed.SecretSizes, size)
+			}
+		}
+	}
+	if len(agreed.SecretSizes) == 0 {
+		incompatibility.problems = append(incompatibility.problems, fmt.Sprintf("secret sizes: %v, the counterparty supports %v", own.SecretSizes, peer.SecretSizes))
+	}
+	agreed.ClaimableBalances = own.ClaimableBalances && peer.ClaimableBalances
+	if len(incompatibility.problems) > 0 {
+		return agreed, incompatibility
+	}
+	return agreed, nil
+}
+
+//supports checks that the agreed capabilities cover a swap
+func (c capabilities) supports(assetKind string, hashFunction string, secretSize int) error {
+	if !containsString(c.Assets, assetKind) {
+		return fmt.Errorf("The %s asset of the swap is not supported by both parties", assetKind)
+	}
+	if !containsString(c.HashFunctions, hashFunction) {
+		return fmt.Errorf("The %s hash function of the swap is not supported by both parties", hashFunction)
+	}
+	for _, size := range c.SecretSizes {
+		if size == secretSize {
+			return nil
+		}
+	}
+	return fmt.Errorf("The %d byte secret of the swap is not supported by both parties", secretSize)
+}
+
+func intersectStrings(a []string, b []string) (intersection []string) {
+	for _, s := range a {
+		if containsString(b, s) {
+			intersection = append(intersection, s)
+		}
+	}
+	return
+}
+
+func containsString(list []string, s string) bool {
+	for _, element := range list {
+		if element == s {
+			return true
+		}
+	}
+	return false
+}
+
+//capabilitiesCmd prints the capabilities of this tool to send to the counterparty
+type capabilitiesCmd struct{}
+
+func (cmd *capabilitiesCmd) runCommand(client horizonclient.ClientInterface) error {
+	return cmd.runOfflineCommand()
+}
+
+func (cmd *capabilitiesCmd) runOfflineCommand() error {
+	jsonoutput, _ := json.Marshal(ownCapabilities())
+	fmt.Println(string(jsonoutput))
+	return nil
+}
+
+//handshakeCmd negotiates the parameters with the capabilities the counterparty sent
+type handshakeCmd struct {
+	peerFile string
+}
+
+func (cmd *handshakeCmd) runCommand(client horizonclient.ClientInterface) error {
+	return cmd.runOfflineCommand()
+}
+
+func (cmd *handshakeCmd) runOfflineCommand() error {
+	content, err := ioutil.ReadFile(cmd.peerFile)
+	if err != nil {
+		return fmt.Errorf("Failed to read the capabilities of the counterparty: %v", err)
+	}
+	var peer capabilities
+	if err = json.Unmarshal(content, &peer); err != nil {
+		return fmt.Errorf("Failed to decode the capabilities of the counterparty: %v", err)
+	}
+	agreed, err := negotiateCapabilities(ownCapabilities(), peer)
+	if err != nil {
+		return err
+	}
+	if *automatedFlag {
+		jsonoutput, _ := json.Marshal(agreed)
+		fmt.Println(string(jsonoutput))
+		return nil
+	}
+	fmt.Printf("Counterparty version: %s\n", agreed.Version)
+	fmt.Printf("Assets: %s\n", strings.Join(agreed.Assets, ", "))
+	fmt.Printf("Hash functions: %s\n", strings.Join(agreed.HashFunctions, ", "))
+	fmt.Printf("Secret sizes: %v\n", agreed.SecretSizes)
+	fmt.Printf("Claimable balances: %v\n", agreed.ClaimableBalances)
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/stellar/go/clients/horizon"
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/txnbuild"
+	"github.com/stellar/go/xdr"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+)
+
+//collectSignatures adds the receiver's signature to a partially signed redeem transaction stored in cmd.collectFile,
+//creating it with the secret if it does not exist yet, and submits it once the holding account thresholds are met.
+func (cmd *redeemCmd) collectSignatures(client horizonclient.ClientInterface) error {
+	holdingAccount, err := stellar.GetAccount(cmd.holdingAccountAddress, client)
+	if err != nil {
+		return err
+	}
+	var txe string
+	content, err := ioutil.ReadFile(cmd.collectFile)
+	switch {
+	case err == nil:
+		txe = strings.TrimSpace(string(content))
+	case os.IsNotExist(err):
+		redeemTransaction, err := createRedeemTransaction(holdingAccount, cmd.receiverAddress, cmd.secret, targetNetwork, client)
+		if err != nil {
+			return err
+		}
+		if txe, err = redeemTransaction.Base64(); err != nil {
+			return fmt.Errorf("Unable to encode the transaction: %v", err)
+		}
+	default:
+		return err
+	}
+
+	tx, err := txnbuild.TransactionFromXDR(txe)
+	if err != nil {
+		return fmt.Errorf("Invalid partially signed redeem transaction in %s: %v", cmd.collectFile, err)
+	}
+	tx.Network = targetNetwork
+	hash, err := tx.Hash()
+	if err != nil {
+		return err
+	}
+	//the file can come from a co-signer, only sign the redeem of this holding account
+	expected, err := redeemDestinationOperations(holdingAccount, cmd.receiverAddress, client)
+	if err != nil {
+		return err
+	}
+	expected = append(expected, createRedeemOperations(holdingAccount, cmd.receiverAddress)...)
+	if err = checkRedeemEnvelope(txe, hash, holdingAccount, expected); err != nil {
+		return fmt.Errorf("Refusing to sign the transaction in %s: %v", cmd.collectFile, err)
+	}
+	if cmd.ReceiverKeyPair != nil {
+		if txe, err = stellar.SignEnvelope(txe, hash, cmd.ReceiverKeyPair); err != nil {
+			return err
+		}
+	}
+	var envelope xdr.TransactionEnvelope
+	if err = xdr.SafeUnmarshalBase64(txe, &envelope); err != nil {
+		return err
+	}
+	weight, missing := stellar.CollectedWeight(envelope.Signatures, hash, holdingAccount.Signers)
+	threshold := int32(holdingAccount.Thresholds.HighThreshold)
+	if weight < threshold {
+		if err = ioutil.WriteFile(cmd.collectFile, []byte(txe+"\n"), 0600); err != nil {
+			return err
+		}
+		missingSigners := make([]string, 0, len(missing))
+		for _, signer := range missing {
+			missingSigners = append(missingSigners, signer.Key)
+		}
+		if !*automatedFlag {
+			fmt.Printf("Collected signing weight %d of %d\n", weight, threshold)
+			fmt.Printf("Partially signed redeem transaction written to %s\n", cmd.collectFile)
+			fmt.Println("Signers that did not sign yet:")
+			for _, signer := range missing {
+				fmt.Printf("  %s (weight %d)\n", signer.Key, signer.Weight)
+			}
+		} else {
+			output := struct {
+				Weight         int32    `json:"weight"`
+				Threshold      int32    `json:"threshold"`
+				Transaction    string   `json:"transaction"`
+				MissingSigners []string `json:"missingsigners"`
+			}{weight, threshold, txe, missingSigners}
+			jsonoutput, _ := json.Marshal(output)
+			fmt.Println(string(jsonoutput))
+		}
+		return nil
+	}
+
+	txSuccess, err := stellar.SubmitTransaction(txe, client)
+	if err != nil {
+		return err
+	}
+	os.Remove(cmd.collectFile)
+	recordRedeem(cmd.holdingAccountAddress, cmd.secret, txSuccess.Hash, targetNetwork)
+	return printRedeemResult(txSuccess, cmd.receiverAddress, client)
+}
+
+//checkRedeemEnvelope verifies a partially signed transaction is the redeem of the holding account:
+//its source is the holding account, its operations are exactly the expected redeem operations,
+//its fee stays within -maxbasefee and the signatures it already has are valid on the target network.
+func checkRedeemEnvelope(txe string, hash [32]byte, holdingAccount *horizon.Account, expected []txnbuild.Operation) error {
+	var envelope xdr.TransactionEnvelope
+	if err := xdr.SafeUnmarshalBase64(txe, &envelope); err != nil {
+		return err
+	}
+	if source := envelope.Tx.SourceAccount.Address(); source != holdingAccount.AccountID {
+		return fmt.Errorf("its source is %s instead of the holding account %s", source, holdingAccount.AccountID)
+	}
+	if len(envelope.Tx.Operations) != len(expected) {
+		return fmt.Errorf("it has %d operations instead of the %d operations of the redeem", len(envelope.Tx.Operations), len(expected))
+	}
+	for i, operation := range expected {
+		expectedXDR, err := operation.BuildXDR()
+		if err != nil {
+			return err
+		}
+		want, err := xdr.MarshalBase64(expectedXDR)
+		if err != nil {
+			return err
+		}
+		got, err := xdr.MarshalBase64(envelope.Tx.Operations[i])
+		if err != nil {
+			return err
+		}
+		if got != want {
+			return fmt.Errorf("operation %d is not the redeem operation %T", i+1, operation)
+		}
+	}
+	if maxFee := uint64(*maxBaseFeeFlag) * uint64(len(expected)); uint64(envelope.Tx.Fee) > maxFee {
+		return fmt.Errorf("its fee of %d stroops is above -maxbasefee for %d operations", envelope.Tx.Fee, len(expected))
+	}
+	//signatures made for another network do not satisfy any signer with the hash on the target network
+	for _, signature := range envelope.Signatures {
+		if weight, _ := stellar.CollectedWeight([]xdr.DecoratedSignature{signature}, hash, holdingAccount.Signers); weight == 0 {
+			return fmt.Errorf("it has a signature that is not valid for a signer of the holding account on the %s network", networkName(targetNetwork))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/network"
+	hprotocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/txnbuild"
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+)
+
+func TestCheckRedeemEnvelope(t *testing.T) {
+	holdingKeyPair, _ := keypair.Random()
+	receiver, _ := keypair.Random()
+	secret := []byte("secret")
+	secretHash := sha256.Sum256(secret)
+	hashxAddress, err := stellar.CreateHashxAddress(secretHash[:])
+	if !assert.NoError(t, err) {
+		return
+	}
+	holdingAccount := newHoldingAccount(holdingKeyPair.Address(), 100, "", txnbuild.NativeAsset{})
+	holdingAccount.Signers = []hprotocol.Signer{
+		{Key: receiver.Address(), Weight: 1, Type: "ed25519_public_key"},
+		{Key: hashxAddress, Weight: 1, Type: "sha256_hash"},
+	}
+	redeem := createRedeemOperations(holdingAccount, receiver.Address())
+
+	build := func(source txnbuild.Account, operations []txnbuild.Operation, passphrase string, baseFee uint32) (string, [32]byte) {
+		tx := txnbuild.Transaction{
+			SourceAccount: source,
+			Operations:    operations,
+			Timebounds:    txnbuild.NewInfiniteTimeout(),
+			Network:       passphrase,
+			BaseFee:       baseFee,
+		}
+		if !assert.NoError(t, tx.Build()) || !assert.NoError(t, tx.SignHashX(secret)) {
+			t.FailNow()
+		}
+		txe, err := tx.Base64()
+		assert.NoError(t, err)
+		tx.Network = targetNetwork
+		hash, err := tx.Hash()
+		assert.NoError(t, err)
+		return txe, hash
+	}
+
+	txe, hash := build(newHoldingAccount(holdingKeyPair.Address(), 100, "", txnbuild.NativeAsset{}), redeem, targetNetwork, 100)
+	assert.NoError(t, checkRedeemEnvelope(txe, hash, holdingAccount, redeem))
+	signed, err := stellar.SignEnvelope(txe, hash, receiver)
+	if assert.NoError(t, err) {
+		assert.NoError(t, checkRedeemEnvelope(signed, hash, holdingAccount, redeem))
+	}
+
+	//a payment from the receiver's own account
+	payment := []txnbuild.Operation{&txnbuild.Payment{Destination: holdingKeyPair.Address(), Amount: "100", Asset: txnbuild.NativeAsset{}}}
+	txe, hash = build(&txnbuild.SimpleAccount{AccountID: receiver.Address(), Sequence: 5}, payment, targetNetwork, 100)
+	assert.Error(t, checkRedeemEnvelope(txe, hash, holdingAccount, redeem))
+
+	//merging the holding account somewhere else
+	other, _ := keypair.Random()
+	txe, hash = build(newHoldingAccount(holdingKeyPair.Address(), 100, "", txnbuild.NativeAsset{}), createRedeemOperations(holdingAccount, other.Address()), targetNetwork, 100)
+	assert.Error(t, checkRedeemEnvelope(txe, hash, holdingAccount, redeem))
+
+	//an extra operation next to the redeem
+	txe, hash = build(newHoldingAccount(holdingKeyPair.Address(), 100, "", txnbuild.NativeAsset{}), append(payment, redeem...), targetNetwork, 100)
+	assert.Error(t, checkRedeemEnvelope(txe, hash, holdingAccount, redeem))
+
+	//a fee that eats the holding account
+	txe, hash = build(newHoldingAccount(holdingKeyPair.Address(), 100, "", txnbuild.NativeAsset{}), redeem, targetNetwork, uint32(*maxBaseFeeFlag)+1)
+	assert.Error(t, checkRedeemEnvelope(txe, hash, holdingAccount, redeem))
+
+	//signed by the receiver for another network
+	otherNetwork := network.TestNetworkPassphrase
+	if targetNetwork == otherNetwork {
+		otherNetwork = network.PublicNetworkPassphrase
+	}
+	tx := txnbuild.Transaction{
+		SourceAccount: newHoldingAccount(holdingKeyPair.Address(), 100, "", txnbuild.NativeAsset{}),
+		Operations:    redeem,
+		Timebounds:    txnbuild.NewInfiniteTimeout(),
+		Network:       otherNetwork,
+		BaseFee:       100,
+	}
+	if assert.NoError(t, tx.Build()) && assert.NoError(t, tx.Sign(receiver)) {
+		txe, err = tx.Base64()
+		if assert.NoError(t, err) {
+			tx.Network = targetNetwork
+			hash, _ = tx.Hash()
+			assert.Error(t, checkRedeemEnvelope(txe, hash, holdingAccount, redeem))
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/xdr"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+)
+
+//decodeTxCmd prints the content of a transaction envelope, to inspect what is signed or submitted
+type decodeTxCmd struct {
+	transaction string
+}
+
+func (cmd *decodeTxCmd) runCommand(client horizonclient.ClientInterface) error {
+	return cmd.runOfflineCommand()
+}
+
+func (cmd *decodeTxCmd) runOfflineCommand() error {
+	txe, err := readTransactionArgument(cmd.transaction)
+	if err != nil {
+		return err
+	}
+	var envelope xdr.TransactionEnvelope
+	if err = xdr.SafeUnmarshalBase64(txe, &envelope); err != nil {
+		return fmt.Errorf("Failed to decode the transaction envelope: %v", err)
+	}
+	decoded, err := stellar.DecodeTransaction(envelope, targetNetwork)
+	if err != nil {
+		return fmt.Errorf("Failed to hash the transaction: %v", err)
+	}
+	if *automatedFlag {
+		jsonoutput, _ := json.Marshal(decoded)
+		fmt.Println(string(jsonoutput))
+		return nil
+	}
+	fmt.Printf("Transaction hash (%s): %s\n", networkName(targetNetwork), decoded.Hash)
+	fmt.Printf("Source account: %s\n", decoded.SourceAccount)
+	fmt.Printf("Sequence: %d\n", decoded.Sequence)
+	fmt.Printf("Fee: %d stroops\n", decoded.Fee)
+	fmt.Printf("Valid from: %s\n", formatTimebound(decoded.MinTime))
+	fmt.Printf("Valid until: %s\n", formatTimebound(decoded.MaxTime))
+	if decoded.Memo != "" {
+		fmt.Printf("Memo: %s\n", decoded.Memo)
+	}
+	fmt.Printf("Operations: %d\n", len(decoded.Operations))
+	for i, operation := range decoded.Operations {
+		fmt.Printf("  %d: %s\n", i, operation.Type)
+		if operation.SourceAccount != "" {
+			fmt.Printf("     source account: %s\n", operation.SourceAccount)
+		}
+		fields := make([]string, 0, len(operation.Fields))
+		for field := range operation.Fields {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			fmt.Printf("     %s: %s\n", field, operation.Fields[field])
+		}
+	}
+	fmt.Printf("Signatures: %d\n", len(decoded.Signatures))
+	for i, signature := range decoded.Signatures {
+		switch {
+		case signature.Signer != "":
+			fmt.Printf("  %d: signed by %s\n", i, signature.Signer)
+		case signature.HashX:
+			fmt.Printf("  %d: hash preimage %s, this reveals the secret\n", i, signature.Signature)
+		default:
+			fmt.Printf("  %d: unknown signer with hint %s\n", i, signature.Hint)
+		}
+	}
+	return nil
+}
+
+//formatTimebound formats an optional timebound, nil means unbounded
+func formatTimebound(t *time.Time) string {
+	if t == nil {
+		return "unbounded"
+	}
+	return t.Format(time.RFC3339)
+}
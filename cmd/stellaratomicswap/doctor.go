@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/network"
+	"github.com/stellar/go/txnbuild"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/swapdb"
+)
+
+//doctorCmd checks the environment before funds are committed and explains how to fix what is wrong.
+//Unlike bootstrap it also accepts an address and does not write a signed report.
+type doctorCmd struct {
+	key   string
+	asset txnbuild.Asset
+}
+
+//diagnostic is a readiness check with the action to take when it fails
+type diagnostic struct {
+	readinessCheck
+	Hint string `json:"hint,omitempty"`
+}
+
+type diagnostics []diagnostic
+
+func (d *diagnostics) check(name string, err error, detail string, hint string) {
+	check := diagnostic{readinessCheck: readinessCheck{Name: name, OK: err == nil, Detail: detail}}
+	if err != nil {
+		check.Detail = err.Error()
+		check.Hint = hint
+	}
+	*d = append(*d, check)
+}
+
+func (cmd *doctorCmd) runCommand(client horizonclient.ClientInterface) error {
+	var checks diagnostics
+
+	detail, err := checkHorizon(client)
+	horizonHint := "Check the network connection and -horizon"
+	if targetNetwork == network.TestNetworkPassphrase {
+		horizonHint += ", or remove -testnet to use the public network"
+	} else {
+		horizonHint += ", or add -testnet to use the test network"
+	}
+	checks.check("horizon", err, detail, horizonHint)
+	horizonOK := err == nil
+	var baseReserve int32
+	if horizonOK {
+		detail, baseReserve, err = checkClock(client)
+		checks.check("clock", err, detail, "Synchronize the system clock, for example with NTP, the locktimes are compared with the ledger close time")
+	}
+
+	address, detail, err := cmd.checkKey()
+	checks.check("key", err, detail, "Check the seed or mnemonic, or that the keyring or external signer backend is reachable and its credentials are set")
+
+	if horizonOK && address != "" {
+		balanceHint := "Add XLM to the account"
+		if targetNetwork == network.TestNetworkPassphrase {
+			balanceHint = "Fund the account with fundtestnet " + address
+		}
+		if _, ok := cmd.asset.(txnbuild.CreditAsset); ok {
+			balanceHint += ", and make sure it holds the asset given with -asset"
+		}
+		detail, err = checkBalances(address, cmd.asset, baseReserve, client)
+		checks.check("balance", err, detail, balanceHint)
+	}
+
+	detail, err = checkSwapDB()
+	checks.check("database", err, detail, "Stop the swapd or watchtower that has the database open, or choose another directory with -db")
+
+	ready := true
+	for _, check := range checks {
+		ready = ready && check.OK
+	}
+	if *automatedFlag {
+		jsonoutput, _ := json.Marshal(checks)
+		fmt.Println(string(jsonoutput))
+	} else {
+		for _, check := range checks {
+			status := "ok"
+			if !check.OK {
+				status = "FAILED"
+			}
+			fmt.Printf("%-8s %-6s %s\n", check.Name, status, check.Detail)
+			if check.Hint != "" {
+				fmt.Printf("%-15s %s\n", "", check.Hint)
+			}
+		}
+	}
+	if !ready {
+		return errors.New("The environment is not ready for swaps")
+	}
+	return nil
+}
+
+//checkKey loads the key to check that it can sign, an address is only checked for being valid
+func (cmd *doctorCmd) checkKey() (address string, detail string, err error) {
+	if parseAddress(cmd.key) == nil {
+		return cmd.key, fmt.Sprintf("%s, no key given so signing is not checked", cmd.key), nil
+	}
+	signer, err := parseSigner(cmd.key)
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to load the key: %v", err)
+	}
+	detail, err = checkSigner(signer)
+	return signer.Address(), detail, err
+}
+
+//checkSwapDB makes sure the swap database can be opened, only one process can have it open
+func checkSwapDB() (detail string, err error) {
+	if *swapDBFlag == "" {
+		return "the swap database is disabled, swaps can not be resumed or listed", nil
+	}
+	var swaps []swapdb.Swap
+	err = withSwapDB(func(db *swapdb.DB) (err error) {
+		swaps, err = db.List()
+		return
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s, %d swaps recorded", *swapDBFlag, len(swaps)), nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/keypair"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+	"github.com/threefoldtech/atomicswap/swapcrypto"
+)
+
+//releaseSecretCmd lets a mediator hand the escrowed secret to the participant
+type releaseSecretCmd struct {
+	mediatorKeyPair    *keypair.Full
+	escrow             string
+	participantAddress string
+}
+
+//openSecretCmd decrypts an escrow with the key it was encrypted to
+type openSecretCmd struct {
+	keyPair *keypair.Full
+	escrow  string
+}
+
+//escrowKeyPair parses the key of an escrow recipient, decrypting needs the seed itself so external signers can not be used
+func escrowKeyPair(seedOrMnemonic string) (*keypair.Full, error) {
+	signer, err := parseSigner(seedOrMnemonic)
+	if err != nil {
+		return nil, err
+	}
+	kp, ok := signer.(*keypair.Full)
+	if !ok {
+		return nil, errors.New("decrypting an escrow requires a seed, not an external signer")
+	}
+	return kp, nil
+}
+
+func (cmd *releaseSecretCmd) runCommand(client horizonclient.ClientInterface) error {
+	return cmd.runOfflineCommand()
+}
+
+func (cmd *releaseSecretCmd) runOfflineCommand() error {
+	secret, err := stellar.DecryptSecret(cmd.escrow, cmd.mediatorKeyPair)
+	if err != nil {
+		return err
+	}
+	registerSecret(hex.EncodeToString(secret))
+	escrow, err := stellar.EncryptSecret(secret, cmd.participantAddress)
+	if err != nil {
+		return fmt.Errorf("Failed to encrypt the secret to the participant: %v", err)
+	}
+	if !*automatedFlag {
+		fmt.Printf("Secret hash: %x\n\n", swapcrypto.Sha256Hash(secret))
+		fmt.Printf("Secret escrow for %s:\n%s\n", cmd.participantAddress, escrow)
+	} else {
+		output := struct {
+			SecretHash  string `json:"hash"`
+			Participant string `json:"participant"`
+			Escrow      string `json:"escrow"`
+		}{
+			fmt.Sprintf("%x", swapcrypto.Sha256Hash(secret)),
+			cmd.participantAddress,
+			escrow,
+		}
+		jsonoutput, _ := json.Marshal(output)
+		fmt.Println(string(jsonoutput))
+	}
+	return nil
+}
+
+func (cmd *openSecretCmd) runCommand(client horizonclient.ClientInterface) error {
+	return cmd.runOfflineCommand()
+}
+
+func (cmd *openSecretCmd) runOfflineCommand() error {
+	secret, err := stellar.DecryptSecret(cmd.escrow, cmd.keyPair)
+	if err != nil {
+		return err
+	}
+	registerSecret(hex.EncodeToString(secret))
+	if !*automatedFlag {
+		fmt.Printf("Secret:      %x\n", secret)
+		fmt.Printf("Secret hash: %x\n", swapcrypto.Sha256Hash(secret))
+	} else {
+		output := struct {
+			Secret     string `json:"secret"`
+			SecretHash string `json:"hash"`
+		}{
+			fmt.Sprintf("%x", secret),
+			fmt.Sprintf("%x", swapcrypto.Sha256Hash(secret)),
+		}
+		jsonoutput, _ := json.Marshal(output)
+		fmt.Println(string(jsonoutput))
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/threefoldtech/atomicswap/adapter"
+)
+
+//eventBuffer is the number of events a subscriber can fall behind before it misses events
+const eventBuffer = 64
+
+//eventKeepalive is the interval of the comments that keep an idle event stream open through proxies
+const eventKeepalive = 30 * time.Second
+
+//eventStream is the notifier of the daemon's event endpoints, it passes the events to the connected subscribers
+type eventStream struct {
+	lock sync.Mutex
+	//subscribers maps the channel of a subscriber to the swap id or contract address it follows, empty for all events
+	subscribers map[chan swapEvent]string
+}
+
+func newEventStream() *eventStream {
+	return &eventStream{subscribers: make(map[chan swapEvent]string)}
+}
+
+//subscribe returns a channel receiving the events of the swap with the id or contract address, all events if it is empty
+func (s *eventStream) subscribe(swap string) chan swapEvent {
+	events := make(chan swapEvent, eventBuffer)
+	s.lock.Lock()
+	s.subscribers[events] = swap
+	s.lock.Unlock()
+	return events
+}
+
+func (s *eventStream) unsubscribe(events chan swapEvent) {
+	s.lock.Lock()
+	delete(s.subscribers, events)
+	s.lock.Unlock()
+}
+
+//deliver never blocks on a slow subscriber, the events it can not keep up with are dropped
+func (s *eventStream) deliver(event swapEvent) error {
+	ids := eventSwaps(event.Data)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for events, swap := range s.subscribers {
+		if swap != "" && !ids[swap] {
+			continue
+		}
+		select {
+		case events <- event:
+		default:
+			logger.WithField("event", event.Event).Warn("An event subscriber is not keeping up, dropping the event")
+		}
+	}
+	return nil
+}
+
+//eventSwaps returns the swap id and the contract address an event is about
+func eventSwaps(data interface{}) map[string]bool {
+	ids := make(map[string]bool)
+	switch data := data.(type) {
+	case trackedSwap:
+		ids[data.ID] = true
+		if data.Contract != nil {
+			ids[data.Contract.Address] = true
+		}
+	case map[string]string:
+		ids[data["contract"]] = true
+		ids[data["holdingaccount"]] = true
+	default:
+		//the audit results carry the contract address
+		var fields struct {
+			Contract adapter.Contract `json:"contract"`
+		}
+		if encoded, err := json.Marshal(data); err == nil && json.Unmarshal(encoded, &fields) == nil {
+			ids[fields.Contract.Address] = true
+		}
+	}
+	delete(ids, "")
+	return ids
+}
+
+//swapEvents streams the events of a swap for /swaps/{id}/events, and all events for /events, as server-sent events
+func (cmd *swapdCmd) swapEvents(w http.ResponseWriter, r *http.Request) {
+	if !cmd.authorize(w, r, http.MethodGet) {
+		return
+	}
+	var swap string
+	if r.URL.Path != "/events" {
+		swap = strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/swaps/"), "/events")
+		if swap == "" || strings.Contains(swap, "/") || !strings.HasSuffix(r.URL.Path, "/events") {
+			writeResponse(w, errorResponse(http.StatusNotFound, "not found"))
+			return
+		}
+		//untracked contracts can be followed by their address
+		if _, err := cmd.lookup(swap); err != nil && parseAddress(swap) != nil {
+			writeResponse(w, errorResponse(http.StatusNotFound, err.Error()))
+			return
+		}
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeResponse(w, errorResponse(http.StatusInternalServerError, "streaming is not supported"))
+		return
+	}
+	events := cmd.events.subscribe(swap)
+	defer cmd.events.unsubscribe(events)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	keepalive := time.NewTicker(eventKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		case event := <-events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Event, data)
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/atomicswap/adapter"
+)
+
+func TestSwapEvents(t *testing.T) {
+	cmd := &swapdCmd{token: "token", events: newEventStream(), swaps: make(map[string]*trackedSwap)}
+	cmd.notifiers = notifiers{cmd.events}
+	cmd.swaps["swap1"] = &trackedSwap{ID: "swap1"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", cmd.swapEvents)
+	mux.HandleFunc("/swaps/", cmd.swapEvents)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	get := func(path string, token string) *http.Response {
+		request, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		response, err := http.DefaultClient.Do(request)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return response
+	}
+	response := get("/swaps/swap1/events", "wrong")
+	response.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	response = get("/swaps/unknown/events", "token")
+	response.Body.Close()
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+
+	response = get("/swaps/swap1/events", "token")
+	defer response.Body.Close()
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+	other, _ := keypair.Random()
+	//the events of other swaps are not streamed
+	assert.NoError(t, cmd.events.deliver(swapEvent{Event: eventRefunded, Data: map[string]string{"contract": other.Address()}}))
+	assert.NoError(t, cmd.events.deliver(swapEvent{Event: eventInitiated, Data: trackedSwap{ID: "swap1", Contract: &adapter.Contract{Address: other.Address()}}}))
+
+	reader := bufio.NewReader(response.Body)
+	line, err := reader.ReadString('\n')
+	if assert.NoError(t, err) {
+		assert.Equal(t, "event: "+eventInitiated+"\n", line)
+	}
+	line, err = reader.ReadString('\n')
+	if assert.NoError(t, err) && assert.True(t, strings.HasPrefix(line, "data: ")) {
+		var event struct {
+			Event string      `json:"event"`
+			Data  trackedSwap `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+		assert.Equal(t, eventInitiated, event.Event)
+		assert.Equal(t, "swap1", event.Data.ID)
+	}
+
+	//the audit results are matched on the contract address
+	assert.Equal(t, map[string]bool{other.Address(): true}, eventSwaps(struct {
+		adapter.Audit
+		SecretHash string `json:"secrethash"`
+	}{adapter.Audit{Contract: adapter.Contract{Address: other.Address()}}, "aa"}))
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stellar/go/txnbuild"
+	"github.com/stellar/go/xdr"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+)
+
+//exportRefundTransaction writes the refund transaction to path as base64 XDR, to path.txrep as SEP-0011 txrep
+//and the sha256 checksums of both files to path.sha256 in the format of the sha256sum tool.
+func exportRefundTransaction(path string, refundTransaction txnbuild.Transaction) (err error) {
+	txe, err := refundTransaction.Base64()
+	if err != nil {
+		return
+	}
+	var envelope xdr.TransactionEnvelope
+	if err = xdr.SafeUnmarshalBase64(txe, &envelope); err != nil {
+		return
+	}
+	txrep, err := stellar.TxRep(envelope)
+	if err != nil {
+		return
+	}
+	files := []struct {
+		path    string
+		content []byte
+	}{
+		{path, []byte(txe + "\n")},
+		{path + ".txrep", []byte(txrep)},
+	}
+	checksums := ""
+	for _, file := range files {
+		if err = ioutil.WriteFile(file.path, file.content, 0644); err != nil {
+			return fmt.Errorf("Failed to write the refund transaction to %s: %v", file.path, err)
+		}
+		checksums += fmt.Sprintf("%x  %s\n", sha256.Sum256(file.content), filepath.Base(file.path))
+	}
+	if err = ioutil.WriteFile(path+".sha256", []byte(checksums), 0644); err != nil {
+		return fmt.Errorf("Failed to write the refund transaction checksums: %v", err)
+	}
+	if !*automatedFlag {
+		fmt.Printf("refund transaction written to %s, %s.txrep and %s.sha256\n", path, path, path)
+	}
+	return
+}
+
+//parseTransactionArgument decodes a base64 XDR transaction argument.
+//Long envelopes get mangled by shells, so @<file> reads it from a file and - from stdin.
+func parseTransactionArgument(arg string) (txnbuild.Transaction, error) {
+	txe, err := readTransactionArgument(arg)
+	if err != nil {
+		return txnbuild.Transaction{}, err
+	}
+	return txnbuild.TransactionFromXDR(txe)
+}
+
+//readTransactionArgument returns the base64 XDR of a transaction argument as parseTransactionArgument accepts it
+func readTransactionArgument(arg string) (string, error) {
+	return readArgument(arg)
+}
+
+//readArgument returns the value of an argument that can also be read from a file with @<file> or from stdin with -
+func readArgument(arg string) (string, error) {
+	value := arg
+	switch {
+	case arg == "-":
+		content, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("Failed to read stdin: %v", err)
+		}
+		value = string(content)
+	case strings.HasPrefix(arg, "@"):
+		content, err := ioutil.ReadFile(arg[1:])
+		if err != nil {
+			return "", err
+		}
+		value = string(content)
+	}
+	return strings.TrimSpace(value), nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/txnbuild"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+)
+
+//feeSigner is the account given with -feeaccount that pays for the transactions of the funding account, nil if it is not set
+var feeSigner stellar.Signer
+
+//feeAccountLock serializes the use of the sequence number of the fee account
+var feeAccountLock sync.Mutex
+
+//useFeeAccount makes the fee account the source of a transaction whose operations all have their own source account.
+//The fee account then pays the fee and its sequence number is used, so concurrent swaps do not compete for the
+//sequence number of the funding account. The returned signers include the fee account if it is used.
+//The fee account stays locked until unlock is called, which has to happen after the transaction is submitted.
+func useFeeAccount(tx *txnbuild.Transaction, signers []stellar.Signer, client horizonclient.ClientInterface) (allSigners []stellar.Signer, unlock func(), err error) {
+	if feeSigner == nil {
+		return signers, func() {}, nil
+	}
+	feeAccountLock.Lock()
+	feeAccount, err := stellar.GetAccount(feeSigner.Address(), client)
+	if err != nil {
+		feeAccountLock.Unlock()
+		return nil, nil, err
+	}
+	tx.SourceAccount = feeAccount
+	return append(signers, feeSigner), feeAccountLock.Unlock, nil
+}
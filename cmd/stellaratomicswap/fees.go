@@ -0,0 +1,48 @@
+package main
+
+import (
+	"github.com/stellar/go/clients/horizonclient"
+)
+
+//minBaseFee is the minimum base fee per operation the network accepts, in stroops
+const minBaseFee = 100
+
+//suggestBaseFee returns the base fee per operation for a transaction submitted now.
+//Unless -basefee is set, it bids the 90th percentile of the fees accepted in the recent ledgers, capped by -maxbasefee.
+//Only the fee needed to get into the ledger is charged, so bidding high during surge pricing costs little.
+func suggestBaseFee(client horizonclient.ClientInterface) uint32 {
+	if *baseFeeFlag != 0 {
+		return uint32(*baseFeeFlag)
+	}
+	stats, err := client.FeeStats()
+	if err != nil {
+		logger.Warnf("Failed to get the fee statistics, using the minimum base fee: %v", err)
+		return clampBaseFee(minBaseFee)
+	}
+	fee := stats.P90AcceptedFee
+	if stats.LastLedgerBaseFee > fee {
+		fee = stats.LastLedgerBaseFee
+	}
+	logger.WithField("capacityusage", stats.LedgerCapacityUsage).Debugf("Suggested base fee %d stroops", fee)
+	return clampBaseFee(fee)
+}
+
+//refundBaseFee is the base fee of the refund transaction.
+//It is signed now but only submitted after the locktime, when the fees can not be known yet,
+//and it can not be rebuilt afterwards, so it bids the maximum unless -basefee is set.
+func refundBaseFee() uint32 {
+	if *baseFeeFlag != 0 {
+		return uint32(*baseFeeFlag)
+	}
+	return clampBaseFee(int(*maxBaseFeeFlag))
+}
+
+func clampBaseFee(fee int) uint32 {
+	if fee < minBaseFee {
+		fee = minBaseFee
+	}
+	if max := int(*maxBaseFeeFlag); fee > max && max >= minBaseFee {
+		fee = max
+	}
+	return uint32(fee)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/stellar/go/amount"
+	"github.com/stellar/go/txnbuild"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+)
+
+//feeAccountCheckInterval is how often swapd checks the balance of the fee account
+const feeAccountCheckInterval = time.Minute
+
+//feeTopUpWindow is the period the top-up limit applies to
+const feeTopUpWindow = 24 * time.Hour
+
+//feeTopUp keeps the XLM balance of the fee account above a threshold by paying it from the funding account of swapd.
+//The amounts are in stroops.
+type feeTopUp struct {
+	below  int64
+	amount int64
+	//limit is the maximum amount topped up within feeTopUpWindow
+	limit int64
+	//topUps are the top-ups within the last feeTopUpWindow
+	topUps []feeTopUpRecord
+	//alerted is set when the low balance was notified, until the balance is above the threshold again
+	alerted bool
+}
+
+type feeTopUpRecord struct {
+	time   time.Time
+	amount int64
+}
+
+//newFeeTopUp parses the decimal amounts of the top-up flags
+func newFeeTopUp(below string, topUpAmount string, limit string) (*feeTopUp, error) {
+	f := &feeTopUp{}
+	var err error
+	if f.below, err = amount.ParseInt64(below); err != nil || f.below <= 0 {
+		return nil, fmt.Errorf("invalid -feetopup amount %q", below)
+	}
+	if f.amount, err = amount.ParseInt64(topUpAmount); err != nil || f.amount <= 0 {
+		return nil, fmt.Errorf("invalid -feetopupamount %q", topUpAmount)
+	}
+	if f.limit, err = amount.ParseInt64(limit); err != nil || f.limit < f.amount {
+		return nil, fmt.Errorf("invalid -feetopuplimit %q, it should be at least -feetopupamount", limit)
+	}
+	return f, nil
+}
+
+//next returns the amount to top up for the balance of the fee account, 0 if none,
+//and the reason to alert the operator when the balance is low but can not be topped up within the limit.
+func (f *feeTopUp) next(balance int64, now time.Time) (topUp int64, alert string) {
+	if balance >= f.below {
+		f.alerted = false
+		return 0, ""
+	}
+	var spent int64
+	recent := f.topUps[:0]
+	for _, record := range f.topUps {
+		if now.Sub(record.time) < feeTopUpWindow {
+			recent = append(recent, record)
+			spent += record.amount
+		}
+	}
+	f.topUps = recent
+	if spent+f.amount > f.limit {
+		return 0, f.alert(fmt.Sprintf("the top-up limit of %s XLM per %s is reached", amount.StringFromInt64(f.limit), feeTopUpWindow))
+	}
+	return f.amount, ""
+}
+
+//alert returns the reason only once until the balance is above the threshold again
+func (f *feeTopUp) alert(reason string) string {
+	if f.alerted {
+		return ""
+	}
+	f.alerted = true
+	return reason
+}
+
+//record registers a submitted top-up
+func (f *feeTopUp) record(topUp int64, now time.Time) {
+	f.topUps = append(f.topUps, feeTopUpRecord{time: now, amount: topUp})
+}
+
+//watchFeeAccount checks the balance of the fee account periodically and tops it up
+func (cmd *swapdCmd) watchFeeAccount() {
+	for {
+		cmd.checkFeeAccount()
+		time.Sleep(feeAccountCheckInterval)
+	}
+}
+
+//checkFeeAccount tops up the fee account from the funding account when its XLM balance is below the threshold
+func (cmd *swapdCmd) checkFeeAccount() {
+	client := cmd.adapter.client
+	feeAddress := feeSigner.Address()
+	feeAccount, err := stellar.GetAccount(feeAddress, client)
+	if err != nil {
+		logger.Warnf("Failed to get the fee account %s: %v", feeAddress, err)
+		return
+	}
+	var balance int64
+	for _, b := range feeAccount.Balances {
+		if b.Asset.Type == stellar.NativeAssetType {
+			balance, _ = amount.ParseInt64(b.Balance)
+		}
+	}
+	topUp, alert := cmd.feeTopUp.next(balance, time.Now())
+	if alert != "" {
+		logger.Warnf("The XLM balance of the fee account %s is low: %s", feeAddress, alert)
+		cmd.notify(eventFeeAccountLow, map[string]string{"feeaccount": feeAddress, "balance": amount.StringFromInt64(balance), "reason": alert})
+	}
+	if topUp == 0 {
+		return
+	}
+	hash, err := cmd.payFeeAccount(topUp)
+	if err != nil {
+		logger.Errorf("Failed to top up the fee account %s: %v", feeAddress, err)
+		if alert = cmd.feeTopUp.alert(fmt.Sprintf("the top-up failed: %v", err)); alert != "" {
+			cmd.notify(eventFeeAccountLow, map[string]string{"feeaccount": feeAddress, "balance": amount.StringFromInt64(balance), "reason": alert})
+		}
+		return
+	}
+	cmd.feeTopUp.record(topUp, time.Now())
+	logger.WithField("transaction", hash).Infof("Topped up the fee account %s with %s XLM", feeAddress, amount.StringFromInt64(topUp))
+	cmd.notify(eventFeeAccountToppedUp, map[string]string{"feeaccount": feeAddress, "amount": amount.StringFromInt64(topUp), "transaction": hash})
+}
+
+//payFeeAccount pays topUp stroops of XLM from the funding account to the fee account
+func (cmd *swapdCmd) payFeeAccount(topUp int64) (hash string, err error) {
+	client := cmd.adapter.client
+	cmd.fundingLock.Lock()
+	defer cmd.fundingLock.Unlock()
+	fundingAccount, err := stellar.GetAccount(cmd.signer.Address(), client)
+	if err != nil {
+		return "", fmt.Errorf("Failed to get the funding account: %v", err)
+	}
+	tx := txnbuild.Transaction{
+		SourceAccount: fundingAccount,
+		Operations: []txnbuild.Operation{
+			&txnbuild.Payment{Destination: feeSigner.Address(), Amount: amount.StringFromInt64(topUp), Asset: txnbuild.NativeAsset{}},
+		},
+		Timebounds: setupTimebounds(),
+		Network:    targetNetwork,
+		BaseFee:    suggestBaseFee(client),
+	}
+	txe, err := stellar.BuildSignEncode(&tx, cmd.signer)
+	if err != nil {
+		return "", err
+	}
+	result, err := stellar.SubmitTransaction(txe, client)
+	if err != nil {
+		return "", err
+	}
+	return result.Hash, nil
+}
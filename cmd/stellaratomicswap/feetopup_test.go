@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeeTopUp(t *testing.T) {
+	_, err := newFeeTopUp("x", "10", "100")
+	assert.Error(t, err)
+	_, err = newFeeTopUp("5", "0", "100")
+	assert.Error(t, err)
+	_, err = newFeeTopUp("5", "10", "9")
+	assert.Error(t, err)
+
+	f, err := newFeeTopUp("5", "10", "25")
+	if !assert.NoError(t, err) {
+		return
+	}
+	now := time.Now()
+	const xlm = 10000000
+
+	topUp, alert := f.next(5*xlm, now)
+	assert.Equal(t, int64(0), topUp)
+	assert.Empty(t, alert)
+
+	//below the threshold it is topped up until the limit is reached
+	for i := 0; i < 2; i++ {
+		topUp, alert = f.next(4*xlm, now)
+		assert.Equal(t, int64(10*xlm), topUp)
+		assert.Empty(t, alert)
+		f.record(topUp, now)
+	}
+	topUp, alert = f.next(4*xlm, now)
+	assert.Equal(t, int64(0), topUp)
+	assert.Contains(t, alert, "limit")
+	//the alert is only sent once
+	topUp, alert = f.next(4*xlm, now.Add(time.Hour))
+	assert.Equal(t, int64(0), topUp)
+	assert.Empty(t, alert)
+
+	//the top-ups older than the window no longer count
+	topUp, alert = f.next(4*xlm, now.Add(feeTopUpWindow))
+	assert.Equal(t, int64(10*xlm), topUp)
+	assert.Empty(t, alert)
+	f.record(topUp, now.Add(feeTopUpWindow))
+
+	//a balance above the threshold rearms the alert
+	f.next(6*xlm, now.Add(feeTopUpWindow))
+	assert.Equal(t, "the top-up failed", f.alert("the top-up failed"))
+	assert.Empty(t, f.alert("the top-up failed"))
+}
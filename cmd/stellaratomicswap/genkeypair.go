@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/network"
+)
+
+//friendbotURL is the testnet faucet that creates and funds accounts
+const friendbotURL = "https://friendbot.stellar.org/"
+
+//genKeyPairCmd creates a new random keypair, so no other tool is needed to start swapping
+type genKeyPairCmd struct {
+	fundTestnet bool
+}
+
+func (cmd *genKeyPairCmd) runCommand(client horizonclient.ClientInterface) error {
+	if cmd.fundTestnet && targetNetwork != network.TestNetworkPassphrase {
+		return errors.New("Only testnet accounts can be funded, add -testnet")
+	}
+	kp, err := keypair.Random()
+	if err != nil {
+		return fmt.Errorf("Failed to generate a keypair: %v", err)
+	}
+	if cmd.fundTestnet {
+		if err = fundWithFriendbot(kp.Address()); err != nil {
+			return fmt.Errorf("Failed to fund %s with friendbot: %v", kp.Address(), err)
+		}
+	}
+	if *automatedFlag {
+		output := struct {
+			Address string `json:"address"`
+			Seed    string `json:"seed"`
+			Funded  bool   `json:"funded"`
+		}{kp.Address(), kp.Seed(), cmd.fundTestnet}
+		jsonoutput, _ := json.Marshal(output)
+		fmt.Println(string(jsonoutput))
+		return nil
+	}
+	fmt.Printf("Address: %s\n", kp.Address())
+	fmt.Printf("Seed:    %s\n", kp.Seed())
+	if cmd.fundTestnet {
+		fmt.Println("The account is created and funded on testnet")
+	} else {
+		fmt.Println("The account does not exist until it receives at least the minimum balance")
+	}
+	return nil
+}
+
+//fundWithFriendbot asks friendbot to create the testnet account
+func fundWithFriendbot(address string) error {
+	transport, err := newHorizonHTTPTransport()
+	if err != nil {
+		return err
+	}
+	httpClient := &http.Client{Transport: horizonTransport(transport)}
+	response, err := httpClient.Get(friendbotURL + "?addr=" + url.QueryEscape(address))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusBadRequest {
+		//friendbot only creates accounts
+		return errors.New("the account probably already exists")
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("friendbot answered %s", response.Status)
+	}
+	return nil
+}
+
+//fundTestnetCmd creates and funds an existing keypair's account on testnet
+type fundTestnetCmd struct {
+	address string
+}
+
+func (cmd *fundTestnetCmd) runCommand(client horizonclient.ClientInterface) error {
+	if targetNetwork != network.TestNetworkPassphrase {
+		return errors.New("friendbot only funds testnet accounts, add -testnet")
+	}
+	if err := fundWithFriendbot(cmd.address); err != nil {
+		return fmt.Errorf("Failed to fund %s with friendbot: %v", cmd.address, err)
+	}
+	if *automatedFlag {
+		output := struct {
+			Address string `json:"address"`
+		}{cmd.address}
+		jsonoutput, _ := json.Marshal(output)
+		fmt.Println(string(jsonoutput))
+		return nil
+	}
+	fmt.Printf("%s is created and funded on testnet\n", cmd.address)
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/swapdb"
+)
+
+//haltLock guards memoryHalt, which holds the halt switch when the swap database is disabled
+var (
+	haltLock   sync.Mutex
+	memoryHalt swapdb.Halt
+)
+
+//getHalt returns the halt switch.
+//It is read from the database every time so a halt set from the command line reaches a running daemon.
+func getHalt() (halt swapdb.Halt, err error) {
+	if *swapDBFlag == "" {
+		haltLock.Lock()
+		defer haltLock.Unlock()
+		return memoryHalt, nil
+	}
+	err = withSwapDB(func(db *swapdb.DB) (err error) {
+		halt, err = db.GetHalt()
+		return
+	})
+	return
+}
+
+func setHalt(halt swapdb.Halt) error {
+	if *swapDBFlag == "" {
+		haltLock.Lock()
+		defer haltLock.Unlock()
+		memoryHalt = halt
+		return nil
+	}
+	return withSwapDB(func(db *swapdb.DB) error {
+		return db.PutHalt(halt)
+	})
+}
+
+//newHalt returns a set halt switch with a reason or an unset one
+func newHalt(halted bool, reason string) swapdb.Halt {
+	if !halted {
+		return swapdb.Halt{}
+	}
+	return swapdb.Halt{Halted: true, Reason: reason, Since: time.Now()}
+}
+
+//checkNotHalted returns an error when the halt switch is set.
+//If the switch can not be read it is considered set, funds are not moved when in doubt.
+func checkNotHalted() error {
+	halt, err := getHalt()
+	if err != nil {
+		return apiError{code: http.StatusServiceUnavailable, err: fmt.Errorf("Failed to read the halt switch: %v", err)}
+	}
+	if halt.Halted {
+		return apiError{code: http.StatusServiceUnavailable, err: fmt.Errorf("Halted since %s: %s", halt.Since.Format(time.RFC3339), halt.Reason)}
+	}
+	return nil
+}
+
+//haltCmd sets or clears the halt switch of the daemon
+type haltCmd struct {
+	halted bool
+	reason string
+}
+
+func (cmd *haltCmd) runCommand(client horizonclient.ClientInterface) error {
+	return cmd.runOfflineCommand()
+}
+
+func (cmd *haltCmd) runOfflineCommand() error {
+	if *swapDBFlag == "" {
+		return errors.New("The swap database is disabled, the halt switch can only be set through the daemon api")
+	}
+	halt := newHalt(cmd.halted, cmd.reason)
+	if err := setHalt(halt); err != nil {
+		return fmt.Errorf("Failed to set the halt switch: %v", err)
+	}
+	if *automatedFlag {
+		jsonoutput, _ := json.Marshal(halt)
+		fmt.Println(string(jsonoutput))
+	} else if halt.Halted {
+		fmt.Printf("Halted: %s\n", halt.Reason)
+	} else {
+		fmt.Println("Resumed")
+	}
+	return nil
+}
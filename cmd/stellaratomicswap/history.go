@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/protocols/horizon/base"
+	"github.com/stellar/go/protocols/horizon/operations"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+)
+
+//historyCmd reconstructs the timeline of a holding account from its operations on horizon
+type historyCmd struct {
+	holdingAccount string
+}
+
+//historyEvent is a step in the life of a holding account
+type historyEvent struct {
+	Time        time.Time `json:"time"`
+	Event       string    `json:"event"`
+	Detail      string    `json:"detail"`
+	Transaction string    `json:"transaction"`
+	//Received is what the merge of the holding account credited to the receiver
+	Received []stellar.CreditedAmount `json:"received,omitempty"`
+}
+
+//swapHistory is the timeline of a holding account
+type swapHistory struct {
+	HoldingAccount string `json:"holdingaccount"`
+	//Outcome is redeemed or refunded once the holding account is merged
+	Outcome string         `json:"outcome,omitempty"`
+	Events  []historyEvent `json:"events"`
+}
+
+func (cmd *historyCmd) runCommand(client horizonclient.ClientInterface) error {
+	history, err := getSwapHistory(cmd.holdingAccount, client)
+	if err != nil {
+		return err
+	}
+	if *automatedFlag {
+		jsonoutput, _ := json.Marshal(history)
+		fmt.Println(string(jsonoutput))
+		return nil
+	}
+	fmt.Printf("Holding account: %s\n", history.HoldingAccount)
+	for _, event := range history.Events {
+		fmt.Printf("%s  %-10s %s (transaction %s)\n", event.Time.UTC().Format(time.RFC3339), event.Event, event.Detail, event.Transaction)
+		for _, received := range event.Received {
+			fmt.Printf("    received %s %s\n", received.Amount, received.Asset)
+		}
+	}
+	if history.Outcome != "" {
+		fmt.Printf("Outcome: %s\n", history.Outcome)
+	}
+	return nil
+}
+
+//getSwapHistory walks the successful operations involving the holding account.
+//Like status, a merge into the account that created the holding account is a refund, a merge into any other account a redeem.
+func getSwapHistory(holdingAccountAddress string, client horizonclient.ClientInterface) (history swapHistory, err error) {
+	history.HoldingAccount = holdingAccountAddress
+	records, err := stellar.GetAccountOperations(holdingAccountAddress, client)
+	if err != nil {
+		return history, fmt.Errorf("Failed to get the operations of the holding account: %v", err)
+	}
+	if len(records) == 0 {
+		return history, fmt.Errorf("Holding account %s has no history", holdingAccountAddress)
+	}
+	funder := ""
+	for _, record := range records {
+		event := historyEvent{}
+		switch operation := record.(type) {
+		case operations.CreateAccount:
+			if !operation.TransactionSuccessful || operation.Account != holdingAccountAddress {
+				continue
+			}
+			funder = operation.Funder
+			event = newHistoryEvent(operation.Base, "created", fmt.Sprintf("created by %s with %s XLM", operation.Funder, operation.StartingBalance))
+		case operations.ChangeTrust:
+			if !operation.TransactionSuccessful {
+				continue
+			}
+			if isZeroAmount(operation.Limit) {
+				event = newHistoryEvent(operation.Base, "trustline", "removed the trustline to "+historyAssetName(operation.Asset))
+			} else {
+				event = newHistoryEvent(operation.Base, "trustline", fmt.Sprintf("added a trustline to %s with limit %s", historyAssetName(operation.Asset), operation.Limit))
+			}
+		case operations.Payment:
+			if !operation.TransactionSuccessful {
+				continue
+			}
+			if operation.To == holdingAccountAddress {
+				event = newHistoryEvent(operation.Base, "funded", fmt.Sprintf("received %s %s from %s", operation.Amount, historyAssetName(operation.Asset), operation.From))
+			} else {
+				event = newHistoryEvent(operation.Base, "paid", fmt.Sprintf("paid %s %s to %s", operation.Amount, historyAssetName(operation.Asset), operation.To))
+			}
+		case operations.ManageData:
+			if !operation.TransactionSuccessful {
+				continue
+			}
+			if operation.Value == "" {
+				event = newHistoryEvent(operation.Base, "data", "removed data entry "+operation.Name)
+			} else {
+				event = newHistoryEvent(operation.Base, "data", "set data entry "+operation.Name)
+			}
+		case operations.SetOptions:
+			if !operation.TransactionSuccessful {
+				continue
+			}
+			event = newHistoryEvent(operation.Base, "signers", describeSetOptions(operation))
+		case operations.AccountMerge:
+			if !operation.TransactionSuccessful || operation.Account != holdingAccountAddress {
+				continue
+			}
+			history.Outcome = contractStatusRedeemed
+			if operation.Into == funder {
+				history.Outcome = contractStatusRefunded
+			}
+			event = newHistoryEvent(operation.Base, history.Outcome, "merged into "+operation.Into)
+			event.Received = getReceivedAmounts(operation.TransactionHash, operation.Into, client)
+		default:
+			continue
+		}
+		history.Events = append(history.Events, event)
+	}
+	return
+}
+
+func newHistoryEvent(operation operations.Base, event string, detail string) historyEvent {
+	return historyEvent{Time: operation.LedgerCloseTime, Event: event, Detail: detail, Transaction: operation.TransactionHash}
+}
+
+//describeSetOptions names the swap signers by their kind: an account, a secret hash or a refund transaction hash
+func describeSetOptions(operation operations.SetOptions) string {
+	var changes []string
+	if operation.SignerKey != "" && operation.SignerWeight != nil {
+		kind := "signer"
+		switch operation.SignerKey[0] {
+		case 'G':
+			kind = "account signer"
+		case 'X':
+			kind = "secret hash signer"
+		case 'T':
+			kind = "refund transaction signer"
+		}
+		changes = append(changes, fmt.Sprintf("%s %s with weight %d", kind, operation.SignerKey, *operation.SignerWeight))
+	}
+	if operation.MasterKeyWeight != nil {
+		changes = append(changes, fmt.Sprintf("master key weight %d", *operation.MasterKeyWeight))
+	}
+	if operation.LowThreshold != nil || operation.MedThreshold != nil || operation.HighThreshold != nil {
+		changes = append(changes, fmt.Sprintf("thresholds %s/%s/%s", historyThreshold(operation.LowThreshold), historyThreshold(operation.MedThreshold), historyThreshold(operation.HighThreshold)))
+	}
+	if operation.HomeDomain != "" {
+		changes = append(changes, "home domain "+operation.HomeDomain)
+	}
+	if len(changes) == 0 {
+		return "set options"
+	}
+	return strings.Join(changes, ", ")
+}
+
+func historyThreshold(threshold *int) string {
+	if threshold == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d", *threshold)
+}
+
+func historyAssetName(asset base.Asset) string {
+	if asset.Type == stellar.NativeAssetType {
+		return "XLM"
+	}
+	return asset.Code + ":" + asset.Issuer
+}
+
+//isZeroAmount returns true for an amount of 0 in any notation horizon uses
+func isZeroAmount(value string) bool {
+	return strings.Trim(value, "0.") == ""
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/keypair"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+)
+
+//newHoldingKeyPair creates the keypair of a new holding account.
+//With -deterministic it is derived from the funding seed, the secret hash and the counterparty instead of random.
+func newHoldingKeyPair(fundingSigner stellar.Signer, secretHash []byte, counterPartyAddress string) (*keypair.Full, error) {
+	if !*deterministicFlag {
+		holdingAccountKeyPair, err := stellar.GenerateKeyPair()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create holding account keypair: %s", err)
+		}
+		return holdingAccountKeyPair, nil
+	}
+	fundingKeyPair, ok := fundingSigner.(*keypair.Full)
+	if !ok {
+		return nil, errors.New("A deterministic holding account needs the seed of the funding account, it can not be used with an external signer")
+	}
+	holdingAccountKeyPair, err := stellar.DeriveHoldingKeyPair(fundingKeyPair, secretHash, counterPartyAddress)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive the holding account keypair: %v", err)
+	}
+	return holdingAccountKeyPair, nil
+}
+
+//deriveHoldingKeyCmd derives the holding account keypair of a swap set up with -deterministic again
+type deriveHoldingKeyCmd struct {
+	fundingKeyPair      *keypair.Full
+	counterPartyAddress string
+	secretHash          []byte
+}
+
+func (cmd *deriveHoldingKeyCmd) runCommand(client horizonclient.ClientInterface) error {
+	return cmd.runOfflineCommand()
+}
+
+func (cmd *deriveHoldingKeyCmd) runOfflineCommand() error {
+	holdingAccountKeyPair, err := stellar.DeriveHoldingKeyPair(cmd.fundingKeyPair, cmd.secretHash, cmd.counterPartyAddress)
+	if err != nil {
+		return fmt.Errorf("Failed to derive the holding account keypair: %v", err)
+	}
+	if *automatedFlag {
+		output := struct {
+			HoldingAccount string `json:"holdingaccount"`
+			Seed           string `json:"seed"`
+		}{holdingAccountKeyPair.Address(), holdingAccountKeyPair.Seed()}
+		jsonoutput, _ := json.Marshal(output)
+		fmt.Println(string(jsonoutput))
+		return nil
+	}
+	fmt.Printf("holding account address: %s\n", holdingAccountKeyPair.Address())
+	fmt.Printf("holding account seed:    %s\n", holdingAccountKeyPair.Seed())
+	return nil
+}
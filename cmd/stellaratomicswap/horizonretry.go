@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	//horizonRetryDelay is the delay before the first retry of a horizon request, it doubles on every retry
+	horizonRetryDelay = time.Second
+	//maxHorizonRetryDelay bounds the delay between retries, also when horizon asks for a longer one with Retry-After
+	maxHorizonRetryDelay = time.Minute
+)
+
+//horizonRetryTransport retries horizon requests that failed because of rate limiting or an unavailable horizon.
+//A submitted transaction is only retried when horizon did not process it: when it was rate limited or unavailable (429 and 503).
+//Submitting it again after a timeout could apply it already, the setup handles that by resuming its remaining steps.
+type horizonRetryTransport struct {
+	next    http.RoundTripper
+	retries int
+}
+
+func (t horizonRetryTransport) RoundTrip(request *http.Request) (response *http.Response, err error) {
+	delay := horizonRetryDelay
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if request, err = rewindRequest(request); err != nil {
+				return
+			}
+		}
+		response, err = t.next.RoundTrip(request)
+		if attempt >= t.retries || !isRetriableHorizonResponse(request, response, err) {
+			return
+		}
+		wait := delay
+		if response != nil {
+			if retryAfter, ok := parseRetryAfter(response.Header.Get("Retry-After")); ok {
+				wait = retryAfter
+			}
+			response.Body.Close()
+		}
+		if wait > maxHorizonRetryDelay {
+			wait = maxHorizonRetryDelay
+		}
+		logger.WithField("url", request.URL.String()).Debugf("Horizon request failed, retrying in %v (%d/%d)", wait, attempt+1, t.retries)
+		select {
+		case <-time.After(wait):
+		case <-request.Context().Done():
+			return nil, request.Context().Err()
+		}
+		delay *= 2
+	}
+}
+
+//isRetriableHorizonResponse returns true if sending the request again can succeed without risking a double submission
+func isRetriableHorizonResponse(request *http.Request, response *http.Response, err error) bool {
+	submission := request.Method != http.MethodGet
+	if err != nil {
+		//the connection may have broken after the transaction reached horizon
+		return !submission && request.Context().Err() == nil
+	}
+	switch response.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return !submission
+	}
+	return false
+}
+
+//rewindRequest returns a copy of the request with a fresh body to send it again
+func rewindRequest(request *http.Request) (*http.Request, error) {
+	if request.Body == nil || request.GetBody == nil {
+		return request, nil
+	}
+	body, err := request.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	rewound := request.Clone(request.Context())
+	rewound.Body = body
+	return rewound, nil
+}
+
+//parseRetryAfter parses the Retry-After header, both as a number of seconds and as an http date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
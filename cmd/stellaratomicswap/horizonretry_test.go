@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRetriableHorizonResponse(t *testing.T) {
+	transportError := errors.New("connection reset by peer")
+	tests := []struct {
+		method    string
+		status    int
+		err       error
+		retriable bool
+	}{
+		{http.MethodGet, http.StatusOK, nil, false},
+		{http.MethodGet, http.StatusBadRequest, nil, false},
+		{http.MethodGet, http.StatusNotFound, nil, false},
+		{http.MethodGet, http.StatusTooManyRequests, nil, true},
+		{http.MethodGet, http.StatusInternalServerError, nil, true},
+		{http.MethodGet, http.StatusBadGateway, nil, true},
+		{http.MethodGet, http.StatusServiceUnavailable, nil, true},
+		{http.MethodGet, http.StatusGatewayTimeout, nil, true},
+		{http.MethodGet, 0, transportError, true},
+		//a submitted transaction is only sent again when horizon did not process it
+		{http.MethodPost, http.StatusOK, nil, false},
+		{http.MethodPost, http.StatusBadRequest, nil, false},
+		{http.MethodPost, http.StatusTooManyRequests, nil, true},
+		{http.MethodPost, http.StatusInternalServerError, nil, false},
+		{http.MethodPost, http.StatusBadGateway, nil, false},
+		{http.MethodPost, http.StatusServiceUnavailable, nil, true},
+		{http.MethodPost, http.StatusGatewayTimeout, nil, false},
+		{http.MethodPost, 0, transportError, false},
+	}
+	for _, test := range tests {
+		request, _ := http.NewRequest(test.method, "https://horizon.example.com/transactions", nil)
+		var response *http.Response
+		if test.err == nil {
+			response = &http.Response{StatusCode: test.status}
+		}
+		assert.Equal(t, test.retriable, isRetriableHorizonResponse(request, response, test.err), "%s %d %v", test.method, test.status, test.err)
+	}
+
+	//a canceled request is not retried
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	request, _ := http.NewRequest(http.MethodGet, "https://horizon.example.com/accounts", nil)
+	assert.False(t, isRetriableHorizonResponse(request.WithContext(ctx), nil, context.Canceled))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		wait  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"0", 0, true},
+		{"5", 5 * time.Second, true},
+		{" 120 ", 2 * time.Minute, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		//a date in the past means retrying right away
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+	}
+	for _, test := range tests {
+		wait, ok := parseRetryAfter(test.value)
+		assert.Equal(t, test.ok, ok, test.value)
+		assert.Equal(t, test.wait, wait, test.value)
+	}
+
+	wait, ok := parseRetryAfter(time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat))
+	assert.True(t, ok)
+	assert.True(t, wait > 28*time.Second && wait <= 30*time.Second, wait)
+}
+
+//countingTransport answers every request with the same response or error and counts the requests
+type countingTransport struct {
+	status   int
+	err      error
+	requests int
+}
+
+func (t *countingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	t.requests++
+	if t.err != nil {
+		return nil, t.err
+	}
+	return &http.Response{StatusCode: t.status, Header: http.Header{"Retry-After": []string{"0"}}, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+}
+
+func TestHorizonRetryTransport(t *testing.T) {
+	tests := []struct {
+		method   string
+		status   int
+		err      error
+		requests int
+	}{
+		{http.MethodGet, http.StatusOK, nil, 1},
+		{http.MethodGet, http.StatusTooManyRequests, nil, 3},
+		{http.MethodPost, http.StatusServiceUnavailable, nil, 3},
+		{http.MethodPost, http.StatusGatewayTimeout, nil, 1},
+		{http.MethodPost, http.StatusInternalServerError, nil, 1},
+		{http.MethodPost, 0, errors.New("EOF"), 1},
+	}
+	for _, test := range tests {
+		next := &countingTransport{status: test.status, err: test.err}
+		request, _ := http.NewRequest(test.method, "https://horizon.example.com/transactions", strings.NewReader("tx=AAAA"))
+		response, err := horizonRetryTransport{next: next, retries: 2}.RoundTrip(request)
+		if err == nil {
+			response.Body.Close()
+		}
+		assert.Equal(t, test.requests, next.requests, "%s %d %v", test.method, test.status, test.err)
+	}
+}
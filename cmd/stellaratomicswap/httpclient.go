@@ -0,0 +1,148 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/stellar/go/clients/horizonclient"
+)
+
+//newHorizonHTTPTransport creates the transport for horizon requests from the -httptimeout, -nokeepalive, -cacert and -proxy flags.
+//The timeout bounds connecting and waiting for the answer, not reading it, so streams stay open.
+//Without -proxy, the proxy in the HTTPS_PROXY and HTTP_PROXY environment variables is used unless NO_PROXY excludes horizon.
+func newHorizonHTTPTransport() (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if *proxyFlag != "" {
+		proxyURL, err := parseProxyURL(*proxyFlag)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	transport.DisableKeepAlives = *noKeepAliveFlag
+	transport.MaxIdleConnsPerHost = 16
+	if *httpTimeoutFlag > 0 {
+		transport.DialContext = (&net.Dialer{Timeout: *httpTimeoutFlag, KeepAlive: 30 * time.Second}).DialContext
+		transport.TLSHandshakeTimeout = *httpTimeoutFlag
+		transport.ResponseHeaderTimeout = *httpTimeoutFlag
+	}
+	if *caCertFlag != "" {
+		certificates, err := ioutil.ReadFile(*caCertFlag)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read the CA certificates: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(certificates) {
+			return nil, fmt.Errorf("No PEM encoded certificates in %s", *caCertFlag)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	return transport, nil
+}
+
+//parseProxyURL parses the url of an http or a socks5 proxy.
+//With a socks5 proxy the proxy resolves the host names, so no DNS request leaks past a Tor proxy.
+func parseProxyURL(value string) (*url.URL, error) {
+	proxyURL, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid proxy url %s: %v", value, err)
+	}
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h", "http", "https":
+	default:
+		return nil, fmt.Errorf("Unsupported proxy %s, use a socks5://, http:// or https:// url", value)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("Invalid proxy url %s: no host", value)
+	}
+	return proxyURL, nil
+}
+
+//newHorizonClient creates a client for the horizon server at url
+func newHorizonClient(url string) (*horizonclient.Client, error) {
+	transport, err := newHorizonHTTPTransport()
+	if err != nil {
+		return nil, err
+	}
+	return newHorizonClientWithHTTP(url, &http.Client{Transport: transport}), nil
+}
+
+//newHorizonClientWithHTTP creates a client for the horizon server at url on top of an http client,
+//its transport is wrapped to add the -horizonheader headers and to retry and log the requests like the other horizon clients
+func newHorizonClientWithHTTP(url string, httpClient *http.Client) *horizonclient.Client {
+	wrapped := *httpClient
+	transport := httpClient.Transport
+	if len(horizonHeadersFlag.header) > 0 {
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		transport = horizonHeaderTransport{next: transport, header: horizonHeadersFlag.header}
+	}
+	wrapped.Transport = horizonTransport(transport)
+	client := &horizonclient.Client{HorizonURL: url, HTTP: &wrapped}
+	if *httpTimeoutFlag > 0 {
+		//the horizon client bounds every request, except streams, in whole seconds
+		client.SetHorizonTimeOut(uint((*httpTimeoutFlag + time.Second - 1) / time.Second))
+	}
+	return client
+}
+
+//headerFlag collects the headers of a flag that can be passed multiple times
+type headerFlag struct {
+	header http.Header
+}
+
+//newHeaderFlag defines a flag that adds a "Name: value" header every time it is passed
+func newHeaderFlag(name string, usage string) *headerFlag {
+	f := &headerFlag{header: http.Header{}}
+	flagset.Var(f, name, usage)
+	return f
+}
+
+func (f *headerFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	var names []string
+	for name := range f.header {
+		names = append(names, name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func (f *headerFlag) Set(value string) error {
+	parts := strings.SplitN(value, ":", 2)
+	name := strings.TrimSpace(parts[0])
+	if len(parts) != 2 || name == "" || strings.ContainsAny(name, " \t") {
+		return fmt.Errorf("invalid header %q, expected \"Name: value\"", value)
+	}
+	headerValue := strings.TrimSpace(parts[1])
+	//header values are usually api keys
+	registerSecret(headerValue)
+	f.header.Add(name, headerValue)
+	return nil
+}
+
+//horizonHeaderTransport adds headers to every horizon request, like the api key of a horizon provider
+type horizonHeaderTransport struct {
+	next   http.RoundTripper
+	header http.Header
+}
+
+func (t horizonHeaderTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	request = request.Clone(request.Context())
+	for name, values := range t.header {
+		request.Header.Del(name)
+		for _, value := range values {
+			request.Header.Add(name, value)
+		}
+	}
+	return t.next.RoundTrip(request)
+}
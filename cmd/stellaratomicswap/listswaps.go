@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/swapdb"
+)
+
+//The states listswaps shows, derived from the recorded status and the holding account on the chain
+const (
+	swapStateActive     = "active"
+	swapStateRedeemable = "redeemable"
+	swapStateRefundable = "refundable"
+	swapStateCompleted  = "completed"
+	swapStateFailed     = "failed"
+)
+
+type listSwapsCmd struct {
+	status string
+}
+
+//listedSwap is a recorded swap with its current state
+type listedSwap struct {
+	swapdb.Swap
+	State string `json:"state"`
+	//LocktimeRemaining is the number of seconds until the swap can be refunded, negative when it already can
+	LocktimeRemaining int64 `json:"locktimeremaining"`
+}
+
+//swapState derives the state of a recorded swap.
+//A holding account that is merged without a refund by this tool was redeemed by the counterparty,
+//which reveals the secret to a participant so the initiator's contract can be redeemed.
+func swapState(swap swapdb.Swap, client horizonclient.ClientInterface) (string, error) {
+	switch swap.Status {
+	case swapdb.StatusRedeemed, swapdb.StatusRefunded:
+		return swapStateCompleted, nil
+	case swapdb.StatusFailed, swapdb.StatusSettingUp:
+		return swapStateFailed, nil
+	}
+	_, err := client.AccountDetail(horizonclient.AccountRequest{AccountID: swap.HoldingAccount})
+	if stellar.IsNotFoundError(err) {
+		if swap.Role == "participant" {
+			return swapStateRedeemable, nil
+		}
+		return swapStateCompleted, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if time.Now().After(swap.Locktime) {
+		return swapStateRefundable, nil
+	}
+	return swapStateActive, nil
+}
+
+func (cmd *listSwapsCmd) runCommand(client horizonclient.ClientInterface) error {
+	if *swapDBFlag == "" {
+		return errors.New("The swap database is disabled")
+	}
+	var swaps []swapdb.Swap
+	err := withSwapDB(func(db *swapdb.DB) (err error) {
+		swaps, err = db.List()
+		return
+	})
+	if err != nil {
+		return err
+	}
+
+	network := networkName(targetNetwork)
+	listed := make([]listedSwap, 0, len(swaps))
+	for _, swap := range swaps {
+		if swap.Network != network {
+			continue
+		}
+		state, err := swapState(swap, client)
+		if err != nil {
+			return fmt.Errorf("Failed to get the state of holding account %s: %v", swap.HoldingAccount, err)
+		}
+		if cmd.status != "" && cmd.status != state {
+			continue
+		}
+		listed = append(listed, listedSwap{Swap: swap, State: state, LocktimeRemaining: int64(time.Until(swap.Locktime) / time.Second)})
+	}
+
+	if *automatedFlag {
+		jsonoutput, _ := json.Marshal(listed)
+		fmt.Println(string(jsonoutput))
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HOLDING ACCOUNT\tROLE\tSTATE\tAMOUNT\tCOUNTERPARTY\tLOCKTIME")
+	for _, swap := range listed {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s %s\t%s\t%s\n", swap.HoldingAccount, swap.Role, swap.State, swap.Amount, swap.Asset, swap.Counterparty, locktimeCountdown(swap))
+	}
+	return w.Flush()
+}
+
+//locktimeCountdown describes when a swap can be refunded
+func locktimeCountdown(swap listedSwap) string {
+	if swap.State == swapStateCompleted || swap.State == swapStateFailed {
+		return swap.Locktime.Format(time.RFC3339)
+	}
+	remaining := time.Duration(swap.LocktimeRemaining) * time.Second
+	if remaining > 0 {
+		return "refundable in " + remaining.String()
+	}
+	return "refundable since " + (-remaining).String()
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+//logger writes the diagnostics of the tool to stderr, the results of the commands go to stdout
+var logger = logrus.New()
+
+//seedPattern matches stellar seeds, they are redacted even if they were not registered
+var seedPattern = regexp.MustCompile(`\bS[A-Z2-7]{55}\b`)
+
+//secretsLock guards secrets, the values that are redacted from the logs and errors
+var (
+	secretsLock sync.RWMutex
+	secrets     = make(map[string]bool)
+)
+
+//registerSecret marks values as sensitive so they are redacted from the logs and errors
+func registerSecret(values ...string) {
+	secretsLock.Lock()
+	defer secretsLock.Unlock()
+	for _, value := range values {
+		if value != "" {
+			secrets[value] = true
+		}
+	}
+}
+
+//forgetSecret stops redacting values, for the secrets of swaps that completed.
+//A long running process would otherwise keep every secret it ever saw in memory.
+func forgetSecret(values ...string) {
+	secretsLock.Lock()
+	defer secretsLock.Unlock()
+	for _, value := range values {
+		delete(secrets, value)
+	}
+}
+
+//redact replaces the seeds and registered secrets in s unless -revealsecrets is set
+func redact(s string) string {
+	if *revealSecretsFlag {
+		return s
+	}
+	s = seedPattern.ReplaceAllString(s, "[REDACTED]")
+	secretsLock.RLock()
+	defer secretsLock.RUnlock()
+	for secret := range secrets {
+		s = strings.Replace(s, secret, "[REDACTED]", -1)
+	}
+	return s
+}
+
+//redactingFormatter redacts the message and fields of a log entry before formatting it
+type redactingFormatter struct {
+	logrus.Formatter
+}
+
+func (f redactingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	entry.Message = redact(entry.Message)
+	//the fields can be shared with other entries, redact a copy
+	data := make(logrus.Fields, len(entry.Data))
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case string:
+			value = redact(v)
+		case error:
+			value = redact(v.Error())
+		case fmt.Stringer:
+			value = redact(v.String())
+		}
+		data[key] = value
+	}
+	entry.Data = data
+	return f.Formatter.Format(entry)
+}
+
+//setupLogging configures the logger from the flags.
+//Only warnings are logged in automated mode unless -verbose is set, nothing is logged with -quiet.
+func setupLogging() error {
+	logger.Out = os.Stderr
+	var formatter logrus.Formatter
+	switch *logFormatFlag {
+	case "console":
+		formatter = &logrus.TextFormatter{FullTimestamp: true}
+	case "json":
+		formatter = &logrus.JSONFormatter{}
+	default:
+		return errors.New("The log format must be console or json")
+	}
+	logger.Formatter = redactingFormatter{formatter}
+	switch {
+	case *quietFlag && *verboseFlag:
+		return errors.New("-quiet and -verbose can not be combined")
+	case *quietFlag:
+		logger.Out = ioutil.Discard
+		logger.Level = logrus.PanicLevel
+	case *verboseFlag:
+		logger.Level = logrus.DebugLevel
+	case *automatedFlag:
+		logger.Level = logrus.WarnLevel
+	default:
+		logger.Level = logrus.InfoLevel
+	}
+	return nil
+}
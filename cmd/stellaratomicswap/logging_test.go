@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stellar/go/keypair"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	seed, _ := keypair.Random()
+	const secret = "c0ffee00c0ffee00c0ffee00c0ffee00c0ffee00c0ffee00c0ffee00c0ffee00"
+	registerSecret(secret)
+	defer forgetSecret(secret)
+
+	//seeds are redacted without being registered, addresses are kept
+	assert.Equal(t, "seed [REDACTED] of "+seed.Address(), redact("seed "+seed.Seed()+" of "+seed.Address()))
+	assert.Equal(t, "secret [REDACTED]", redact("secret "+secret))
+	assert.Equal(t, "no secrets", redact("no secrets"))
+
+	defer func(reveal bool) { *revealSecretsFlag = reveal }(*revealSecretsFlag)
+	*revealSecretsFlag = true
+	assert.Equal(t, "secret "+secret+" "+seed.Seed(), redact("secret "+secret+" "+seed.Seed()))
+	*revealSecretsFlag = false
+
+	forgetSecret(secret)
+	assert.Equal(t, "secret "+secret, redact("secret "+secret))
+}
+
+func TestRedactingFormatter(t *testing.T) {
+	seed, _ := keypair.Random()
+	const secret = "5ec7e75ec7e75ec7e75ec7e75ec7e75ec7e75ec7e75ec7e75ec7e75ec7e75ec7"
+	registerSecret(secret)
+	defer forgetSecret(secret)
+
+	var out bytes.Buffer
+	log := logrus.New()
+	log.Out = &out
+	log.Formatter = redactingFormatter{&logrus.JSONFormatter{}}
+	fields := logrus.Fields{"seed": seed.Seed(), "address": seed.Address()}
+	log.WithFields(fields).WithError(errors.New("Failed to redeem with secret "+secret)).Warnf("Redeeming with %s", secret)
+
+	var entry map[string]string
+	if assert.NoError(t, json.Unmarshal(out.Bytes(), &entry)) {
+		assert.Equal(t, "Redeeming with [REDACTED]", entry["msg"])
+		assert.Equal(t, "Failed to redeem with secret [REDACTED]", entry[logrus.ErrorKey])
+		assert.Equal(t, "[REDACTED]", entry["seed"])
+		assert.Equal(t, seed.Address(), entry["address"])
+	}
+	assert.False(t, strings.Contains(out.String(), secret))
+	//the fields of the caller are not changed
+	assert.Equal(t, seed.Seed(), fields["seed"])
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"bytes"
-	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
@@ -10,8 +9,10 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
-	"reflect"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -20,11 +21,15 @@ import (
 
 	"github.com/stellar/go/strkey"
 
+	"github.com/stellar/go/amount"
 	"github.com/stellar/go/clients/horizon"
 	"github.com/stellar/go/clients/horizonclient"
 	hprotocol "github.com/stellar/go/protocols/horizon"
 	"github.com/stellar/go/txnbuild"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/signer"
 	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/swapdb"
+	"github.com/threefoldtech/atomicswap/swapcrypto"
 	"github.com/threefoldtech/atomicswap/timings"
 
 	"github.com/stellar/go/keypair"
@@ -33,18 +38,71 @@ import (
 
 const verify = true
 
-const secretSize = 32
+//version of the tool, used to tag holding accounts
+const version = "0.1.0"
 
 var (
 	targetNetwork = network.PublicNetworkPassphrase
 )
 var (
-	flagset       = flag.NewFlagSet("", flag.ExitOnError)
-	testnetFlag   = flagset.Bool("testnet", false, "use testnet network")
-	automatedFlag = flagset.Bool("automated", false, "Use automated/unattended version with json output")
-	assetParam    = flagset.String("asset", "", "The asset to transfer in case of non native XLM, format: `code:issuer`")
+	flagset               = flag.NewFlagSet("", flag.ExitOnError)
+	testnetFlag           = flagset.Bool("testnet", false, "use testnet network")
+	automatedFlag         = flagset.Bool("automated", false, "Use automated/unattended version with json output")
+	assetParam            = flagset.String("asset", "", "The asset to transfer in case of non native XLM, format: `code:issuer`")
+	tagFlag               = flagset.Bool("tag", false, "Tag the holding account with data entries identifying it as an atomic swap escrow")
+	homeDomainFlag        = flagset.String("homedomain", "", "Home `domain` to set on the holding account")
+	signerFlag            = flagset.String("signer", "", "Sign with an external `backend:key` instead of the seed argument, for example kms:<key-id> or vault:<key-name>")
+	collectFlag           = flagset.String("collect", "", "Collect the redeem signatures in a `file` and only submit once enough signers signed")
+	sep7Flag              = flagset.Bool("sep7", false, "Print a SEP-0007 web+stellar URI for a wallet to sign and submit the redeem or refund transaction instead of submitting it")
+	refundFileFlag        = flagset.String("refundfile", "", "Also write the refund transaction to this `file`, as txrep and with checksums")
+	outDirFlag            = flagset.String("outdir", "", "Write the secret, secret hash and refund transaction of initiate and participate to files in this `directory` instead of printing them")
+	timeoutFlag           = flagset.Duration("timeout", 0, "Abort the command after this `duration` and report the steps that were completed, 0 means no timeout")
+	keyPathFlag           = flagset.String("keypath", stellar.DefaultDerivationPath, "SEP-0005 derivation `path` used when a seed is given as a mnemonic")
+	reportFlag            = flagset.String("report", "", "Write an audit report of the contract to this `file`, to share with third parties")
+	mediatorFlag          = flagset.String("mediator", "", "Encrypt the secret to this mediator `address` at initiate, so the mediator can release it to the participant")
+	policyFlag            = flagset.String("policy", "", "Load the minimum amounts and precision allowed per asset from this json `file`")
+	waitFlag              = flagset.Duration("wait", 0, "Keep retrying for this `duration` when horizon does not know the holding account yet, it can take a while before a new account is ingested")
+	waitLocktimeFlag      = flagset.Duration("waitlocktime", 0, "Make refund wait for the locktime to pass when it passes within this `duration`, instead of failing right away")
+	verboseFlag           = flagset.Bool("verbose", false, "Also log debug information on stderr, like every horizon request and submitted transaction")
+	quietFlag             = flagset.Bool("quiet", false, "Only print the result of the command, no logs")
+	statusFlag            = flagset.String("status", "", "Only list the swaps in this `state`: active, redeemable, refundable, completed or failed")
+	swapDBFlag            = flagset.String("db", defaultSwapDBPath(), "Record the swaps in the database in this `directory`, empty to disable")
+	webhookFlag           = flagset.String("webhook", "", "Post the swap events of swapd and the watchtower to this `url`, signed with the key in WEBHOOK_SECRET, @file reads it from a file swapd reads again on SIGHUP")
+	notifyFlag            = flagset.String("notify", "", "Also notify the swap events of swapd and the watchtower through these comma separated `backends`: telegram, email, @file reads them from a file swapd reads again on SIGHUP")
+	alertBeforeFlag       = flagset.Duration("alertbefore", time.Hour, "The watchtower alerts this `duration` before the locktime of a swap passes")
+	logFormatFlag         = flagset.String("logformat", "console", "Write the logs on stderr as console text or as json")
+	revealSecretsFlag     = flagset.Bool("revealsecrets", false, "Do not redact seeds and secrets from the logs and errors")
+	horizonFlag           = flagset.String("horizon", "", "Use the horizon server at this `url` instead of the public SDF one, for example a full history archive to audit old swaps")
+	fundTestnetFlag       = flagset.Bool("fundtestnet", false, "Create and fund the account of genkeypair on testnet with friendbot")
+	maxBaseFeeFlag        = flagset.Uint("maxbasefee", 10000, "Never bid more than this base fee in `stroops` per operation, the pre-signed refund transaction always bids it")
+	baseFeeFlag           = flagset.Uint("basefee", 0, "Bid this base fee in `stroops` per operation instead of deriving it from the fee statistics, also for the pre-signed refund transaction")
+	feeAccountFlag        = flagset.String("feeaccount", "", "Pay the fees of the holding account creation and funding transactions with this `seed`, a channel account, instead of the funding account")
+	feeTopUpFlag          = flagset.String("feetopup", "", "Let swapd top up the XLM balance of the -feeaccount from its funding account when it falls below this `amount`")
+	feeTopUpAmountFlag    = flagset.String("feetopupamount", "10", "The XLM `amount` swapd sends to the fee account per top-up")
+	feeTopUpLimitFlag     = flagset.String("feetopuplimit", "100", "The maximum XLM `amount` swapd tops up the fee account with per 24 hours, it alerts when more is needed")
+	txValidityFlag        = flagset.Duration("txvalidity", 5*time.Minute, "The transactions setting up a holding account are only valid for this `duration` after they are built")
+	expectedAmountFlag    = flagset.String("expectedamount", "", "Make auditcontract fail unless the holding account holds at least this `amount` of the asset given with -asset")
+	refundAddressFlag     = flagset.String("refundaddress", "", "Make auditcontract fail unless the refund transaction returns the funds to this `address`")
+	accountFileFlag       = flagset.String("accountfile", "", "Audit the contract offline against the holding account as horizon returned it, read from this json `file`")
+	createDestinationFlag = flagset.Bool("createdestination", false, "Let redeem create the receiver account from the holding account's XLM when it does not exist")
+	secretSizeFlag        = flagset.Int("secretsize", swapcrypto.SecretSize, "Size in bytes of the secret initiate creates, for counterpart chains that use other secret sizes")
+	secretFileFlag        = flagset.String("secretfile", "", "Read the hex encoded secret for initiate to use instead of creating one from this `file`, - for stdin; a secret already used by a recorded swap is refused")
+	entropyFlag           = flagset.String("entropy", "", "Hex encoded extra `entropy` mixed with the random bytes of the secrets initiate creates")
+	followFlag            = flagset.Bool("follow", false, "Let extractsecret stream the transactions of the holding account and print the secret as soon as it is redeemed")
+	stroopsFlag           = flagset.Bool("stroops", false, "Amount arguments are integer numbers of stroops, the smallest unit of 0.0000001, instead of decimal amounts")
+	deterministicFlag     = flagset.Bool("deterministic", false, "Derive the holding account key from the funding seed, the secret hash and the counterparty instead of generating a random one")
+	horizonRetriesFlag    = flagset.Int("horizonretries", 3, "Retry horizon requests that are rate limited or fail because horizon is unavailable this many `times`, with a doubling delay or the one horizon asks for")
+	httpTimeoutFlag       = flagset.Duration("httptimeout", time.Minute, "Give up on a horizon request that did not connect or answer within this `duration`, streams stay open once they answer")
+	noKeepAliveFlag       = flagset.Bool("nokeepalive", false, "Open a new connection for every horizon request instead of keeping them alive")
+	caCertFlag            = flagset.String("cacert", "", "Only trust the PEM encoded certificates in this `file` for the TLS connections to horizon, for a private horizon")
+	proxyFlag             = flagset.String("proxy", "", "Send the horizon requests through the proxy at this `url`, like socks5://127.0.0.1:9050 for Tor")
+	tlsCertFlag           = flagset.String("tlscert", "", "Serve the swapd API over TLS with the PEM encoded certificate in this `file`")
+	tlsKeyFlag            = flagset.String("tlskey", "", "Serve the swapd API over TLS with the PEM encoded private key in this `file`")
 )
 
+//horizonHeadersFlag holds the headers added to every horizon request
+var horizonHeadersFlag = newHeaderFlag("horizonheader", "Add this \"Name: value\" `header` to every horizon request, like the api key of a horizon provider, can be passed multiple times")
+
 // There are two directions that the atomic swap can be performed, as the
 // initiator can be on either chain.  This tool only deals with creating the
 // Stellar transactions for these swaps.  A second tool should be used for the
@@ -72,12 +130,45 @@ func init() {
 		fmt.Println("Usage: stellaratomicswap [flags] cmd [cmd args]")
 		fmt.Println()
 		fmt.Println("Commands:")
-		fmt.Println("  initiate [-asset code:issuer] <initiator seed> <participant address> <amount>")
+		fmt.Println("  initiate [-asset code:issuer] [-mediator address] <initiator seed> <participant address> <amount>")
 		fmt.Println("  participate [-asset code:issuer]  <participant seed> <initiator address> <amount> <secret hash>")
-		fmt.Println("  redeem <receiver seed> <holdingAccountAdress> <secret>")
+		fmt.Println("  redeem [-collect file] <receiver seed> <holdingAccountAdress> <secret>")
 		fmt.Println("  refund <refund transaction>")
 		fmt.Println("  extractsecret <holdingAccountAdress> <secret hash>")
-		fmt.Println("  auditcontract <holdingAccountAdress> < refund transaction>")
+		fmt.Println("  auditcontract [-report file] <holdingAccountAdress> < refund transaction>")
+		fmt.Println("  savekey <alias> <seed | @seedfile | ->")
+		fmt.Println("  attest <seed> <terms file>")
+		fmt.Println("  verifyattestation <attestation file>")
+		fmt.Println("  bootstrap [-asset code:issuer] <seed> <report file>")
+		fmt.Println("  releasesecret <mediator seed> <escrow> <participant address>")
+		fmt.Println("  opensecret <seed> <escrow>")
+		fmt.Println("  autoswap <config file>")
+		fmt.Println("  swapd [-asset code:issuer] <seed> <listen address>")
+		fmt.Println("  listswaps [-status state]")
+		fmt.Println("  resume <funder seed> <holdingAccountAdress>")
+		fmt.Println("  halt <reason>")
+		fmt.Println("  unhalt")
+		fmt.Println("  exportswap <holdingAccountAdress>")
+		fmt.Println("  importswap <swap>")
+		fmt.Println("  status <holdingAccountAdress>")
+		fmt.Println("  waitredeem <holdingAccountAdress> <secret hash>")
+		fmt.Println("  capabilities")
+		fmt.Println("  handshake <capabilities file>")
+		fmt.Println("  watchtower")
+		fmt.Println("  decodetx <transaction>")
+		fmt.Println("  genkeypair")
+		fmt.Println("  fundtestnet <address>")
+		fmt.Println("  balance <address>")
+		fmt.Println("  doctor <address or seed>")
+		fmt.Println("  sweep <holding account seed or address>")
+		fmt.Println("  deriveholdingkey <funder seed> <counterparty address> <secret hash>")
+		fmt.Println("  history <holdingAccountAdress>")
+		fmt.Println("  state verify")
+		fmt.Println()
+		fmt.Println("Seeds can also be given as a quoted BIP39 mnemonic, the key is then derived using -keypath.")
+		fmt.Println("When -signer is set, the seed argument is omitted and the external signer is used instead.")
+		fmt.Println("Seeds saved with savekey can be referenced as keyring:<alias>.")
+		fmt.Println("A transaction argument can also be read from a file with @<file> or from stdin with -.")
 		fmt.Println()
 		fmt.Println("Flags:")
 		flagset.PrintDefaults()
@@ -95,44 +186,70 @@ type offlineCommand interface {
 }
 
 type initiateCmd struct {
-	InitiatorKeyPair *keypair.Full
-	cp2Addr          string
-	amount           string
-	asset            txnbuild.Asset
+	InitiatorKeyPair stellar.Signer
+	//secret is given with -secret, nil to create one
+	secret  []byte
+	cp2Addr string
+	amount  string
+	asset   txnbuild.Asset
+	setup   setupProgress
 }
 
 type participateCmd struct {
 	cp1Addr             string
-	participatorKeyPair *keypair.Full
+	participatorKeyPair stellar.Signer
 	amount              string
 	secretHash          []byte
 	asset               txnbuild.Asset
+	setup               setupProgress
 }
 
 type redeemCmd struct {
-	ReceiverKeyPair       *keypair.Full
+	ReceiverKeyPair       stellar.Signer
+	receiverAddress       string
 	holdingAccountAddress string
 	secret                []byte
+	collectFile           string
+	//sep7 prints the partially signed redeem transaction as a SEP-0007 URI instead of submitting it
+	sep7 bool
 }
 
 type refundCmd struct {
 	refundTx txnbuild.Transaction
+	sep7     bool
 }
 
 type extractSecretCmd struct {
 	holdingAccountAdress string
 	secretHash           string
+	//follow streams the transactions of the holding account until the secret is revealed
+	follow bool
+}
+
+type saveKeyCmd struct {
+	alias string
+	seed  string
 }
 
 type auditContractCmd struct {
 	refundTx             txnbuild.Transaction
 	holdingAccountAdress string
+	//expectedAmount of asset is the negotiated amount the holding account has to hold, empty to not check it
+	expectedAmount string
+	asset          txnbuild.Asset
+	//refundAddress is the address the counterparty stated the refund goes to, empty to not check it
+	refundAddress string
 }
 
 func main() {
 	showUsage, err := run()
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
+		if *automatedFlag {
+			printJSONError(err)
+		} else {
+			fmt.Fprintln(os.Stderr, redact(err.Error()))
+			printSetupError(err)
+		}
 	}
 	if showUsage {
 		flagset.Usage()
@@ -173,6 +290,12 @@ func run() (showUsage bool, err error) {
 	if len(args) == 0 {
 		return true, nil
 	}
+	if *signerFlag != "" {
+		switch args[0] {
+		case "initiate", "participate", "redeem", "attest", "bootstrap", "swapd", "resume", "doctor":
+			args = append([]string{args[0], *signerFlag}, args[1:]...)
+		}
+	}
 	cmdArgs := 0
 	switch args[0] {
 	case "initiate":
@@ -187,6 +310,62 @@ func run() (showUsage bool, err error) {
 		cmdArgs = 2
 	case "auditcontract":
 		cmdArgs = 2
+	case "savekey":
+		cmdArgs = 2
+	case "attest":
+		cmdArgs = 2
+	case "verifyattestation":
+		cmdArgs = 1
+	case "bootstrap":
+		cmdArgs = 2
+	case "releasesecret":
+		cmdArgs = 3
+	case "opensecret":
+		cmdArgs = 2
+	case "autoswap":
+		cmdArgs = 1
+	case "swapd":
+		cmdArgs = 2
+	case "listswaps":
+		cmdArgs = 0
+	case "resume":
+		cmdArgs = 2
+	case "halt":
+		cmdArgs = 1
+	case "unhalt":
+		cmdArgs = 0
+	case "exportswap":
+		cmdArgs = 1
+	case "importswap":
+		cmdArgs = 1
+	case "status":
+		cmdArgs = 1
+	case "waitredeem":
+		cmdArgs = 2
+	case "capabilities":
+		cmdArgs = 0
+	case "handshake":
+		cmdArgs = 1
+	case "watchtower":
+		cmdArgs = 0
+	case "decodetx":
+		cmdArgs = 1
+	case "genkeypair":
+		cmdArgs = 0
+	case "fundtestnet":
+		cmdArgs = 1
+	case "balance":
+		cmdArgs = 1
+	case "doctor":
+		cmdArgs = 1
+	case "sweep":
+		cmdArgs = 1
+	case "deriveholdingkey":
+		cmdArgs = 3
+	case "history":
+		cmdArgs = 1
+	case "state":
+		cmdArgs = 1
 	default:
 		return true, fmt.Errorf("unknown command %v", args[0])
 	}
@@ -199,9 +378,31 @@ func run() (showUsage bool, err error) {
 		return true, fmt.Errorf("unexpected argument: %s", flagset.Arg(0))
 	}
 
+	if err = setupLogging(); err != nil {
+		return true, err
+	}
 	if *testnetFlag {
 		targetNetwork = network.TestNetworkPassphrase
 	}
+	if *feeAccountFlag != "" {
+		if feeSigner, err = parseSigner(*feeAccountFlag); err != nil {
+			return true, fmt.Errorf("invalid fee account seed: %v", err)
+		}
+	}
+	if *txValidityFlag < time.Second {
+		return true, errors.New("-txvalidity must be at least a second")
+	}
+	if *baseFeeFlag != 0 && *baseFeeFlag < minBaseFee {
+		return true, fmt.Errorf("-basefee must be at least %d stroops", minBaseFee)
+	}
+	if *baseFeeFlag != 0 && args[0] == "refund" {
+		return true, errors.New("-basefee can not change the fee of the refund transaction, it is fixed when the holding account is set up")
+	}
+	if *policyFlag != "" {
+		if err = loadAmountPolicies(*policyFlag); err != nil {
+			return true, err
+		}
+	}
 
 	var client horizonclient.ClientInterface
 	switch targetNetwork {
@@ -211,46 +412,80 @@ func run() (showUsage bool, err error) {
 		client = horizonclient.DefaultTestNetClient
 
 	}
+	horizonURL := *horizonFlag
+	if horizonClient, ok := client.(*horizonclient.Client); ok && horizonURL == "" {
+		horizonURL = horizonClient.HorizonURL
+	}
+	if horizonURL != "" {
+		horizonClient, err := newHorizonClient(horizonURL)
+		if err != nil {
+			return false, err
+		}
+		client = horizonClient
+	}
 
 	var cmd command
 	switch args[0] {
 	case "initiate":
-		initiatorKeypair, err := keypair.Parse(args[1])
+		initiatorKeypair, err := parseSigner(args[1])
 		if err != nil {
 			return true, fmt.Errorf("invalid initiator seed: %v", err)
 		}
-		initiatorFullKeypair, ok := initiatorKeypair.(*keypair.Full)
-		if !ok {
-			return true, errors.New("invalid initiator seed")
-		}
 
-		_, err = keypair.Parse(args[2])
+		err = parseAddress(args[2])
 		if err != nil {
 			return true, fmt.Errorf("invalid participant address: %v", err)
 		}
+		if err = checkSwapAddresses(initiatorKeypair.Address(), args[2]); err != nil {
+			return true, err
+		}
 
-		_, err = strconv.ParseFloat(args[3], 64)
+		swapAmount, err := parseAmountArgument(args[3])
 		if err != nil {
 			return true, fmt.Errorf("failed to decode amount: %v", err)
 		}
 
-		cmd = &initiateCmd{InitiatorKeyPair: initiatorFullKeypair, cp2Addr: args[2], amount: args[3], asset: asset}
+		if *mediatorFlag != "" {
+			if err = parseAddress(*mediatorFlag); err != nil {
+				return true, fmt.Errorf("invalid mediator address: %v", err)
+			}
+		}
+
+		var secret []byte
+		if *secretFileFlag != "" {
+			secretArg := "-"
+			if *secretFileFlag != "-" {
+				secretArg = "@" + *secretFileFlag
+			}
+			hexSecret, err := readArgument(secretArg)
+			if err != nil {
+				return false, fmt.Errorf("failed to read the secret: %v", err)
+			}
+			registerSecret(hexSecret)
+			if secret, err = hex.DecodeString(hexSecret); err != nil {
+				return true, fmt.Errorf("failed to decode secret: %v", err)
+			}
+			if err = checkSecretSize(len(secret)); err != nil {
+				return true, err
+			}
+		}
+
+		cmd = &initiateCmd{InitiatorKeyPair: initiatorKeypair, cp2Addr: args[2], amount: swapAmount, asset: asset, secret: secret}
 	case "participate":
-		participatorKeypair, err := keypair.Parse(args[1])
+		participatorKeypair, err := parseSigner(args[1])
 		if err != nil {
 			return true, fmt.Errorf("invalid participator seed: %v", err)
 		}
-		participatorFullKeypair, ok := participatorKeypair.(*keypair.Full)
-		if !ok {
-			return true, errors.New("invalid participator seed")
-		}
 
-		_, err = keypair.Parse(args[2])
+		err = parseAddress(args[2])
 		if err != nil {
 			return true, fmt.Errorf("invalid initiator address: %v", err)
 		}
+		if err = checkSwapAddresses(participatorKeypair.Address(), args[2]); err != nil {
+			return true, err
+		}
 
-		_, err = strconv.ParseFloat(args[3], 64)
+		swapAmount, err := parseAmountArgument(args[3])
 		if err != nil {
 			return true, fmt.Errorf("failed to decode amount: %v", err)
 		}
@@ -262,68 +497,377 @@ func run() (showUsage bool, err error) {
 		if len(secretHash) != sha256.Size {
 			return true, errors.New("secret hash has wrong size")
 		}
-		cmd = &participateCmd{participatorKeyPair: participatorFullKeypair, cp1Addr: args[2], amount: args[3], secretHash: secretHash, asset: asset}
+		cmd = &participateCmd{participatorKeyPair: participatorKeypair, cp1Addr: args[2], amount: swapAmount, secretHash: secretHash, asset: asset}
 	case "auditcontract":
-		_, err = keypair.Parse(args[1])
+		err = parseAddress(args[1])
 		if err != nil {
 			return true, fmt.Errorf("invalid holding account address: %v", err)
 		}
-		refundTransaction, err := txnbuild.TransactionFromXDR(args[2])
+		refundTransaction, err := parseTransactionArgument(args[2])
 		if err != nil {
 			return true, fmt.Errorf("failed to decode refund transaction: %v", err)
 		}
-		cmd = &auditContractCmd{holdingAccountAdress: args[1], refundTx: refundTransaction}
+		expectedAmount := ""
+		if *expectedAmountFlag != "" {
+			if expectedAmount, err = parseAmountArgument(*expectedAmountFlag); err != nil {
+				return true, fmt.Errorf("invalid expected amount: %v", err)
+			}
+			//an expected amount of 0 or less would accept an empty holding account
+			if value, _ := amount.ParseInt64(expectedAmount); value <= 0 {
+				return true, fmt.Errorf("invalid expected amount %s, it should be positive", *expectedAmountFlag)
+			}
+		}
+		if *refundAddressFlag != "" {
+			if err = parseAddress(*refundAddressFlag); err != nil {
+				return true, fmt.Errorf("invalid refund address: %v", err)
+			}
+		}
+		auditCmd := auditContractCmd{holdingAccountAdress: args[1], refundTx: refundTransaction, expectedAmount: expectedAmount, asset: asset, refundAddress: *refundAddressFlag}
+		if *accountFileFlag != "" {
+			if *reportFlag != "" {
+				return true, errors.New("an audit report needs the transactions of the holding account, it can not be written offline")
+			}
+			cmd = &offlineAuditContractCmd{auditContractCmd: auditCmd, accountFile: *accountFileFlag}
+		} else {
+			cmd = &auditCmd
+		}
 	case "refund":
 
-		refundTransaction, err := txnbuild.TransactionFromXDR(args[1])
+		refundTransaction, err := parseTransactionArgument(args[1])
 		if err != nil {
 			return true, fmt.Errorf("failed to decode refund transaction: %v", err)
 		}
-		cmd = &refundCmd{refundTx: refundTransaction}
+		cmd = &refundCmd{refundTx: refundTransaction, sep7: *sep7Flag}
 	case "redeem":
 
-		receiverKeypair, err := keypair.Parse(args[1])
-		if err != nil {
-			return true, fmt.Errorf("invalid receiver seed: %v", err)
-		}
-		receiverFullKeypair, ok := receiverKeypair.(*keypair.Full)
-		if !ok {
-			return true, errors.New("invalid receiver seed")
+		var receiverKeypair stellar.Signer
+		receiverAddress := args[1]
+		//When collecting signatures or signing in a wallet, the receiver can be passed as an address and sign later
+		if (*collectFlag == "" && !*sep7Flag) || parseAddress(receiverAddress) != nil {
+			receiverKeypair, err = parseSigner(args[1])
+			if err != nil {
+				return true, fmt.Errorf("invalid receiver seed: %v", err)
+			}
+			receiverAddress = receiverKeypair.Address()
 		}
-		_, err = keypair.Parse(args[2])
+		err = parseAddress(args[2])
 		if err != nil {
 			return true, fmt.Errorf("invalid holding account address: %v", err)
 		}
+		if receiverAddress == args[2] {
+			return true, errors.New("the receiver can not be the holding account itself")
+		}
+		registerSecret(args[3])
 		secret, err := hex.DecodeString(args[3])
 		if err != nil {
 			return true, fmt.Errorf("failed to decode secret: %v", err)
 		}
-		if len(secret) != secretSize {
-			return true, fmt.Errorf("The secret should be %d bytes instead of %d", secretSize, len(secret))
+		if err = checkSecretSize(len(secret)); err != nil {
+			return true, err
 		}
-		cmd = &redeemCmd{ReceiverKeyPair: receiverFullKeypair, holdingAccountAddress: args[2], secret: secret}
+		cmd = &redeemCmd{ReceiverKeyPair: receiverKeypair, receiverAddress: receiverAddress, holdingAccountAddress: args[2], secret: secret, collectFile: *collectFlag, sep7: *sep7Flag}
 
 	case "extractsecret":
 
-		_, err = keypair.Parse(args[1])
+		err = parseAddress(args[1])
+		if err != nil {
+			return true, fmt.Errorf("invalid holding account address: %v", err)
+		}
+		cmd = &extractSecretCmd{holdingAccountAdress: args[1], secretHash: args[2], follow: *followFlag}
+	case "savekey":
+		//the seed is read from stdin or a file with - or @<file>, on the command line it ends up in the process list and the shell history
+		seed, err := readArgument(args[2])
+		if err != nil {
+			return false, fmt.Errorf("Failed to read the seed: %v", err)
+		}
+		registerSecret(seed)
+		cmd = &saveKeyCmd{alias: args[1], seed: seed}
+	case "attest":
+		attestSigner, err := parseSigner(args[1])
+		if err != nil {
+			return true, fmt.Errorf("invalid seed: %v", err)
+		}
+		cmd = &attestCmd{signer: attestSigner, termsFile: args[2]}
+	case "verifyattestation":
+		cmd = &verifyAttestationCmd{attestationFile: args[1]}
+	case "bootstrap":
+		bootstrapSigner, err := parseSigner(args[1])
+		if err != nil {
+			return true, fmt.Errorf("invalid seed: %v", err)
+		}
+		cmd = &bootstrapCmd{signer: bootstrapSigner, asset: asset, reportFile: args[2]}
+	case "releasesecret":
+		mediatorKeyPair, err := escrowKeyPair(args[1])
+		if err != nil {
+			return true, fmt.Errorf("invalid mediator seed: %v", err)
+		}
+		if err = parseAddress(args[3]); err != nil {
+			return true, fmt.Errorf("invalid participant address: %v", err)
+		}
+		cmd = &releaseSecretCmd{mediatorKeyPair: mediatorKeyPair, escrow: args[2], participantAddress: args[3]}
+	case "opensecret":
+		keyPair, err := escrowKeyPair(args[1])
+		if err != nil {
+			return true, fmt.Errorf("invalid seed: %v", err)
+		}
+		cmd = &openSecretCmd{keyPair: keyPair, escrow: args[2]}
+	case "autoswap":
+		cmd = &autoSwapCmd{configFile: args[1]}
+	case "swapd":
+		swapdSigner, err := parseSigner(args[1])
+		if err != nil {
+			return true, fmt.Errorf("invalid seed: %v", err)
+		}
+		swapdNotifiers, err := newNotifiers(*webhookFlag, *notifyFlag)
+		if err != nil {
+			return true, err
+		}
+		if (*tlsCertFlag == "") != (*tlsKeyFlag == "") {
+			return true, errors.New("-tlscert and -tlskey have to be passed together")
+		}
+		if *tlsCertFlag == "" && !isLoopbackAddress(args[2]) {
+			return true, fmt.Errorf("Refusing to serve the swapd API on %s without TLS, pass -tlscert and -tlskey or listen on localhost", args[2])
+		}
+		var topUp *feeTopUp
+		if *feeTopUpFlag != "" {
+			if feeSigner == nil {
+				return true, errors.New("-feetopup needs a -feeaccount to top up")
+			}
+			if feeSigner.Address() == swapdSigner.Address() {
+				return true, errors.New("-feetopup needs a -feeaccount other than the funding account")
+			}
+			if topUp, err = newFeeTopUp(*feeTopUpFlag, *feeTopUpAmountFlag, *feeTopUpLimitFlag); err != nil {
+				return true, err
+			}
+		}
+		cmd = &swapdCmd{signer: swapdSigner, asset: asset, listenAddress: args[2], tlsCert: *tlsCertFlag, tlsKey: *tlsKeyFlag, notifiers: swapdNotifiers, feeTopUp: topUp}
+	case "listswaps":
+		switch *statusFlag {
+		case "", swapStateActive, swapStateRedeemable, swapStateRefundable, swapStateCompleted, swapStateFailed:
+		default:
+			return true, fmt.Errorf("invalid status %q", *statusFlag)
+		}
+		cmd = &listSwapsCmd{status: *statusFlag}
+	case "resume":
+		fundingSigner, err := parseSigner(args[1])
+		if err != nil {
+			return true, fmt.Errorf("invalid seed: %v", err)
+		}
+		if err = parseAddress(args[2]); err != nil {
+			return true, fmt.Errorf("invalid holding account address: %v", err)
+		}
+		cmd = &resumeCmd{fundingSigner: fundingSigner, holdingAccount: args[2]}
+	case "halt":
+		cmd = &haltCmd{halted: true, reason: args[1]}
+	case "unhalt":
+		cmd = &haltCmd{halted: false}
+	case "exportswap":
+		if err = parseAddress(args[1]); err != nil {
+			return true, fmt.Errorf("invalid holding account address: %v", err)
+		}
+		cmd = &exportSwapCmd{holdingAccount: args[1]}
+	case "importswap":
+		blob, err := decodeSwapBlob(args[1])
+		if err != nil {
+			return true, fmt.Errorf("invalid swap: %v", err)
+		}
+		cmd = &importSwapCmd{blob: blob}
+	case "status":
+		if err = parseAddress(args[1]); err != nil {
+			return true, fmt.Errorf("invalid holding account address: %v", err)
+		}
+		cmd = &statusCmd{holdingAccount: args[1]}
+	case "waitredeem":
+		if err = parseAddress(args[1]); err != nil {
+			return true, fmt.Errorf("invalid holding account address: %v", err)
+		}
+		if secretHash, err := hex.DecodeString(args[2]); err != nil || len(secretHash) != swapcrypto.SHA256.Size() {
+			return true, fmt.Errorf("invalid secret hash %q", args[2])
+		}
+		cmd = &waitRedeemCmd{holdingAccountAddress: args[1], secretHash: args[2]}
+	case "capabilities":
+		cmd = &capabilitiesCmd{}
+	case "handshake":
+		cmd = &handshakeCmd{peerFile: args[1]}
+	case "watchtower":
+		watchtowerNotifiers, err := newNotifiers(*webhookFlag, *notifyFlag)
+		if err != nil {
+			return true, err
+		}
+		cmd = &watchtowerCmd{notifiers: watchtowerNotifiers, alertBefore: *alertBeforeFlag}
+	case "decodetx":
+		cmd = &decodeTxCmd{transaction: args[1]}
+	case "genkeypair":
+		cmd = &genKeyPairCmd{fundTestnet: *fundTestnetFlag}
+	case "fundtestnet":
+		if err = parseAddress(args[1]); err != nil {
+			return true, fmt.Errorf("invalid address: %v", err)
+		}
+		cmd = &fundTestnetCmd{address: args[1]}
+	case "balance":
+		if err = parseAddress(args[1]); err != nil {
+			return true, fmt.Errorf("invalid address: %v", err)
+		}
+		cmd = &balanceCmd{address: args[1]}
+	case "doctor":
+		cmd = &doctorCmd{key: args[1], asset: asset}
+	case "sweep":
+		if holdingKeyPair, err := keypair.Parse(args[1]); err == nil {
+			if full, ok := holdingKeyPair.(*keypair.Full); ok {
+				registerSecret(args[1])
+				cmd = &sweepCmd{holdingKeyPair: full, holdingAccount: full.Address()}
+				break
+			}
+		}
+		if err = parseAddress(args[1]); err != nil {
+			return true, fmt.Errorf("invalid holding account seed or address: %v", err)
+		}
+		cmd = &sweepCmd{holdingAccount: args[1]}
+	case "deriveholdingkey":
+		fundingSigner, err := parseSigner(args[1])
 		if err != nil {
+			return true, fmt.Errorf("invalid seed: %v", err)
+		}
+		fundingKeyPair, ok := fundingSigner.(*keypair.Full)
+		if !ok {
+			return true, errors.New("the seed of the funding account is needed, an external signer can not be used")
+		}
+		if err = parseAddress(args[2]); err != nil {
+			return true, fmt.Errorf("invalid counterparty address: %v", err)
+		}
+		secretHash, err := hex.DecodeString(args[3])
+		if err != nil || len(secretHash) != swapcrypto.SHA256.Size() {
+			return true, fmt.Errorf("invalid secret hash %q", args[3])
+		}
+		cmd = &deriveHoldingKeyCmd{fundingKeyPair: fundingKeyPair, counterPartyAddress: args[2], secretHash: secretHash}
+	case "history":
+		if err = parseAddress(args[1]); err != nil {
 			return true, fmt.Errorf("invalid holding account address: %v", err)
 		}
-		cmd = &extractSecretCmd{holdingAccountAdress: args[1], secretHash: args[2]}
+		cmd = &historyCmd{holdingAccount: args[1]}
+	case "state":
+		if args[1] != "verify" {
+			return true, fmt.Errorf("unknown state command %v", args[1])
+		}
+		cmd = &verifyStateCmd{}
 	}
-	err = cmd.runCommand(client)
+	if cmd, ok := cmd.(offlineCommand); ok {
+		return false, cmd.runOfflineCommand()
+	}
+	err = runCommand(cmd, client, *timeoutFlag)
 	return false, err
 }
 
-func sha256Hash(x []byte) []byte {
-	h := sha256.Sum256(x)
-	return h[:]
+//parseAmountArgument parses an amount argument following stellar's amount rules: a decimal number with at most 7 decimals,
+//or with -stroops an integer number of stroops. The amount is returned in the decimal notation used by horizon and txnbuild.
+func parseAmountArgument(arg string) (string, error) {
+	if *stroopsFlag {
+		stroops, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid number of stroops %q", arg)
+		}
+		return amount.StringFromInt64(stroops), nil
+	}
+	if _, err := amount.ParseInt64(arg); err != nil {
+		return "", err
+	}
+	return arg, nil
+}
+
+//waitForAccountDetail gets the details of an account, retrying with backoff for up to wait while horizon reports it does not exist.
+//When the counterparty just created the holding account, horizon may not have ingested it yet.
+func waitForAccountDetail(address string, wait time.Duration, client horizonclient.ClientInterface) (account hprotocol.Account, err error) {
+	deadline := time.Now().Add(wait)
+	backoff := time.Second
+	for {
+		account, err = client.AccountDetail(horizonclient.AccountRequest{AccountID: address})
+		if err == nil || !stellar.IsNotFoundError(err) || time.Now().Add(backoff).After(deadline) {
+			return
+		}
+		logger.WithField("account", address).Debugf("Account not found, retrying in %v (waiting until %s)", backoff, deadline.Format(time.Stamp))
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > 30*time.Second {
+			backoff = 30 * time.Second
+		}
+	}
 }
-func createRefundTransaction(holdingAccountAddress string, refundAccountAdress string, locktime time.Time, client horizonclient.ClientInterface) (refundTransaction txnbuild.Transaction, err error) {
-	holdingAccount, err := stellar.GetAccount(holdingAccountAddress, client)
+
+//printJSONError prints the error as json, including the completed steps of a partially executed command
+func printJSONError(err error) {
+	output := struct {
+		Error string `json:"error"`
+		*setupError
+	}{Error: redact(err.Error())}
+	if se, ok := err.(*setupError); ok {
+		output.Error = redact(se.err.Error())
+		output.setupError = se
+	}
+	jsonoutput, _ := json.Marshal(output)
+	fmt.Fprintln(os.Stderr, string(jsonoutput))
+}
+
+//parseSigner parses a strkey encoded seed, derives the keypair from a mnemonic
+//or creates an external signer from a `backend:key` specification
+func parseSigner(seedOrMnemonic string) (stellar.Signer, error) {
+	if signer.IsSpec(seedOrMnemonic) {
+		return signer.FromSpec(seedOrMnemonic)
+	}
+	registerSecret(seedOrMnemonic)
+	if stellar.IsMnemonic(seedOrMnemonic) {
+		return stellar.KeyPairFromMnemonic(seedOrMnemonic, "", *keyPathFlag)
+	}
+	kp, err := keypair.Parse(seedOrMnemonic)
+	if err != nil {
+		return nil, err
+	}
+	fullKeyPair, ok := kp.(*keypair.Full)
+	if !ok {
+		return nil, errors.New("an address was given instead of a seed")
+	}
+	return fullKeyPair, nil
+}
+
+//parseAddress checks that an account address is given and not a seed
+func parseAddress(address string) error {
+	kp, err := keypair.Parse(address)
 	if err != nil {
+		return err
+	}
+	if _, ok := kp.(*keypair.Full); ok {
+		return errors.New("a seed was given instead of an address, make sure you did not share it")
+	}
+	return nil
+}
+
+//checkSwapAddresses rejects swaps where the funding account and the counterparty are the same
+func checkSwapAddresses(fundingAddress string, counterPartyAddress string) error {
+	if fundingAddress == counterPartyAddress {
+		return fmt.Errorf("the counterparty address %s is the address of the funding account itself", counterPartyAddress)
+	}
+	return nil
+}
+
+//holdingAccountDataEntries returns the data entries identifying the holding account as an atomic swap escrow of this tool.
+//The secret hash is used as the swap ID.
+func holdingAccountDataEntries(secretHash []byte) (entries []txnbuild.ManageData) {
+	if !*tagFlag {
 		return
 	}
+	return []txnbuild.ManageData{
+		{Name: "atomicswap", Value: []byte("stellaratomicswap " + version)},
+		{Name: "atomicswap.id", Value: secretHash},
+	}
+}
+
+//createRefundTransaction builds the refund transaction of a holding account whose current state is given
+func createRefundTransaction(holdingAccount *horizon.Account, refundAccountAdress string, locktime time.Time, dataEntries []txnbuild.ManageData, baseFee uint32, networkPassphrase string) (refundTransaction txnbuild.Transaction, err error) {
+	//The data entries are only added after the refund transaction is created but need to be removed before the merge
+	if len(dataEntries) > 0 && holdingAccount.Data == nil {
+		holdingAccount.Data = make(map[string]string, len(dataEntries))
+	}
+	for _, entry := range dataEntries {
+		holdingAccount.Data[entry.Name] = base64.StdEncoding.EncodeToString(entry.Value)
+	}
 	_, err = holdingAccount.IncrementSequenceNumber()
 	if err != nil {
 		err = fmt.Errorf("Unable to increment the sequence number of the holding account:%v", err)
@@ -335,8 +879,9 @@ func createRefundTransaction(holdingAccountAddress string, refundAccountAdress s
 	refundTransaction = txnbuild.Transaction{
 		Timebounds:    txnbuild.NewTimebounds(locktime.Unix(), int64(0)),
 		Operations:    operations,
-		Network:       targetNetwork,
+		Network:       networkPassphrase,
 		SourceAccount: holdingAccount,
+		BaseFee:       baseFee,
 	}
 
 	if err = refundTransaction.Build(); err != nil {
@@ -346,38 +891,119 @@ func createRefundTransaction(holdingAccountAddress string, refundAccountAdress s
 	return
 }
 
-//createHoldingAccountTransaction creates a new account to hold the atomic swap balance
-//with the signers modified to the atomic swap rules:
-//- signature of the destinee and the secret
-//- hash of a specific transaction that is present on the chain
-//    that merges the escrow account to the account that needs to withdraw
-//    and that can only be published in the future ( timeout mechanism)
+//createHoldingAccount creates, funds and locks the holding account in a single transaction of the funding account,
+//so the holding account never exists without the swap signers. For a non native asset, the same transaction adds the trustline and pays amount of the asset.
+//A new account starts with the ledger it is created in as sequence number, which is not known in advance: the transaction bumps the sequence number
+//of the holding account to a value higher than any ledger it can be applied in, so the refund transaction the signers refer to can be built before it.
+//The sequence number of the funding account is incremented so it can be used for the next transaction, unless a fee account is the source of the transaction.
+func createHoldingAccount(fundingSigner stellar.Signer, holdingAccountKeyPair *keypair.Full, counterPartyAddress string, xlmAmount string, amount string, asset txnbuild.Asset, secretHash []byte, locktime time.Time, refundBaseFee uint32, fundingAccount *horizon.Account, progress *setupProgress, networkPassphrase string, client horizonclient.ClientInterface) (refundTransaction txnbuild.Transaction, err error) {
+	ledger, err := latestLedger(client)
+	if err != nil {
+		return
+	}
+	holdingSequence := holdingAccountSequence(ledger.Sequence)
+	holdingAccount := newHoldingAccount(holdingAccountKeyPair.Address(), holdingSequence, amount, asset)
+	dataEntries := holdingAccountDataEntries(secretHash)
+	refundTransaction, err = createRefundTransaction(newHoldingAccount(holdingAccountKeyPair.Address(), holdingSequence, amount, asset), fundingSigner.Address(), locktime, dataEntries, refundBaseFee, networkPassphrase)
+	if err != nil {
+		return
+	}
+	refundTransactionHash, err := refundTransaction.Hash()
+	if err != nil {
+		err = fmt.Errorf("Failed to Hash the refund transaction: %s", err)
+		return
+	}
+	progress.done(stepRefundTxCreated)
 
-//createHoldingAccount creates a new account to hold the atomic swap balance
-func createHoldingAccount(holdingAccountAddress string, amount string, fundingKeyPair *keypair.Full, network string, asset txnbuild.Asset, client horizonclient.ClientInterface) (err error) {
-	fundingAccount, err := stellar.GetAccount(fundingKeyPair.Address(), client)
+	createAccountTransaction, err := stellar.CreateAccountTransaction(holdingAccount.AccountID, xlmAmount, fundingAccount, networkPassphrase, suggestBaseFee(client), setupTimebounds())
 	if err != nil {
+		err = fmt.Errorf("Failed to create the holding account transaction: %s", err)
 		return
 	}
-	createAccountTransaction, err := stellar.CreateAccountTransaction(holdingAccountAddress, amount, fundingAccount, network)
+	if !asset.IsNative() {
+		createAccountTransaction.Operations = append(createAccountTransaction.Operations, createFundingOperations(fundingAccount, holdingAccount, amount, asset)...)
+	}
+	createAccountTransaction.Operations = append(createAccountTransaction.Operations, &txnbuild.BumpSequence{BumpTo: holdingSequence, SourceAccount: holdingAccount})
+	signingOperations, err := createHoldingAccountSigningOperations(holdingAccount, counterPartyAddress, secretHash, refundTransactionHash[:], dataEntries, *homeDomainFlag)
 	if err != nil {
-		return fmt.Errorf("Failed to create the holding account transaction: %s", err)
+		err = fmt.Errorf("Failed to create the signing options: %s", err)
+		return
 	}
-	txe, err := createAccountTransaction.BuildSignEncode(fundingKeyPair)
+	createAccountTransaction.Operations = append(createAccountTransaction.Operations, signingOperations...)
+	signers, unlock, err := useFeeAccount(&createAccountTransaction, []stellar.Signer{fundingSigner, holdingAccountKeyPair}, client)
 	if err != nil {
-		return fmt.Errorf("Failed to sign the holding account transaction: %s", err)
+		err = fmt.Errorf("Failed to get the fee account: %v", err)
+		return
 	}
-	_, err = stellar.SubmitTransaction(txe, client)
+	defer unlock()
+	txe, err := stellar.BuildSignEncode(&createAccountTransaction, signers...)
 	if err != nil {
-		accountID, err2 := createAccountTransaction.HashHex()
-		if err2 != nil {
-			panic(err2)
-		}
-		return fmt.Errorf("Failed to publish the holding account creation transaction : %s\n%s", accountID, err)
+		err = fmt.Errorf("Failed to sign the holding account transaction: %s", err)
+		return
 	}
+	progress.start(stepAccountCreated)
+	if !asset.IsNative() {
+		progress.start(stepAccountFunded)
+	}
+	progress.start(stepOptionsSet)
+	txSuccess, err := stellar.SubmitTransaction(txe, client)
+	if err != nil {
+		transactionID, _ := createAccountTransaction.HashHex()
+		err = fmt.Errorf("Failed to publish the holding account creation transaction : %s\n%w", transactionID, err)
+		return
+	}
+	if int64(txSuccess.Ledger)<<32 >= holdingSequence {
+		//only possible if ledgers closed faster than one per second while the transaction was valid
+		err = fmt.Errorf("The holding account was created in ledger %d, after the sequence number the refund transaction was built for", txSuccess.Ledger)
+		return
+	}
+	progress.done(stepAccountCreated)
+	if !asset.IsNative() {
+		progress.done(stepAccountFunded)
+	}
+	progress.done(stepOptionsSet)
 	return
 }
-func createHoldingAccountSigningTransaction(holdingAccount *horizon.Account, counterPartyAddress string, secretHash []byte, refundTxHash []byte, network string) (setOptionsTransaction txnbuild.Transaction, err error) {
+
+//holdingAccountSequence returns the sequence number a holding account is bumped to when it is created.
+//It is above the starting sequence number of an account created in any ledger that closes while the setup transaction is valid,
+//counting at most one ledger per second.
+func holdingAccountSequence(latestLedger int32) int64 {
+	return (int64(latestLedger) + int64(txValidityFlag.Seconds()) + 60) << 32
+}
+
+//setupTimebounds limits the validity of a transaction setting up a holding account to -txvalidity from now,
+//so a delayed or replayed transaction can not set up the account long after the swap was abandoned
+func setupTimebounds() txnbuild.Timebounds {
+	return txnbuild.NewTimeout(int64(txValidityFlag.Seconds()))
+}
+
+//newHoldingAccount returns the state of a holding account with sequence number sequence right after it was created and funded with amount of asset,
+//so the transactions of the holding account can be built without fetching it from horizon
+func newHoldingAccount(address string, sequence int64, amount string, asset txnbuild.Asset) *horizon.Account {
+	account := &horizon.Account{
+		AccountID: address,
+		Sequence:  strconv.FormatInt(sequence, 10),
+	}
+	if !asset.IsNative() {
+		balance := horizon.Balance{Balance: amount}
+		balance.Code = asset.GetCode()
+		balance.Issuer = asset.GetIssuer()
+		balance.Type = "credit_alphanum4"
+		if len(balance.Code) > 4 {
+			balance.Type = "credit_alphanum12"
+		}
+		account.Balances = append(account.Balances, balance)
+	}
+	return account
+}
+
+//createHoldingAccountSigningOperations returns the operations setting the signers of the holding account to the atomic swap rules:
+//- signature of the destinee and the secret
+//- hash of a specific transaction that is present on the chain
+//    that merges the escrow account to the account that needs to withdraw
+//    and that can only be published in the future ( timeout mechanism)
+func createHoldingAccountSigningOperations(holdingAccount *horizon.Account, counterPartyAddress string, secretHash []byte, refundTxHash []byte, dataEntries []txnbuild.ManageData, homeDomain string) (operations []txnbuild.Operation, err error) {
 
 	depositorSigningOperation := txnbuild.SetOptions{
 		Signer: &txnbuild.Signer{
@@ -415,70 +1041,91 @@ func createHoldingAccountSigningTransaction(holdingAccount *horizon.Account, cou
 		HighThreshold:   txnbuild.NewThreshold(txnbuild.Threshold(2)),
 		SourceAccount:   holdingAccount,
 	}
-	setOptionsTransaction = txnbuild.Transaction{
-		SourceAccount: holdingAccount, //TODO: check if this can be changed to the fundingaccount
-		Operations: []txnbuild.Operation{
-			&depositorSigningOperation,
-			&secretSigningOperation,
-			&refundSigningOperation,
-			&setSigningWeightsOperation,
-		},
-		Network:    network,
-		Timebounds: txnbuild.NewInfiniteTimeout(), //TODO: Use a real timeout
+	operations = make([]txnbuild.Operation, 0, len(dataEntries)+5)
+	for i := range dataEntries {
+		dataEntries[i].SourceAccount = holdingAccount
+		operations = append(operations, &dataEntries[i])
 	}
-
+	if homeDomain != "" {
+		operations = append(operations, &txnbuild.SetOptions{
+			HomeDomain:    &homeDomain,
+			SourceAccount: holdingAccount,
+		})
+	}
+	operations = append(operations,
+		&depositorSigningOperation,
+		&secretSigningOperation,
+		&refundSigningOperation,
+		&setSigningWeightsOperation,
+	)
 	return
 }
-func setHoldingAccountSigningOptions(holdingAccountKeyPair *keypair.Full, counterPartyAddress string, secretHash []byte, refundTxHash []byte, network string, client horizonclient.ClientInterface) (err error) {
 
-	holdingAccountAddress := holdingAccountKeyPair.Address()
-	holdingAccount, err := stellar.GetAccount(holdingAccountAddress, client)
+//createHoldingAccountSigningTransaction creates the transaction of the holding account setting its signers,
+//only resume still needs it for a holding account that was created without them
+func createHoldingAccountSigningTransaction(holdingAccount *horizon.Account, counterPartyAddress string, secretHash []byte, refundTxHash []byte, dataEntries []txnbuild.ManageData, homeDomain string, network string, baseFee uint32) (setOptionsTransaction txnbuild.Transaction, err error) {
+	operations, err := createHoldingAccountSigningOperations(holdingAccount, counterPartyAddress, secretHash, refundTxHash, dataEntries, homeDomain)
 	if err != nil {
 		return
 	}
-	setSigningOptionsTransaction, err := createHoldingAccountSigningTransaction(holdingAccount, counterPartyAddress, secretHash, refundTxHash, targetNetwork)
-	if err != nil {
-		return fmt.Errorf("Failed to create the signing options transaction: %s", err)
+	setOptionsTransaction = txnbuild.Transaction{
+		SourceAccount: holdingAccount,
+		Operations:    operations,
+		Network:       network,
+		Timebounds:    setupTimebounds(),
+		BaseFee:       baseFee,
 	}
-	txe, err := setSigningOptionsTransaction.BuildSignEncode(holdingAccountKeyPair)
+
+	return
+}
+
+// signHoldingAccountSigningOptions creates and signs the transaction setting the atomic swap signers on the holding account
+func signHoldingAccountSigningOptions(holdingAccountKeyPair *keypair.Full, holdingAccount *horizon.Account, counterPartyAddress string, secretHash []byte, refundTxHash []byte, dataEntries []txnbuild.ManageData, network string, baseFee uint32) (txe string, err error) {
+	setSigningOptionsTransaction, err := createHoldingAccountSigningTransaction(holdingAccount, counterPartyAddress, secretHash, refundTxHash, dataEntries, *homeDomainFlag, network, baseFee)
 	if err != nil {
-		return fmt.Errorf("Failed to sign the signing options transaction: %s", err)
+		err = fmt.Errorf("Failed to create the signing options transaction: %s", err)
+		return
 	}
-	_, err = stellar.SubmitTransaction(txe, client)
+	txe, err = setSigningOptionsTransaction.BuildSignEncode(holdingAccountKeyPair)
 	if err != nil {
-		return fmt.Errorf("Failed to publish the signing options transaction : %s", err)
+		err = fmt.Errorf("Failed to sign the signing options transaction: %s", err)
 	}
 	return
 }
-func fundHoldingAccount(fundingKeyPair *keypair.Full, holdingAccountKeyPair *keypair.Full, amount string, asset txnbuild.Asset, client horizonclient.ClientInterface) (err error) {
-	holdingAccount, err := stellar.GetAccount(holdingAccountKeyPair.Address(), client)
-	if err != nil {
-		return
-	}
 
+//createFundingOperations returns the operations adding the trustline to the holding account and paying it amount of asset
+func createFundingOperations(fundingAccount *horizon.Account, holdingAccount *horizon.Account, amount string, asset txnbuild.Asset) []txnbuild.Operation {
 	changetrust := txnbuild.ChangeTrust{
 		Line:          txnbuild.CreditAsset{Code: asset.GetCode(), Issuer: asset.GetIssuer()},
 		Limit:         amount,
 		SourceAccount: holdingAccount,
 	}
-	fundingAccount, err := stellar.GetAccount(fundingKeyPair.Address(), client)
-	if err != nil {
-		return
-	}
 	payment := txnbuild.Payment{
 		Destination:   holdingAccount.AccountID,
 		Amount:        amount,
 		Asset:         asset,
 		SourceAccount: fundingAccount,
 	}
+	return []txnbuild.Operation{&changetrust, &payment}
+}
 
+//fundHoldingAccount funds a holding account that was created without the asset, only resume still needs it
+//for a setup that was interrupted before the creation and the funding were combined in one transaction
+func fundHoldingAccount(fundingKeyPair stellar.Signer, fundingAccount *horizon.Account, holdingAccountKeyPair *keypair.Full, holdingAccount *horizon.Account, amount string, asset txnbuild.Asset, networkPassphrase string, client horizonclient.ClientInterface) (err error) {
 	tx := txnbuild.Transaction{
 		SourceAccount: fundingAccount,
-		Operations:    []txnbuild.Operation{&changetrust, &payment},
-		Timebounds:    txnbuild.NewInfiniteTimeout(), // Use a real timeout in production!
-		Network:       targetNetwork,
+		Operations:    createFundingOperations(fundingAccount, holdingAccount, amount, asset),
+		Timebounds:    setupTimebounds(),
+		Network:       networkPassphrase,
+		BaseFee:       suggestBaseFee(client),
 	}
-	txe, err := tx.BuildSignEncode(holdingAccountKeyPair, fundingKeyPair)
+	signers, unlock, err := useFeeAccount(&tx, []stellar.Signer{holdingAccountKeyPair, fundingKeyPair}, client)
+	if err != nil {
+		err = fmt.Errorf("Failed to get the fee account: %v", err)
+		return
+	}
+	defer unlock()
+	txe, err := stellar.BuildSignEncode(&tx, signers...)
 	if err != nil {
 		err = fmt.Errorf("Failed to build,sign and encode the funding transaction: %v", err)
 		return
@@ -486,148 +1133,317 @@ func fundHoldingAccount(fundingKeyPair *keypair.Full, holdingAccountKeyPair *key
 	_, err = stellar.SubmitTransaction(txe, client)
 	if err != nil {
 		transactionID, _ := tx.HashHex()
-		err = fmt.Errorf("Failed to publish the funding transaction : %s\n%s", transactionID, err)
+		err = fmt.Errorf("Failed to publish the funding transaction : %s\n%w", transactionID, err)
 		return
 	}
 	return
 }
-func createAtomicSwapHoldingAccount(fundingKeyPair *keypair.Full, holdingAccountKeyPair *keypair.Full, counterPartyAddress string, amount string, secretHash []byte, locktime time.Time, asset txnbuild.Asset, client horizonclient.ClientInterface) (refundTransaction txnbuild.Transaction, err error) {
+
+// createAtomicSwapHoldingAccount sets up the holding account and records its state in the swap database
+func createAtomicSwapHoldingAccount(fundingKeyPair stellar.Signer, holdingAccountKeyPair *keypair.Full, counterPartyAddress string, amount string, secretHash []byte, locktime time.Time, asset txnbuild.Asset, record *swapdb.Swap, progress *setupProgress, networkPassphrase string, client horizonclient.ClientInterface) (refundTransaction txnbuild.Transaction, err error) {
 
 	holdingAccountAddress := holdingAccountKeyPair.Address()
+	if holdingAccountAddress == counterPartyAddress || holdingAccountAddress == fundingKeyPair.Address() {
+		err = errors.New("the holding account can not be the funding account or the counterparty")
+		return
+	}
 
+	if err = checkAmountPolicy(amount, asset); err != nil {
+		return
+	}
 	xlmAmount := "10"
 	if asset.IsNative() {
 		xlmAmount = amount
 	}
-	err = createHoldingAccount(holdingAccountAddress, xlmAmount, fundingKeyPair, targetNetwork, asset, client)
+	fundingAccount, err := stellar.GetAccount(fundingKeyPair.Address(), client)
 	if err != nil {
 		return
 	}
-
-	if !asset.IsNative() {
-		err = fundHoldingAccount(fundingKeyPair, holdingAccountKeyPair, amount, asset, client)
+	if err = checkSetupFunds(fundingAccount, xlmAmount, amount, asset, secretHash, client); err != nil {
+		return
+	}
+	progress.setHoldingAccount(holdingAccountKeyPair)
+	record.RefundBaseFee = refundBaseFee()
+	updateSwapDB(func(db *swapdb.DB) error { return db.Put(record) })
+	defer func() {
+		if err != nil && stellar.IsTransientError(err) {
+			refundTransaction, err = retryHoldingAccountSetup(err, fundingKeyPair, holdingAccountKeyPair, record, asset, secretHash, progress, networkPassphrase, client)
+		}
 		if err != nil {
+			recordSetup(record, progress, nil, err)
+		} else {
+			recordSetup(record, progress, &refundTransaction, nil)
+		}
+	}()
+	refundTransaction, err = createHoldingAccount(fundingKeyPair, holdingAccountKeyPair, counterPartyAddress, xlmAmount, amount, asset, secretHash, locktime, record.RefundBaseFee, fundingAccount, progress, networkPassphrase, client)
+	return
+}
+
+func (cmd *initiateCmd) progress() *setupProgress {
+	return &cmd.setup
+}
+
+func (cmd *participateCmd) progress() *setupProgress {
+	return &cmd.setup
+}
+
+//checkSecretSize verifies a secret fits a hash(x) signer and is not too small to guess
+func checkSecretSize(size int) error {
+	if size < swapcrypto.MinSecretSize || size > stellar.MaxHashXPreimageSize {
+		return fmt.Errorf("The secret should be %d to %d bytes instead of %d", swapcrypto.MinSecretSize, stellar.MaxHashXPreimageSize, size)
+	}
+	return nil
+}
+
+//checkSecretUnused refuses a given secret whose hash is already recorded in the swap database or in a secrethash file in -outdir:
+//once a secret is revealed by a redeem, anyone can unlock every contract locked with its hash
+func checkSecretUnused(secretHash []byte) error {
+	hexSecretHash := hex.EncodeToString(secretHash)
+	if *swapDBFlag != "" {
+		var swaps []swapdb.Swap
+		err := withSwapDB(func(db *swapdb.DB) (err error) {
+			swaps, err = db.FindBySecretHash(hexSecretHash)
 			return
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to check the swap database for an earlier use of the secret: %v", err)
+		}
+		if len(swaps) > 0 {
+			return fmt.Errorf("The secret was already used for the swap of holding account %s, never use a secret twice", swaps[0].HoldingAccount)
+		}
+	} else {
+		logger.Warn("The swap database is disabled, it can not be checked that the secret was not used before")
+	}
+	if *outDirFlag != "" {
+		paths, _ := filepath.Glob(filepath.Join(*outDirFlag, "*.secrethash"))
+		for _, path := range paths {
+			content, err := ioutil.ReadFile(path)
+			if err == nil && strings.TrimSpace(string(content)) == hexSecretHash {
+				return fmt.Errorf("The secret was already used for the swap in %s, never use a secret twice", path)
+			}
 		}
 	}
+	return nil
+}
 
-	refundTransaction, err = createRefundTransaction(holdingAccountAddress, fundingKeyPair.Address(), locktime, client)
-	if err != nil {
+// generateSecret creates a random secret of -secretsize bytes, mixed with -entropy if given, and its sha256 hash
+func generateSecret() (secret []byte, secretHash []byte, err error) {
+	if err = checkSecretSize(*secretSizeFlag); err != nil {
 		return
 	}
-	refundTransactionHash, err := refundTransaction.Hash()
-	if err != nil {
-		err = fmt.Errorf("Failed to Hash the refund transaction: %s", err)
-		return
+	if *entropyFlag != "" {
+		entropy, decodeErr := hex.DecodeString(*entropyFlag)
+		if decodeErr != nil {
+			return nil, nil, fmt.Errorf("The entropy should be hex encoded: %v", decodeErr)
+		}
+		secret, secretHash, err = swapcrypto.SHA256.GenerateSecretWithEntropy(*secretSizeFlag, entropy)
+	} else {
+		secret, secretHash, err = swapcrypto.SHA256.GenerateSecretOfSize(*secretSizeFlag)
+	}
+	if err == nil {
+		registerSecret(hex.EncodeToString(secret))
 	}
-	err = setHoldingAccountSigningOptions(holdingAccountKeyPair, counterPartyAddress, secretHash, refundTransactionHash[:], targetNetwork, client)
-
 	return
 }
+
 func (cmd *initiateCmd) runCommand(client horizonclient.ClientInterface) error {
-	var secret [secretSize]byte
-	_, err := rand.Read(secret[:])
-	if err != nil {
+	var secret, secretHash []byte
+	var err error
+	if cmd.secret != nil {
+		secret, secretHash = cmd.secret, swapcrypto.SHA256.Sum(cmd.secret)
+		if err = checkSecretUnused(secretHash); err != nil {
+			swapcrypto.Zero(secret)
+			return err
+		}
+	} else if secret, secretHash, err = generateSecret(); err != nil {
 		return err
 	}
-	secretHash := sha256Hash(secret[:])
+	defer swapcrypto.Zero(secret)
+	//Encrypt before anything is created so a failure can not lose the secret of a funded holding account
+	escrow := ""
+	if *mediatorFlag != "" {
+		if escrow, err = stellar.EncryptSecret(secret, *mediatorFlag); err != nil {
+			return fmt.Errorf("Failed to encrypt the secret to the mediator: %v", err)
+		}
+	}
 	fundingAccountAddress := cmd.InitiatorKeyPair.Address()
-	holdingAccountKeyPair, err := stellar.GenerateKeyPair()
+	holdingAccountKeyPair, err := newHoldingKeyPair(cmd.InitiatorKeyPair, secretHash, cmd.cp2Addr)
 	if err != nil {
-		return fmt.Errorf("Failed to create holding account keypair: %s", err)
+		return err
 	}
 	holdingAccountAddress := holdingAccountKeyPair.Address()
-	//TODO: print the holding account private key in case of an error further down this function
-	//to recover the funds
 
 	locktime := time.Now().Add(timings.LockTime)
-	refundTransaction, err := createAtomicSwapHoldingAccount(cmd.InitiatorKeyPair, holdingAccountKeyPair, cmd.cp2Addr, cmd.amount, secretHash, locktime, cmd.asset, client)
+	record := newSwapRecord("initiator", holdingAccountKeyPair, fundingAccountAddress, cmd.cp2Addr, cmd.amount, cmd.asset, secret, secretHash, locktime, targetNetwork)
+	refundTransaction, err := createAtomicSwapHoldingAccount(cmd.InitiatorKeyPair, holdingAccountKeyPair, cmd.cp2Addr, cmd.amount, secretHash, locktime, cmd.asset, record, &cmd.setup, targetNetwork, client)
 	if err != nil {
-		return err
+		return cmd.setup.wrap(err)
 	}
 
 	serializedRefundTx, err := refundTransaction.Base64()
 	if err != nil {
 		return err
 	}
+	accounting := getSwapAccounting(holdingAccountAddress, cmd.amount, cmd.asset, cmd.cp2Addr, refundTransaction, client)
+	var files map[string]string
+	if *outDirFlag != "" {
+		files, err = writeSwapFiles(*outDirFlag, holdingAccountAddress, map[string]string{
+			"secret":            fmt.Sprintf("%x", secret),
+			"secrethash":        fmt.Sprintf("%x", secretHash),
+			"refundtransaction": serializedRefundTx,
+		})
+		//The holding account is funded, print the details rather than losing the secret
+		if err != nil {
+			logger.Errorf("%v, printing the swap details instead", err)
+		}
+	}
+	shownSecret, shownRefundTx := fmt.Sprintf("%x", secret), serializedRefundTx
+	if files != nil {
+		shownSecret, shownRefundTx = "", ""
+	}
 	if !*automatedFlag {
-		fmt.Printf("Secret:      %x\n", secret)
+		if files != nil {
+			printSwapFiles(files)
+		} else {
+			fmt.Printf("Secret:      %x\n", secret)
+		}
 		fmt.Printf("Secret hash: %x\n\n", secretHash)
 		fmt.Printf("initiator address: %s\n", fundingAccountAddress)
 		fmt.Printf("holding account address: %s\n", holdingAccountAddress)
-		fmt.Printf("refund transaction:\n%s\n", serializedRefundTx)
+		if files == nil {
+			fmt.Printf("refund transaction:\n%s\n", serializedRefundTx)
+		}
+		if escrow != "" {
+			fmt.Printf("secret escrow for mediator %s:\n%s\n", *mediatorFlag, escrow)
+		}
+		printSwapAccounting(accounting)
 	} else {
 		output := struct {
-			Secret                string `json:"secret"`
-			SecretHash            string `json:"hash"`
-			InitiatorAddress      string `json:"initiator"`
-			HoldingAccountAddress string `json:"holdingaccount"`
-			RefundTransaction     string `json:"refundtransaction"`
-		}{fmt.Sprintf("%x", secret),
+			Secret                string            `json:"secret,omitempty"`
+			SecretHash            string            `json:"hash"`
+			InitiatorAddress      string            `json:"initiator"`
+			HoldingAccountAddress string            `json:"holdingaccount"`
+			RefundTransaction     string            `json:"refundtransaction,omitempty"`
+			Escrow                string            `json:"escrow,omitempty"`
+			Accounting            *swapAccounting   `json:"accounting,omitempty"`
+			Files                 map[string]string `json:"files,omitempty"`
+		}{shownSecret,
 			fmt.Sprintf("%x", secretHash),
 			fundingAccountAddress,
 			holdingAccountAddress,
-			serializedRefundTx,
+			shownRefundTx,
+			escrow,
+			accounting,
+			files,
 		}
 		jsonoutput, _ := json.Marshal(output)
 		fmt.Println(string(jsonoutput))
 	}
+	//Only export after printing so a failing export does not hide the swap details
+	if *refundFileFlag != "" {
+		return exportRefundTransaction(*refundFileFlag, refundTransaction)
+	}
 	return nil
 }
 
 func (cmd *participateCmd) runCommand(client horizonclient.ClientInterface) error {
 
 	fundingAccountAddress := cmd.participatorKeyPair.Address()
-	holdingAccountKeyPair, err := stellar.GenerateKeyPair()
+	holdingAccountKeyPair, err := newHoldingKeyPair(cmd.participatorKeyPair, cmd.secretHash, cmd.cp1Addr)
 	if err != nil {
-		return fmt.Errorf("Failed to create holding account keypair: %s", err)
+		return err
 	}
 	holdingAccountAddress := holdingAccountKeyPair.Address()
-	//TODO: print the holding account private key in case of an error further down this function
-	//to recover the funds
 
 	locktime := time.Now().Add(timings.LockTime / 2)
-	refundTransaction, err := createAtomicSwapHoldingAccount(cmd.participatorKeyPair, holdingAccountKeyPair, cmd.cp1Addr, cmd.amount, cmd.secretHash, locktime, cmd.asset, client)
+	record := newSwapRecord("participant", holdingAccountKeyPair, fundingAccountAddress, cmd.cp1Addr, cmd.amount, cmd.asset, nil, cmd.secretHash, locktime, targetNetwork)
+	refundTransaction, err := createAtomicSwapHoldingAccount(cmd.participatorKeyPair, holdingAccountKeyPair, cmd.cp1Addr, cmd.amount, cmd.secretHash, locktime, cmd.asset, record, &cmd.setup, targetNetwork, client)
 	if err != nil {
-		return err
+		return cmd.setup.wrap(err)
 	}
 
 	serializedRefundTx, err := refundTransaction.Base64()
 	if err != nil {
 		return err
 	}
+	accounting := getSwapAccounting(holdingAccountAddress, cmd.amount, cmd.asset, cmd.cp1Addr, refundTransaction, client)
+	var files map[string]string
+	if *outDirFlag != "" {
+		files, err = writeSwapFiles(*outDirFlag, holdingAccountAddress, map[string]string{
+			"secrethash":        fmt.Sprintf("%x", cmd.secretHash),
+			"refundtransaction": serializedRefundTx,
+		})
+		if err != nil {
+			logger.Errorf("%v, printing the swap details instead", err)
+		}
+	}
+	shownRefundTx := serializedRefundTx
+	if files != nil {
+		shownRefundTx = ""
+	}
 	if !*automatedFlag {
 		fmt.Printf("participant address: %s\n", fundingAccountAddress)
 		fmt.Printf("holding account address: %s\n", holdingAccountAddress)
-		fmt.Printf("refund transaction:\n%s\n", serializedRefundTx)
+		if files != nil {
+			printSwapFiles(files)
+		} else {
+			fmt.Printf("refund transaction:\n%s\n", serializedRefundTx)
+		}
+		printSwapAccounting(accounting)
 	} else {
 
 		output := struct {
-			InitiatorAddress      string `json:"partcipant"`
-			HoldingAccountAddress string `json:"holdingaccount"`
-			RefundTransaction     string `json:"refundtransaction"`
+			InitiatorAddress      string            `json:"partcipant"`
+			HoldingAccountAddress string            `json:"holdingaccount"`
+			RefundTransaction     string            `json:"refundtransaction,omitempty"`
+			Accounting            *swapAccounting   `json:"accounting,omitempty"`
+			Files                 map[string]string `json:"files,omitempty"`
 		}{
 			fundingAccountAddress,
 			holdingAccountAddress,
-			serializedRefundTx,
+			shownRefundTx,
+			accounting,
+			files,
 		}
 		jsonoutput, _ := json.Marshal(output)
 		fmt.Println(string(jsonoutput))
 	}
+	if *refundFileFlag != "" {
+		return exportRefundTransaction(*refundFileFlag, refundTransaction)
+	}
 	return nil
 }
 
-func (cmd *auditContractCmd) runCommand(client horizonclient.ClientInterface) error {
-	holdingAccount, err := client.AccountDetail(horizonclient.AccountRequest{AccountID: cmd.holdingAccountAdress})
+//contractAudit is what auditHoldingAccount verified of a swap contract
+type contractAudit struct {
+	holdingAccount   hprotocol.Account
+	recipientAddress string
+	refundAddress    string
+	secretHash       []byte
+	lockTime         int64
+	refundTxHash     [32]byte
+	//redFlags are unusual settings of the holding account that do not prevent the swap
+	redFlags []string
+}
+
+//auditHoldingAccount verifies the signing conditions of the holding account against the refund transaction
+func auditHoldingAccount(holdingAccountAddress string, refundTx *txnbuild.Transaction, networkPassphrase string, client horizonclient.ClientInterface) (audit contractAudit, err error) {
+	holdingAccount, err := waitForAccountDetail(holdingAccountAddress, *waitFlag, client)
 	if err != nil {
-		return fmt.Errorf("Error getting the holding account details: %v", err)
-	}
-	if err != nil {
-		return err
+		return audit, fmt.Errorf("Error getting the holding account details: %v", err)
 	}
+	return auditHoldingAccountState(holdingAccount, refundTx, networkPassphrase)
+}
+
+//auditHoldingAccountState verifies the signing conditions of a holding account in the given state against the refund transaction
+func auditHoldingAccountState(holdingAccount hprotocol.Account, refundTx *txnbuild.Transaction, networkPassphrase string) (audit contractAudit, err error) {
 	//Check if the signing tresholds are correct
 	if holdingAccount.Thresholds.HighThreshold != 2 || holdingAccount.Thresholds.MedThreshold != 2 || holdingAccount.Thresholds.LowThreshold != 2 {
-		return fmt.Errorf("Holding account signing tresholds are wrong.\nTresholds: High: %d, Medium: %d, Low: %d", holdingAccount.Thresholds.HighThreshold, holdingAccount.Thresholds.MedThreshold, holdingAccount.Thresholds.LowThreshold)
+		return audit, fmt.Errorf("Holding account signing tresholds are wrong.\nTresholds: High: %d, Medium: %d, Low: %d", holdingAccount.Thresholds.HighThreshold, holdingAccount.Thresholds.MedThreshold, holdingAccount.Thresholds.LowThreshold)
+	}
+	//An issuer's authorization flags have no place on a holding account, and an immutable account can not be merged
+	if flags := holdingAccount.Flags; flags.AuthRequired || flags.AuthRevocable || flags.AuthImmutable {
+		return audit, fmt.Errorf("Holding account has authorization flags set.\nRequired: %v, Revocable: %v, Immutable: %v", flags.AuthRequired, flags.AuthRevocable, flags.AuthImmutable)
 	}
 	//Get the signing conditions
 	var refundTxHashFromSigningConditions []byte
@@ -640,89 +1456,158 @@ func (cmd *auditContractCmd) runCommand(client horizonclient.ClientInterface) er
 		switch signer.Type {
 		case hprotocol.KeyTypeNames[strkey.VersionByteAccountID]:
 			if recipientAddress != "" {
-				return fmt.Errorf("Multiple recipients as signer: %s and %s", recipientAddress, signer.Key)
+				return audit, fmt.Errorf("Multiple recipients as signer: %s and %s", recipientAddress, signer.Key)
 			}
 			recipientAddress = signer.Key
 			if signer.Weight != 1 {
-				return fmt.Errorf("Signing weight of the recipient is wrong. Recipient: %s Weight: %d", signer.Key, signer.Weight)
+				return audit, fmt.Errorf("Signing weight of the recipient is wrong. Recipient: %s Weight: %d", signer.Key, signer.Weight)
 			}
 		case hprotocol.KeyTypeNames[strkey.VersionByteHashTx]:
 			if refundTxHashFromSigningConditions != nil {
-				return errors.New("Multiple refund transaction hashes as signer")
+				return audit, errors.New("Multiple refund transaction hashes as signer")
 			}
 
 			refundTxHashFromSigningConditions, err = strkey.Decode(strkey.VersionByteHashTx, signer.Key)
 			if err != nil {
-				return fmt.Errorf("Faulty encoded refund transaction hash: %s", err)
+				return audit, fmt.Errorf("Faulty encoded refund transaction hash: %s", err)
 			}
 			if signer.Weight != 2 {
-				return fmt.Errorf("Signing weight of the refund transaction is wrong. Weight: %d", signer.Weight)
+				return audit, fmt.Errorf("Signing weight of the refund transaction is wrong. Weight: %d", signer.Weight)
 			}
 
 		case hprotocol.KeyTypeNames[strkey.VersionByteHashX]:
 			if secretHash != nil {
-				return fmt.Errorf("Multiple secret hashes  transaction hashes as signer: %s and %s", secretHash, signer.Key)
+				return audit, fmt.Errorf("Multiple secret hashes  transaction hashes as signer: %s and %s", secretHash, signer.Key)
 			}
 			secretHash, err = strkey.Decode(strkey.VersionByteHashX, signer.Key)
 			if err != nil {
-				return fmt.Errorf("Faulty encoded secret hash: %s", err)
+				return audit, fmt.Errorf("Faulty encoded secret hash: %s", err)
 			}
 			if signer.Weight != 1 {
-				return fmt.Errorf("Signing weight of the secret hash is wrong. Weight: %d", signer.Weight)
+				return audit, fmt.Errorf("Signing weight of the secret hash is wrong. Weight: %d", signer.Weight)
 			}
 		default:
-			return fmt.Errorf("Unexpected signer type: %s", signer.Type)
+			return audit, fmt.Errorf("Unexpected signer type: %s", signer.Type)
 		}
 	}
 	//Make sure all signing conditions are present
 	if refundTxHashFromSigningConditions == nil {
-		return errors.New("Missing refund transaction hash as signer")
+		return audit, errors.New("Missing refund transaction hash as signer")
 	}
 	if secretHash == nil {
-		return errors.New("Missing secret as signer")
+		return audit, errors.New("Missing secret as signer")
 	}
 	if recipientAddress == "" {
-		return errors.New("Missing recipient as signer")
+		return audit, errors.New("Missing recipient as signer")
 	}
 	//Compare the refund transaction hash in the signing condition to the one of the passed refund transaction
-	cmd.refundTx.Network = targetNetwork
-	refundTxHash, err := cmd.refundTx.Hash()
+	refundTx.Network = networkPassphrase
+	refundTxHash, err := refundTx.Hash()
 	if err != nil {
-		return fmt.Errorf("Unable to hash the passed refund transaction: %v", err)
+		return audit, fmt.Errorf("Unable to hash the passed refund transaction: %v", err)
 	}
 	if !bytes.Equal(refundTxHashFromSigningConditions, refundTxHash[:]) {
-		return errors.New("Refund transaction hash in the signing condition is not equal to the one of the passed refund transaction")
+		if builtFor := refundTransactionNetwork(refundTx, refundTxHashFromSigningConditions); builtFor != "" {
+			return audit, fmt.Errorf("The refund transaction was built for the %s network instead of the %s network, it can never be submitted", networkName(builtFor), networkName(networkPassphrase))
+		}
+		return audit, errors.New("Refund transaction hash in the signing condition is not equal to the one of the passed refund transaction")
 	}
 	//and finally get the locktime and refund address
-	lockTime := cmd.refundTx.Timebounds.MinTime
-	if len(cmd.refundTx.Operations) != 1 {
-		return fmt.Errorf("Refund transaction is expected to have 1 operation instead of %d", len(cmd.refundTx.Operations))
+	lockTime := refundTx.Timebounds.MinTime
+	refundAddress, err := auditRefundOperations(holdingAccount, refundTx)
+	if err != nil {
+		return audit, err
 	}
-	refundoperation := cmd.refundTx.Operations[0]
-	accountMergeOperation, ok := cmd.refundTx.Operations[0].(*txnbuild.AccountMerge)
-	if !ok {
-		return fmt.Errorf("Expecting an accountmerge operation in the refund transaction but got a %v", reflect.TypeOf(refundoperation))
+	audit = contractAudit{
+		holdingAccount:   holdingAccount,
+		recipientAddress: recipientAddress,
+		refundAddress:    refundAddress,
+		secretHash:       secretHash,
+		lockTime:         lockTime,
+		refundTxHash:     refundTxHash,
+		redFlags:         holdingAccountRedFlags(holdingAccount),
 	}
-	if accountMergeOperation.SourceAccount.GetAccountID() != cmd.holdingAccountAdress {
-		return fmt.Errorf("The refund transaction does not refund from the holding account but from %v", accountMergeOperation.SourceAccount.GetAccountID())
+	return
+}
+
+//checkHoldingAccountAssets verifies the holding account only has a trustline to the swapped asset, with the exact code and issuer.
+//An asset with the same code from another issuer is worthless to the recipient, so the issuer is what matters.
+func checkHoldingAccountAssets(audit contractAudit, asset txnbuild.Asset) error {
+	found := asset.IsNative()
+	for _, balance := range audit.holdingAccount.Balances {
+		if balance.Asset.Type == stellar.NativeAssetType {
+			continue
+		}
+		if asset.IsNative() || balance.Code != asset.GetCode() || balance.Issuer != asset.GetIssuer() {
+			return fmt.Errorf("The holding account has an unexpected trustline to %s:%s, set -asset to the negotiated asset", balance.Code, balance.Issuer)
+		}
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("The holding account has no trustline to %s", assetName(asset))
+	}
+	return nil
+}
+
+func (cmd *auditContractCmd) runCommand(client horizonclient.ClientInterface) error {
+	audit, err := auditHoldingAccount(cmd.holdingAccountAdress, &cmd.refundTx, targetNetwork, client)
+	if err != nil {
+		return err
+	}
+	if err = cmd.printAudit(audit); err != nil {
+		return err
+	}
+	if *reportFlag != "" {
+		report, err := newAuditReport(audit.holdingAccount, client)
+		if err != nil {
+			return err
+		}
+		report.RecipientAddress = audit.recipientAddress
+		report.RefundAddress = audit.refundAddress
+		report.SecretHash = fmt.Sprintf("%x", audit.secretHash)
+		report.Locktime = audit.lockTime
+		report.RefundTxHash = fmt.Sprintf("%x", audit.refundTxHash)
+		report.RedFlags = audit.redFlags
+		if report.RefundTx, err = cmd.refundTx.Base64(); err != nil {
+			return err
+		}
+		return writeAuditReport(*reportFlag, report)
+	}
+	return nil
+}
+
+//printAudit checks the audited contract against the negotiated terms and prints it
+func (cmd *auditContractCmd) printAudit(audit contractAudit) (err error) {
+	if err = checkHoldingAccountAssets(audit, cmd.asset); err != nil {
+		return err
+	}
+	if cmd.refundAddress != "" && audit.refundAddress != cmd.refundAddress {
+		return fmt.Errorf("The refund transaction returns the funds to %s instead of %s", audit.refundAddress, cmd.refundAddress)
+	}
+	if cmd.expectedAmount != "" {
+		if err = checkHoldingAccountBalance(audit, assetName(cmd.asset), cmd.expectedAmount); err != nil {
+			return err
+		}
 	}
-	refundAddress := accountMergeOperation.Destination
 	if !*automatedFlag {
 		fmt.Printf("Contract address:        %v\n", cmd.holdingAccountAdress)
 		fmt.Println("Contract value:")
-		for _, balance := range holdingAccount.Balances {
+		for _, balance := range audit.holdingAccount.Balances {
 			if balance.Asset.Type == stellar.NativeAssetType {
 				fmt.Printf("Amount: %s XLM\n", balance.Balance)
 			} else {
 				fmt.Printf("Amount: %s Code: %s Issuer: %s\n", balance.Balance, balance.Code, balance.Issuer)
 			}
 		}
-		fmt.Printf("Recipient address:       %v\n", recipientAddress)
-		fmt.Printf("Refund address: %v\n\n", refundAddress)
+		for _, redFlag := range audit.redFlags {
+			fmt.Printf("Warning: %s\n", redFlag)
+		}
+		fmt.Printf("Recipient address:       %v\n", audit.recipientAddress)
+		fmt.Printf("Refund address: %v\n\n", audit.refundAddress)
 
-		fmt.Printf("Secret hash: %x\n\n", secretHash)
+		fmt.Printf("Secret hash: %x\n\n", audit.secretHash)
 
-		t := time.Unix(lockTime, 0)
+		t := time.Unix(audit.lockTime, 0)
 		fmt.Printf("Locktime: %v\n", t.UTC())
 		reachedAt := time.Until(t).Truncate(time.Second)
 		if reachedAt > 0 {
@@ -732,21 +1617,23 @@ func (cmd *auditContractCmd) runCommand(client horizonclient.ClientInterface) er
 		}
 	} else {
 		output := struct {
-			ContractAddress  string `json:"contractAddress"`
-			ContractValue    string `json:"contractValue"`
-			RecipientAddress string `json:"recipientAddress"`
-			RefundAddress    string `json:"refundAddress"`
-			SecretHash       string `json:"secretHash"`
-			Locktime         string `json:"Locktime"`
+			ContractAddress  string   `json:"contractAddress"`
+			ContractValue    string   `json:"contractValue"`
+			RecipientAddress string   `json:"recipientAddress"`
+			RefundAddress    string   `json:"refundAddress"`
+			SecretHash       string   `json:"secretHash"`
+			Locktime         string   `json:"Locktime"`
+			RedFlags         []string `json:"redflags,omitempty"`
 		}{
 			fmt.Sprintf("%v", cmd.holdingAccountAdress),
 			"", //TODO: json output for balances
-			recipientAddress,
-			refundAddress,
-			fmt.Sprintf("%x", secretHash),
+			audit.recipientAddress,
+			audit.refundAddress,
+			fmt.Sprintf("%x", audit.secretHash),
 			"",
+			audit.redFlags,
 		}
-		t := time.Unix(lockTime, 0)
+		t := time.Unix(audit.lockTime, 0)
 		output.Locktime = fmt.Sprintf("%v", t.UTC())
 		jsonoutput, _ := json.Marshal(output)
 		fmt.Println(string(jsonoutput))
@@ -759,16 +1646,61 @@ func (cmd *refundCmd) runCommand(client horizonclient.ClientInterface) error {
 	if err != nil {
 		return err
 	}
+	if cmd.sep7 {
+		return printSep7URI(txe, "", "Refund atomic swap holding account "+refundedHoldingAccount(&cmd.refundTx))
+	}
+	if err = checkNotMerged(refundedHoldingAccount(&cmd.refundTx), client); err != nil {
+		return err
+	}
+	if locktime := cmd.refundTx.Timebounds.MinTime; locktime != 0 {
+		if *waitLocktimeFlag > 0 {
+			err = waitForLocktime(time.Unix(locktime, 0), *waitLocktimeFlag, client)
+		} else {
+			err = checkLocktimePassed(time.Unix(locktime, 0), client)
+		}
+		if err != nil {
+			return err
+		}
+	}
 	result, err := stellar.SubmitTransaction(txe, client)
 	if err != nil {
 		return err
 	}
+	recordRefund(refundedHoldingAccount(&cmd.refundTx), result.Hash)
+	received := getReceivedAmounts(result.Hash, refundedAddress(&cmd.refundTx), client)
 	if !*automatedFlag {
 		fmt.Println(result.TransactionSuccessToString())
+		printReceivedAmounts(received)
+	} else {
+		output := struct {
+			RefundTransactionTxHash string                   `json:"refundTransaction"`
+			Received                []stellar.CreditedAmount `json:"received,omitempty"`
+		}{
+			result.Hash,
+			received,
+		}
+		jsonoutput, _ := json.Marshal(output)
+		fmt.Println(string(jsonoutput))
 	}
 	return nil
 }
 
+//getReceivedAmounts looks up what the merge of the holding account really credited to the receiver.
+//The transaction already succeeded at this point so a failing lookup is only reported.
+func getReceivedAmounts(transactionHash string, receiverAddress string, client horizonclient.ClientInterface) []stellar.CreditedAmount {
+	received, err := stellar.GetCreditedAmounts(transactionHash, receiverAddress, client)
+	if err != nil {
+		logger.WithField("transaction", transactionHash).Warnf("Failed to get the received amounts: %v", err)
+	}
+	return received
+}
+
+func printReceivedAmounts(received []stellar.CreditedAmount) {
+	for _, amount := range received {
+		fmt.Printf("Received: %s %s\n", amount.Amount, amount.Asset)
+	}
+}
+
 func createRedeemOperations(holdingAccount *horizon.Account, receiverAddress string) (redeemOperations []txnbuild.Operation) {
 	redeemOperations = make([]txnbuild.Operation, 0, len(holdingAccount.Balances))
 	for _, balance := range holdingAccount.Balances {
@@ -791,6 +1723,19 @@ func createRedeemOperations(holdingAccount *horizon.Account, receiverAddress str
 		}
 		redeemOperations = append(redeemOperations, &removetrust)
 	}
+	//Data entries are subentries and block the merge
+	dataNames := make([]string, 0, len(holdingAccount.Data))
+	for name := range holdingAccount.Data {
+		dataNames = append(dataNames, name)
+	}
+	sort.Strings(dataNames)
+	for _, name := range dataNames {
+		removeData := txnbuild.ManageData{
+			Name:          name,
+			SourceAccount: holdingAccount,
+		}
+		redeemOperations = append(redeemOperations, &removeData)
+	}
 
 	mergeAccountOperation := txnbuild.AccountMerge{
 		Destination:   receiverAddress,
@@ -801,51 +1746,94 @@ func createRedeemOperations(holdingAccount *horizon.Account, receiverAddress str
 	return
 }
 
-func (cmd *redeemCmd) runCommand(client horizonclient.ClientInterface) error {
-	holdingAccount, err := stellar.GetAccount(cmd.holdingAccountAddress, client)
+//checkSecretSigner verifies the secret unlocks the holding account, so a wrong secret is reported before horizon rejects the transaction
+func checkSecretSigner(holdingAccount *horizon.Account, secret []byte) error {
+	secretHash := swapcrypto.SHA256.Sum(secret)
+	for _, signer := range holdingAccount.Signers {
+		if signer.Type != hprotocol.KeyTypeNames[strkey.VersionByteHashX] || signer.Weight == 0 {
+			continue
+		}
+		signerHash, err := strkey.Decode(strkey.VersionByteHashX, signer.Key)
+		if err == nil && swapcrypto.Equal(signerHash, secretHash) {
+			return nil
+		}
+	}
+	return fmt.Errorf("The secret does not unlock holding account %s: no signer has its hash %x", holdingAccount.AccountID, secretHash)
+}
+
+//createRedeemTransaction creates the transaction merging the holding account to the receiver, signed with the secret
+func createRedeemTransaction(holdingAccount *horizon.Account, receiverAddress string, secret []byte, networkPassphrase string, client horizonclient.ClientInterface) (redeemTransaction txnbuild.Transaction, err error) {
+	if err = checkSecretSigner(holdingAccount, secret); err != nil {
+		return
+	}
+	operations, err := redeemDestinationOperations(holdingAccount, receiverAddress, client)
 	if err != nil {
-		return err
+		return
 	}
-	receiverAddress := cmd.ReceiverKeyPair.Address()
-	operations := createRedeemOperations(holdingAccount, receiverAddress)
+	operations = append(operations, createRedeemOperations(holdingAccount, receiverAddress)...)
+	baseFee := suggestBaseFee(client)
 
-	redeemTransaction := txnbuild.Transaction{
+	redeemTransaction = txnbuild.Transaction{
 		Timebounds:    txnbuild.NewTimebounds(int64(0), int64(0)),
 		Operations:    operations,
-		Network:       targetNetwork,
+		Network:       networkPassphrase,
 		SourceAccount: holdingAccount,
+		BaseFee:       baseFee,
 	}
 
 	err = redeemTransaction.Build()
 	if err != nil {
-		return fmt.Errorf("Unable to build the transaction: %v", err)
+		err = fmt.Errorf("Unable to build the transaction: %v", err)
+		return
 	}
-	err = redeemTransaction.SignHashX(cmd.secret)
+	err = redeemTransaction.SignHashX(secret)
 	if err != nil {
-		return fmt.Errorf("Unable to sign with the secret:%v", err)
+		err = fmt.Errorf("Unable to sign with the secret:%v", err)
 	}
-	err = redeemTransaction.Sign(cmd.ReceiverKeyPair)
+	return
+}
+
+func (cmd *redeemCmd) runCommand(client horizonclient.ClientInterface) error {
+	defer swapcrypto.Zero(cmd.secret)
+	if cmd.collectFile != "" {
+		return cmd.collectSignatures(client)
+	}
+	if cmd.sep7 {
+		return cmd.printSep7URI(client)
+	}
+	holdingAccount, err := stellar.GetAccount(cmd.holdingAccountAddress, client)
 	if err != nil {
-		return fmt.Errorf("Unable to sign with the receiver keypair:%v", err)
+		return err
 	}
-
-	txe, err := redeemTransaction.Base64()
+	redeemTransaction, err := createRedeemTransaction(holdingAccount, cmd.receiverAddress, cmd.secret, targetNetwork, client)
 	if err != nil {
-		return fmt.Errorf("Unable to encode the transaction: %v", err)
+		return err
+	}
+	txe, err := stellar.SignEncode(&redeemTransaction, cmd.ReceiverKeyPair)
+	if err != nil {
+		return fmt.Errorf("Unable to sign with the receiver keypair:%v", err)
 	}
 
 	txSuccess, err := stellar.SubmitTransaction(txe, client)
 	if err != nil {
 		return err
 	}
+	recordRedeem(cmd.holdingAccountAddress, cmd.secret, txSuccess.Hash, targetNetwork)
+	return printRedeemResult(txSuccess, cmd.receiverAddress, client)
+}
 
+func printRedeemResult(txSuccess hprotocol.TransactionSuccess, receiverAddress string, client horizonclient.ClientInterface) error {
+	received := getReceivedAmounts(txSuccess.Hash, receiverAddress, client)
 	if !*automatedFlag {
 		fmt.Println(txSuccess.TransactionSuccessToString())
+		printReceivedAmounts(received)
 	} else {
 		output := struct {
-			RedeemTransactionTxHash string `json:"redeemTransaction"`
+			RedeemTransactionTxHash string                   `json:"redeemTransaction"`
+			Received                []stellar.CreditedAmount `json:"received,omitempty"`
 		}{
 			fmt.Sprintf("%v", txSuccess.Hash),
+			received,
 		}
 		jsonoutput, _ := json.Marshal(output)
 		fmt.Println(string(jsonoutput))
@@ -854,38 +1842,95 @@ func (cmd *redeemCmd) runCommand(client horizonclient.ClientInterface) error {
 }
 
 func (cmd *extractSecretCmd) runCommand(client horizonclient.ClientInterface) error {
-	transactions, err := stellar.GetAccountDebitediTransactions(cmd.holdingAccountAdress, client)
+	extract := extractSecretTransaction
+	if cmd.follow {
+		extract = followSecret
+	}
+	extractedSecret, transaction, err := extract(cmd.holdingAccountAdress, cmd.secretHash, client)
+	if err != nil {
+		return err
+	}
+	printExtractedSecret(extractedSecret, transaction)
+	return nil
+}
+
+//printExtractedSecret prints the secret with the transaction that revealed it, so automation can drive the redeem on the other chain right away
+func printExtractedSecret(secret []byte, transaction hprotocol.Transaction) {
+	if *automatedFlag {
+		output := struct {
+			Secret      string `json:"secret"`
+			Transaction string `json:"transaction"`
+			Ledger      int32  `json:"ledger"`
+			ClosedAt    string `json:"closedat"`
+		}{fmt.Sprintf("%x", secret), transaction.Hash, transaction.Ledger, transaction.LedgerCloseTime.UTC().Format(time.RFC3339)}
+		jsonoutput, _ := json.Marshal(output)
+		fmt.Println(string(jsonoutput))
+		return
+	}
+	fmt.Printf("Extracted secret: %x\n", secret)
+	fmt.Printf("revealed by transaction %s in ledger %d at %v\n", transaction.Hash, transaction.Ledger, transaction.LedgerCloseTime.UTC())
+}
+
+//errNotRedeemed is returned by extractSecret when nothing was taken from the holding account yet
+var errNotRedeemed = errors.New("The holdingaccount has not been redeemed yet")
+
+//extractSecret finds the secret with the hex encoded secret hash in the signatures of the transactions that debited the holding account
+func extractSecret(holdingAccountAddress string, secretHash string, client horizonclient.ClientInterface) (extractedSecret []byte, err error) {
+	extractedSecret, _, err = extractSecretTransaction(holdingAccountAddress, secretHash, client)
+	return
+}
+
+//extractSecretTransaction is extractSecret that also returns the transaction that revealed the secret
+func extractSecretTransaction(holdingAccountAddress string, secretHash string, client horizonclient.ClientInterface) (extractedSecret []byte, transaction hprotocol.Transaction, err error) {
+	rawSecretHash, err := hex.DecodeString(secretHash)
+	if err != nil {
+		return nil, transaction, fmt.Errorf("Invalid secret hash: %v", err)
+	}
+	transactions, err := stellar.GetAccountDebitediTransactions(holdingAccountAddress, client)
 	if err != nil {
-		return fmt.Errorf("Error getting the transaction that debited the holdingAccount: %v", err)
+		return nil, transaction, fmt.Errorf("Error getting the transaction that debited the holdingAccount: %v", err)
 	}
 	if len(transactions) == 0 {
-		return errors.New("The holdingaccount has not been redeemed yet")
+		return nil, transaction, errNotRedeemed
+	}
+	for _, transaction = range transactions {
+		if extractedSecret = findSecret(transaction, rawSecretHash); extractedSecret != nil {
+			return
+		}
 	}
-	var extractedSecret []byte
-transactionsLoop:
-	for _, transaction := range transactions {
+	return nil, hprotocol.Transaction{}, errors.New("Unable to find the matching secret")
+}
 
-		for _, rawSignature := range transaction.Signatures {
+//findSecret returns the signature of the transaction that is the preimage of the secret hash, nil if there is none
+func findSecret(transaction hprotocol.Transaction, rawSecretHash []byte) []byte {
+	for _, rawSignature := range transaction.Signatures {
 
-			decodedSignature, err := base64.StdEncoding.DecodeString(rawSignature)
-			if err != nil {
-				return fmt.Errorf("Error base64 decoding signature :%v", err)
-			}
-			if len(decodedSignature) > xdr.Signature(decodedSignature).XDRMaxSize() {
-				continue // this is certainly not the secret we are looking for
-			}
-			signatureHash := sha256.Sum256(decodedSignature)
-			hexSignatureHash := fmt.Sprintf("%x", signatureHash)
-			if hexSignatureHash == cmd.secretHash {
-				extractedSecret = decodedSignature
-				break transactionsLoop
-			}
+		decodedSignature, err := base64.StdEncoding.DecodeString(rawSignature)
+		if err != nil {
+			//a malformed signature in one candidate should not hide the secret in another
+			logger.WithField("transaction", transaction.Hash).Warnf("Error base64 decoding signature :%v", err)
+			continue
+		}
+		if len(decodedSignature) > xdr.Signature(decodedSignature).XDRMaxSize() {
+			continue // this is certainly not the secret we are looking for
+		}
+		if swapcrypto.SHA256.Verify(decodedSignature, rawSecretHash) {
+			return decodedSignature
 		}
 	}
+	return nil
+}
+
+func (cmd *saveKeyCmd) runCommand(client horizonclient.ClientInterface) error {
+	return cmd.runOfflineCommand()
+}
 
-	if extractedSecret == nil {
-		return errors.New("Unable to find the matching secret")
+func (cmd *saveKeyCmd) runOfflineCommand() error {
+	if err := signer.SaveToKeyring(cmd.alias, cmd.seed); err != nil {
+		return err
+	}
+	if !*automatedFlag {
+		fmt.Printf("Seed saved in the keyring, use it as keyring:%s\n", cmd.alias)
 	}
-	fmt.Printf("Extracted secret: %x\n", extractedSecret)
 	return nil
 }
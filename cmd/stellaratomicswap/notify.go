@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+//notifyAttempts is the number of times an event is delivered before it is given up
+const notifyAttempts = 5
+
+//The events the daemon and the watchtower notify
+const (
+	eventInitiated       = "initiated"
+	eventParticipated    = "participated"
+	eventContractAudited = "contractaudited"
+	eventRedeemed        = "redeemed"
+	eventSecretExtracted = "secretextracted"
+	eventRefunded        = "refunded"
+	//eventLocktimeApproaching is sent once when the locktime of a locked swap is near
+	eventLocktimeApproaching = "locktimeapproaching"
+	//eventActionRequired is sent when an operator has to do something to complete a swap
+	eventActionRequired = "actionrequired"
+	//eventFeeAccountToppedUp is sent when swapd paid XLM to the fee account
+	eventFeeAccountToppedUp = "feeaccounttoppedup"
+	//eventFeeAccountLow is sent once when the fee account is low and can not be topped up
+	eventFeeAccountLow = "feeaccountlow"
+)
+
+//swapEvent is what is delivered to the notifiers
+type swapEvent struct {
+	Event   string      `json:"event"`
+	Time    time.Time   `json:"time"`
+	Network string      `json:"network"`
+	Data    interface{} `json:"data"`
+}
+
+//text formats the event for humans
+func (e swapEvent) text() string {
+	data, _ := json.MarshalIndent(e.Data, "", "  ")
+	return fmt.Sprintf("stellaratomicswap %s swap event: %s\n%s", e.Network, e.Event, data)
+}
+
+//notifier delivers an event to a single backend
+type notifier interface {
+	deliver(event swapEvent) error
+}
+
+//notifiers delivers the events to all configured backends, none is fine
+type notifiers []notifier
+
+//newNotifiers sets up the webhook and the comma separated backends in list.
+//Both can also be read from a file with @<file>, which swapd reads again when it reloads its configuration.
+func newNotifiers(webhookURL string, list string) (n notifiers, err error) {
+	if strings.HasPrefix(webhookURL, "@") {
+		if webhookURL, err = readArgument(webhookURL); err != nil {
+			return nil, fmt.Errorf("Failed to read the webhook url: %v", err)
+		}
+	}
+	if strings.HasPrefix(list, "@") {
+		if list, err = readArgument(list); err != nil {
+			return nil, fmt.Errorf("Failed to read the notification backends: %v", err)
+		}
+	}
+	if webhookURL != "" {
+		hook, err := newWebhook(webhookURL)
+		if err != nil {
+			return nil, err
+		}
+		n = append(n, hook)
+	}
+	for _, name := range strings.Split(list, ",") {
+		var backend notifier
+		switch strings.TrimSpace(name) {
+		case "":
+			continue
+		case "telegram":
+			backend, err = newTelegramNotifier()
+		case "email":
+			backend, err = newEmailNotifier()
+		default:
+			return nil, fmt.Errorf("Unknown notification backend %q", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		n = append(n, backend)
+	}
+	return
+}
+
+//notify delivers an event in the background, retrying with backoff when a backend is not reachable.
+//The data must not contain secrets, the backends are not trusted with funds.
+func (n notifiers) notify(event string, data interface{}) {
+	e := swapEvent{Event: event, Time: time.Now().UTC(), Network: networkName(targetNetwork), Data: data}
+	for _, backend := range n {
+		go func(backend notifier) {
+			backoff := time.Second
+			for attempt := 1; ; attempt++ {
+				err := backend.deliver(e)
+				if err == nil {
+					return
+				}
+				if attempt == notifyAttempts {
+					logger.WithField("event", event).Errorf("Failed to deliver the event, giving up: %v", err)
+					return
+				}
+				time.Sleep(backoff)
+				backoff *= 2
+			}
+		}(backend)
+	}
+}
+
+//telegramNotifier sends the events as messages of a telegram bot
+type telegramNotifier struct {
+	token  string
+	chatID string
+	client *http.Client
+}
+
+func newTelegramNotifier() (*telegramNotifier, error) {
+	token, chatID := os.Getenv("TELEGRAM_BOT_TOKEN"), os.Getenv("TELEGRAM_CHAT_ID")
+	if token == "" || chatID == "" {
+		return nil, errors.New("TELEGRAM_BOT_TOKEN and TELEGRAM_CHAT_ID are required for telegram notifications")
+	}
+	return &telegramNotifier{token: token, chatID: chatID, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+func (t *telegramNotifier) deliver(event swapEvent) error {
+	body, _ := json.Marshal(map[string]string{"chat_id": t.chatID, "text": event.text()})
+	response, err := t.client.Post("https://api.telegram.org/bot"+t.token+"/sendMessage", "application/json", bytes.NewReader(body))
+	if err != nil {
+		//the url in the error contains the bot token
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("Failed to reach telegram: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("Telegram answered %s", response.Status)
+	}
+	return nil
+}
+
+//emailNotifier mails the events through an SMTP server
+type emailNotifier struct {
+	address string
+	auth    smtp.Auth
+	from    string
+	to      []string
+}
+
+func newEmailNotifier() (*emailNotifier, error) {
+	address, from, to := os.Getenv("SMTP_ADDRESS"), os.Getenv("SMTP_FROM"), os.Getenv("SMTP_TO")
+	if address == "" || from == "" || to == "" {
+		return nil, errors.New("SMTP_ADDRESS, SMTP_FROM and SMTP_TO are required for email notifications")
+	}
+	e := &emailNotifier{address: address, from: from, to: strings.Split(to, ",")}
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		host := address
+		if i := strings.LastIndex(address, ":"); i >= 0 {
+			host = address[:i]
+		}
+		e.auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+	return e, nil
+}
+
+func (e *emailNotifier) deliver(event swapEvent) error {
+	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: stellaratomicswap %s\r\n\r\n%s\r\n", e.from, strings.Join(e.to, ", "), event.Event, event.text())
+	return smtp.SendMail(e.address, e.auth, e.from, e.to, []byte(message))
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+//writeSwapFiles writes the sensitive swap details to files only the user can read, one file per name,
+//and returns the paths by name. Existing files are never overwritten.
+func writeSwapFiles(dir string, holdingAccount string, contents map[string]string) (paths map[string]string, err error) {
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("Failed to create %s: %v", dir, err)
+	}
+	names := make([]string, 0, len(contents))
+	for name := range contents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	paths = make(map[string]string, len(contents))
+	for _, name := range names {
+		path := filepath.Join(dir, holdingAccount+"."+name)
+		if err = writeNewFile(path, []byte(contents[name]+"\n")); err != nil {
+			return nil, fmt.Errorf("Failed to write %s: %v", path, err)
+		}
+		paths[name] = path
+	}
+	return paths, nil
+}
+
+func writeNewFile(path string, content []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+//printSwapFiles prints where the swap details were written
+func printSwapFiles(paths map[string]string) {
+	names := make([]string, 0, len(paths))
+	for name := range paths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s written to %s\n", name, paths[name])
+	}
+}
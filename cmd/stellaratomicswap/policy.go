@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/stellar/go/amount"
+	"github.com/stellar/go/txnbuild"
+)
+
+//assetPolicy restricts the amounts that can be swapped of an asset
+type assetPolicy struct {
+	//Minimum amount of a swap, empty for no minimum
+	Minimum string `json:"minimum,omitempty"`
+	//Decimals is the maximum number of decimals of an amount, stellar itself allows 7
+	Decimals *int `json:"decimals,omitempty"`
+}
+
+//defaultAmountPolicies returns the policies that apply without a policy file.
+//The default native minimum covers the reserves of a holding account with its signers and the transaction fees,
+//a smaller swap would not even pay for its own setup.
+func defaultAmountPolicies() map[string]assetPolicy {
+	return map[string]assetPolicy{
+		"XLM": {Minimum: "3"},
+	}
+}
+
+//amountPolicies are the policies by asset, XLM for the native asset and code:issuer or code for other assets.
+//They are replaced as a whole when swapd reloads the policy file.
+var (
+	amountPolicies     = defaultAmountPolicies()
+	amountPoliciesLock sync.RWMutex
+)
+
+//loadAmountPolicies merges the policies in a json file over the default ones.
+//A field that is not set in the file keeps its default, so configuring the decimals of XLM keeps its minimum.
+//The policies in effect are only replaced when the whole file is valid.
+func loadAmountPolicies(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	policies := make(map[string]assetPolicy)
+	if err = json.Unmarshal(content, &policies); err != nil {
+		return fmt.Errorf("Failed to decode the policy file %s: %v", path, err)
+	}
+	for name, policy := range policies {
+		if policy.Minimum != "" {
+			if _, err = amount.ParseInt64(policy.Minimum); err != nil {
+				return fmt.Errorf("Invalid minimum for %s: %v", name, err)
+			}
+		}
+		if policy.Decimals != nil && (*policy.Decimals < 0 || *policy.Decimals > 7) {
+			return fmt.Errorf("Invalid number of decimals for %s, it should be between 0 and 7", name)
+		}
+	}
+	loaded := defaultAmountPolicies()
+	for name, policy := range policies {
+		merged := loaded[name]
+		if policy.Minimum != "" {
+			merged.Minimum = policy.Minimum
+		}
+		if policy.Decimals != nil {
+			merged.Decimals = policy.Decimals
+		}
+		loaded[name] = merged
+	}
+	amountPoliciesLock.Lock()
+	amountPolicies = loaded
+	amountPoliciesLock.Unlock()
+	return nil
+}
+
+//currentAmountPolicies returns a copy of the policies in effect
+func currentAmountPolicies() map[string]assetPolicy {
+	amountPoliciesLock.RLock()
+	defer amountPoliciesLock.RUnlock()
+	policies := make(map[string]assetPolicy, len(amountPolicies))
+	for name, policy := range amountPolicies {
+		policies[name] = policy
+	}
+	return policies
+}
+
+//policyForAsset returns the policy of the asset, an issuer specific one takes precedence over one for the code
+func policyForAsset(asset txnbuild.Asset) (name string, policy assetPolicy, found bool) {
+	amountPoliciesLock.RLock()
+	defer amountPoliciesLock.RUnlock()
+	creditAsset, ok := asset.(txnbuild.CreditAsset)
+	if !ok {
+		policy, found = amountPolicies["XLM"]
+		return "XLM", policy, found
+	}
+	name = creditAsset.Code + ":" + creditAsset.Issuer
+	if policy, found = amountPolicies[name]; found {
+		return
+	}
+	policy, found = amountPolicies[creditAsset.Code]
+	return creditAsset.Code, policy, found
+}
+
+//checkAmountPolicy verifies an amount to swap against the policy of the asset.
+//It is checked when a holding account is set up, for the commands as well as for the adapter.
+func checkAmountPolicy(swapAmount string, asset txnbuild.Asset) error {
+	value, err := amount.ParseInt64(swapAmount)
+	if err != nil {
+		return fmt.Errorf("Invalid amount %s: %v", swapAmount, err)
+	}
+	if value <= 0 {
+		return errors.New("The amount should be positive")
+	}
+	name, policy, found := policyForAsset(asset)
+	if !found {
+		return nil
+	}
+	if policy.Decimals != nil {
+		if parts := strings.SplitN(swapAmount, ".", 2); len(parts) == 2 && len(strings.TrimRight(parts[1], "0")) > *policy.Decimals {
+			return fmt.Errorf("%s amounts can have at most %d decimals", name, *policy.Decimals)
+		}
+	}
+	if policy.Minimum != "" {
+		minimum := amount.MustParse(policy.Minimum)
+		if value < int64(minimum) {
+			return fmt.Errorf("The amount of %s %s is below the minimum of %s %s", swapAmount, name, policy.Minimum, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/txnbuild"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAmountPolicies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "policy")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	defer func() { amountPolicies = defaultAmountPolicies() }()
+	issuer, _ := keypair.Random()
+	otherIssuer, _ := keypair.Random()
+	usdc := txnbuild.CreditAsset{Code: "USDC", Issuer: issuer.Address()}
+	otherUSDC := txnbuild.CreditAsset{Code: "USDC", Issuer: otherIssuer.Address()}
+	eur := txnbuild.CreditAsset{Code: "EUR", Issuer: issuer.Address()}
+
+	tests := []struct {
+		name   string
+		policy string
+		amount string
+		asset  txnbuild.Asset
+		valid  bool
+	}{
+		{"default XLM minimum", "", "3", txnbuild.NativeAsset{}, true},
+		{"below the default XLM minimum", "", "2.9999999", txnbuild.NativeAsset{}, false},
+		{"no policy for an asset", "", "0.0000001", eur, true},
+		{"not positive", "", "0", eur, false},
+		{"merged over the default", `{"USDC": {"minimum": "1", "decimals": 2}}`, "2.5", txnbuild.NativeAsset{}, false},
+		{"asset added to the default", `{"USDC": {"minimum": "1", "decimals": 2}}`, "0.5", usdc, false},
+		{"decimals only keeps the XLM minimum", `{"XLM": {"decimals": 2}}`, "2.5", txnbuild.NativeAsset{}, false},
+		{"decimals only", `{"XLM": {"decimals": 2}}`, "3.123", txnbuild.NativeAsset{}, false},
+		{"trailing zero decimals", `{"XLM": {"decimals": 2}}`, "3.1200000", txnbuild.NativeAsset{}, true},
+		{"zero decimals", `{"XLM": {"decimals": 0}}`, "3.0", txnbuild.NativeAsset{}, true},
+		{"minimum overridden", `{"XLM": {"minimum": "20"}}`, "10", txnbuild.NativeAsset{}, false},
+		{"code policy", `{"USDC": {"minimum": "1"}}`, "0.5", otherUSDC, false},
+		{"code:issuer over code", `{"USDC": {"minimum": "1"}, "USDC:` + issuer.Address() + `": {"minimum": "0.1"}}`, "0.5", usdc, true},
+		{"code for other issuers", `{"USDC": {"minimum": "1"}, "USDC:` + issuer.Address() + `": {"minimum": "0.1"}}`, "0.5", otherUSDC, false},
+	}
+	for _, test := range tests {
+		amountPolicies = defaultAmountPolicies()
+		if test.policy != "" {
+			path := filepath.Join(dir, "policy.json")
+			if !assert.NoError(t, ioutil.WriteFile(path, []byte(test.policy), 0600), test.name) ||
+				!assert.NoError(t, loadAmountPolicies(path), test.name) {
+				continue
+			}
+		}
+		err := checkAmountPolicy(test.amount, test.asset)
+		if test.valid {
+			assert.NoError(t, err, test.name)
+		} else {
+			assert.Error(t, err, test.name)
+		}
+	}
+
+	//an invalid file is refused without changing the policies in effect
+	amountPolicies = defaultAmountPolicies()
+	for _, policy := range []string{`{"XLM": {"minimum": "x"}}`, `{"XLM": {"decimals": 8}}`, `{"XLM": {"decimals": -1}}`, `[]`} {
+		path := filepath.Join(dir, "policy.json")
+		assert.NoError(t, ioutil.WriteFile(path, []byte(policy), 0600))
+		assert.Error(t, loadAmountPolicies(path), policy)
+		assert.Equal(t, defaultAmountPolicies(), currentAmountPolicies(), policy)
+	}
+	assert.Error(t, loadAmountPolicies(filepath.Join(dir, "missing.json")))
+}
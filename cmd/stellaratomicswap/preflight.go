@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/stellar/go/amount"
+	"github.com/stellar/go/clients/horizon"
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/txnbuild"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+)
+
+//holdingAccountSigners are the signers the signing options add to a holding account:
+//the counterparty, the secret hash and the refund transaction hash
+const holdingAccountSigners = 3
+
+//checkSetupFunds verifies before anything is submitted that the funding account can pay for the setup of a holding account
+//holding xlmAmount XLM and amount of asset, and that xlmAmount covers the minimum balance of the holding account once it is set up.
+//It fails with the exact shortfall instead of an op_underfunded or op_low_reserve halfway through the setup.
+func checkSetupFunds(fundingAccount *horizon.Account, xlmAmount string, swapAmount string, asset txnbuild.Asset, secretHash []byte, client horizonclient.ClientInterface) error {
+	ledger, err := latestLedger(client)
+	if err != nil {
+		return err
+	}
+	baseReserve := int64(ledger.BaseReserve)
+	baseFee := int64(suggestBaseFee(client))
+	holdingXLM, err := amount.ParseInt64(xlmAmount)
+	if err != nil {
+		return fmt.Errorf("Invalid amount %s: %v", xlmAmount, err)
+	}
+
+	holdingSubentries := holdingAccountSigners + len(holdingAccountDataEntries(secretHash))
+	if !asset.IsNative() {
+		holdingSubentries++
+	}
+	holdingNeeds := int64(2+holdingSubentries) * baseReserve
+	if holdingXLM < holdingNeeds {
+		return fmt.Errorf("The holding account needs %s XLM for its minimum balance but only receives %s XLM, %s XLM short",
+			amount.StringFromInt64(holdingNeeds), xlmAmount, amount.StringFromInt64(holdingNeeds-holdingXLM))
+	}
+
+	fundingNeeds := holdingXLM
+	if feeSigner == nil {
+		//the setup transaction is paid by the funder
+		fundingNeeds += int64(setupOperationCount(asset, secretHash)) * baseFee
+	}
+	var spendable int64
+	assetFound := asset.IsNative()
+	for _, balance := range fundingAccount.Balances {
+		if balance.Asset.Type == stellar.NativeAssetType {
+			if spendable, err = spendableBalance(balance); err != nil {
+				return err
+			}
+			spendable -= int64(2+fundingAccount.SubentryCount) * baseReserve
+			continue
+		}
+		if asset.IsNative() || balance.Code != asset.GetCode() || balance.Issuer != asset.GetIssuer() {
+			continue
+		}
+		assetFound = true
+		assetSpendable, err := spendableBalance(balance)
+		if err != nil {
+			return err
+		}
+		assetNeeds, err := amount.ParseInt64(swapAmount)
+		if err != nil {
+			return fmt.Errorf("Invalid amount %s: %v", swapAmount, err)
+		}
+		if assetSpendable < assetNeeds {
+			return fmt.Errorf("The funding account %s needs %s %s but only %s %s is spendable, %s %s short",
+				fundingAccount.AccountID, swapAmount, asset.GetCode(), amount.StringFromInt64(assetSpendable), asset.GetCode(), amount.StringFromInt64(assetNeeds-assetSpendable), asset.GetCode())
+		}
+	}
+	if !assetFound {
+		return fmt.Errorf("The funding account %s has no trustline to %s", fundingAccount.AccountID, assetName(asset))
+	}
+	if spendable < fundingNeeds {
+		return fmt.Errorf("The funding account %s needs %s XLM to set up the holding account but only %s XLM is spendable above its own minimum balance, %s XLM short",
+			fundingAccount.AccountID, amount.StringFromInt64(fundingNeeds), amount.StringFromInt64(spendable), amount.StringFromInt64(fundingNeeds-spendable))
+	}
+	return nil
+}
+
+//spendableBalance is the balance minus what is reserved for open sell offers
+func spendableBalance(balance horizon.Balance) (int64, error) {
+	total, err := amount.ParseInt64(balance.Balance)
+	if err != nil {
+		return 0, err
+	}
+	if balance.SellingLiabilities == "" {
+		return total, nil
+	}
+	liabilities, err := amount.ParseInt64(balance.SellingLiabilities)
+	if err != nil {
+		return 0, err
+	}
+	return total - liabilities, nil
+}
+
+//setupOperationCount returns the number of operations of the transaction setting up a holding account:
+//the account creation, for a non native asset the trustline and payment, the sequence bump, the data entries,
+//the home domain and the signers and their weights
+func setupOperationCount(asset txnbuild.Asset, secretHash []byte) int {
+	operations := 2 + len(holdingAccountDataEntries(secretHash)) + holdingAccountSigners + 1
+	if !asset.IsNative() {
+		operations += 2
+	}
+	if *homeDomainFlag != "" {
+		operations++
+	}
+	return operations
+}
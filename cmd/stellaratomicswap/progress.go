@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/keypair"
+)
+
+//The steps of setting up a holding account
+const (
+	stepAccountCreated  = "holdingaccountcreated"
+	stepAccountFunded   = "holdingaccountfunded"
+	stepRefundTxCreated = "refundtransactioncreated"
+	stepOptionsSet      = "signingoptionsset"
+)
+
+var errTimeout = errors.New("timeout exceeded")
+
+//setupProgress keeps track of the steps of a multi transaction command that are completed
+type setupProgress struct {
+	mu             sync.Mutex
+	holdingAccount string
+	holdingSeed    string
+	completed      []string
+	current        string
+}
+
+func (p *setupProgress) setHoldingAccount(holdingAccount *keypair.Full) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.holdingAccount = holdingAccount.Address()
+	p.holdingSeed = holdingAccount.Seed()
+}
+
+func (p *setupProgress) start(step string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = step
+}
+
+//done marks a step as completed, a step that is done again when the setup is retried is only recorded once
+func (p *setupProgress) done(step string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = ""
+	for _, completed := range p.completed {
+		if completed == step {
+			return
+		}
+	}
+	p.completed = append(p.completed, step)
+}
+
+//wrap returns err with the progress made so far, if any
+func (p *setupProgress) wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.completed) == 0 && p.current == "" {
+		return err
+	}
+	return &setupError{
+		HoldingAccount: p.holdingAccount,
+		HoldingSeed:    p.holdingSeed,
+		CompletedSteps: append([]string(nil), p.completed...),
+		PendingStep:    p.current,
+		err:            err,
+	}
+}
+
+//setupError is returned when a multi transaction command fails after some steps have been completed.
+//The holding account seed is not part of the message, which ends up in logs, it is printed separately by printSetupError.
+type setupError struct {
+	HoldingAccount string   `json:"holdingaccount,omitempty"`
+	HoldingSeed    string   `json:"holdingseed,omitempty"`
+	CompletedSteps []string `json:"completedsteps"`
+	PendingStep    string   `json:"pendingstep,omitempty"`
+	err            error
+}
+
+func (e *setupError) Error() string {
+	msg := fmt.Sprintf("%v\nholding account: %s\ncompleted steps: %s", e.err, e.HoldingAccount, strings.Join(e.CompletedSteps, ", "))
+	if e.PendingStep != "" {
+		msg = fmt.Sprintf("%s\nstep in progress, outcome unknown: %s", msg, e.PendingStep)
+	}
+	return msg
+}
+
+//printSetupError prints the holding account seed of a failed setup, it controls the holding account
+//as long as the signing options are not set, so the funds can be recovered with sweep.
+func printSetupError(err error) {
+	if se, ok := err.(*setupError); ok && se.HoldingSeed != "" {
+		fmt.Fprintf(os.Stderr, "holding account seed, to recover the funds with sweep: %s\n", se.HoldingSeed)
+	}
+}
+
+//progressCommand is a command that reports the progress of its steps
+type progressCommand interface {
+	command
+	progress() *setupProgress
+}
+
+//runCommand runs the command, aborting it when the timeout is exceeded.
+//A zero timeout means no timeout.
+func runCommand(cmd command, client horizonclient.ClientInterface, timeout time.Duration) error {
+	if timeout == 0 {
+		return cmd.runCommand(client)
+	}
+	result := make(chan error, 1)
+	go func() {
+		result <- cmd.runCommand(client)
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(timeout):
+		if pc, ok := cmd.(progressCommand); ok {
+			return pc.progress().wrap(errTimeout)
+		}
+		return errTimeout
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/stellar/go/amount"
+	"github.com/stellar/go/clients/horizon"
+	"github.com/stellar/go/clients/horizonclient"
+	hprotocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/txnbuild"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+)
+
+//redeemDestinationOperations returns the operations creating the receiver account in the redeem transaction when it does not exist yet,
+//an account merge into a missing account fails. The holding account pays the minimum balance of the new account from its XLM
+//and the receiver adds the trustlines for the swapped assets, so it has to sign the redeem transaction.
+func redeemDestinationOperations(holdingAccount *horizon.Account, receiverAddress string, client horizonclient.ClientInterface) ([]txnbuild.Operation, error) {
+	_, exists, err := getOptionalAccount(receiverAddress, client)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get the receiver account: %v", err)
+	}
+	if exists {
+		return nil, nil
+	}
+	if !*createDestinationFlag {
+		return nil, fmt.Errorf("The receiver account %s does not exist, fund it first or add -createdestination to create it from the holding account's XLM", receiverAddress)
+	}
+	latest, err := latestLedger(client)
+	if err != nil {
+		return nil, err
+	}
+	var trustlines []txnbuild.Operation
+	for _, balance := range holdingAccount.Balances {
+		if balance.Asset.Type == stellar.NativeAssetType {
+			continue
+		}
+		trustlines = append(trustlines, &txnbuild.ChangeTrust{
+			Line:          txnbuild.CreditAsset{Code: balance.Code, Issuer: balance.Issuer},
+			SourceAccount: &txnbuild.SimpleAccount{AccountID: receiverAddress},
+		})
+	}
+	startingBalance := int64(2+len(trustlines)) * int64(latest.BaseReserve)
+	operations := []txnbuild.Operation{&txnbuild.CreateAccount{
+		Destination:   receiverAddress,
+		Amount:        amount.StringFromInt64(startingBalance),
+		SourceAccount: holdingAccount,
+	}}
+	return append(operations, trustlines...), nil
+}
+
+//latestLedger returns the latest ledger horizon ingested
+func latestLedger(client horizonclient.ClientInterface) (ledger hprotocol.Ledger, err error) {
+	ledgers, err := client.Ledgers(horizonclient.LedgerRequest{Order: horizonclient.OrderDesc, Limit: 1})
+	if err != nil {
+		return ledger, fmt.Errorf("Failed to get the latest ledger: %v", err)
+	}
+	if len(ledgers.Embedded.Records) == 0 {
+		return ledger, errors.New("No ledgers found")
+	}
+	return ledgers.Embedded.Records[0], nil
+}
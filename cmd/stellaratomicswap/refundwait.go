@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/stellar/go/clients/horizonclient"
+)
+
+//ledgerCloseInterval is about the time between two ledgers
+const ledgerCloseInterval = 5 * time.Second
+
+//waitForLocktime waits at most wait until the refund transaction is valid.
+//Its minimum time is compared with the close time of the ledger it is included in, not with the local clock,
+//so it waits until the latest ledger closed after the locktime.
+func waitForLocktime(locktime time.Time, wait time.Duration, client horizonclient.ClientInterface) error {
+	deadline := time.Now().Add(wait)
+	for {
+		latest, err := latestLedger(client)
+		if err != nil {
+			return err
+		}
+		remaining := locktime.Sub(latest.ClosedAt)
+		if remaining <= 0 {
+			return nil
+		}
+		if time.Now().Add(remaining).After(deadline) {
+			return fmt.Errorf("The refund is only available in %v at %v, longer than -wait", remaining.Truncate(time.Second), locktime.UTC())
+		}
+		logger.Infof("Refund available in %v at %v", remaining.Truncate(time.Second), locktime.UTC())
+		interval := remaining
+		if interval > time.Minute {
+			interval = time.Minute
+		}
+		if interval < ledgerCloseInterval {
+			interval = ledgerCloseInterval
+		}
+		time.Sleep(interval)
+	}
+}
+
+//checkLocktimePassed fails with the time left when the refund transaction is not valid yet,
+//instead of horizon rejecting it with tx_too_early. If the latest ledger is not known, horizon decides.
+func checkLocktimePassed(locktime time.Time, client horizonclient.ClientInterface) error {
+	latest, err := latestLedger(client)
+	if err != nil {
+		logger.Debugf("Not checking the locktime before submitting the refund: %v", err)
+		return nil
+	}
+	if remaining := locktime.Sub(latest.ClosedAt); remaining > 0 {
+		return fmt.Errorf("The refund is only available in %v at %v, use -wait to wait for it", remaining.Truncate(time.Second), locktime.UTC())
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+//notify delivers an event to the notifiers currently configured
+func (cmd *swapdCmd) notify(event string, data interface{}) {
+	cmd.notifiersLock.RLock()
+	n := cmd.notifiers
+	cmd.notifiersLock.RUnlock()
+	n.notify(event, data)
+}
+
+//reloadOnHangup reloads the configuration every time the daemon receives a SIGHUP
+func (cmd *swapdCmd) reloadOnHangup() {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	for range hangup {
+		if err := cmd.reloadConfiguration(); err != nil {
+			logger.Errorf("Failed to reload the configuration, keeping the current one: %v", err)
+			continue
+		}
+		logger.Info("Reloaded the configuration")
+	}
+}
+
+//reloadConfiguration reads the policy file and the notifier targets again, without interrupting the running swaps and event streams.
+//Nothing is changed when either of them is invalid.
+func (cmd *swapdCmd) reloadConfiguration() error {
+	reloaded, err := newNotifiers(*webhookFlag, *notifyFlag)
+	if err != nil {
+		return fmt.Errorf("Failed to set up the notifiers: %v", err)
+	}
+	if *policyFlag != "" {
+		if err = loadAmountPolicies(*policyFlag); err != nil {
+			return err
+		}
+	}
+	cmd.notifiersLock.Lock()
+	cmd.notifiers = append(reloaded, cmd.events)
+	cmd.notifiersLock.Unlock()
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReloadConfiguration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reload")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	policyFile := filepath.Join(dir, "policy.json")
+	webhookFile := filepath.Join(dir, "webhook")
+	defer func(policy, webhook string) {
+		*policyFlag, *webhookFlag = policy, webhook
+		amountPolicies = defaultAmountPolicies()
+	}(*policyFlag, *webhookFlag)
+	*policyFlag, *webhookFlag = policyFile, "@"+webhookFile
+	os.Setenv(webhookSecretVariable, "secret")
+	defer os.Unsetenv(webhookSecretVariable)
+
+	cmd := &swapdCmd{events: newEventStream()}
+	assert.NoError(t, ioutil.WriteFile(policyFile, []byte(`{"XLM": {"minimum": "10"}}`), 0600))
+	assert.NoError(t, ioutil.WriteFile(webhookFile, []byte("https://example.com/hook\n"), 0600))
+	if assert.NoError(t, cmd.reloadConfiguration()) {
+		assert.Equal(t, "10", currentAmountPolicies()["XLM"].Minimum)
+		if assert.Len(t, cmd.notifiers, 2) {
+			assert.Equal(t, "https://example.com/hook", cmd.notifiers[0].(*webhook).url)
+			assert.Equal(t, cmd.events, cmd.notifiers[1])
+		}
+	}
+
+	//an entry removed from the policy file is back at its default after a reload
+	assert.NoError(t, ioutil.WriteFile(policyFile, []byte(`{}`), 0600))
+	assert.NoError(t, ioutil.WriteFile(webhookFile, nil, 0600))
+	if assert.NoError(t, cmd.reloadConfiguration()) {
+		assert.Equal(t, "3", currentAmountPolicies()["XLM"].Minimum)
+		assert.Len(t, cmd.notifiers, 1)
+	}
+
+	//an invalid policy file changes nothing
+	assert.NoError(t, ioutil.WriteFile(policyFile, []byte(`{"XLM": {"minimum": "x"}}`), 0600))
+	assert.NoError(t, ioutil.WriteFile(webhookFile, []byte("https://example.com/other"), 0600))
+	assert.Error(t, cmd.reloadConfiguration())
+	assert.Equal(t, "3", currentAmountPolicies()["XLM"].Minimum)
+	assert.Len(t, cmd.notifiers, 1)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/stellar/go/clients/horizonclient"
+	hprotocol "github.com/stellar/go/protocols/horizon"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+)
+
+//auditReport describes a verified swap contract for third parties like arbiters or compliance reviewers.
+//It only contains what is publicly visible on the ledger, never the secret or any seed.
+type auditReport struct {
+	Network          string              `json:"network"`
+	GeneratedAt      string              `json:"generatedat"`
+	ContractAddress  string              `json:"contractaddress"`
+	Balances         []reportBalance     `json:"balances"`
+	Thresholds       reportThresholds    `json:"thresholds"`
+	Signers          []reportSigner      `json:"signers"`
+	RecipientAddress string              `json:"recipientaddress"`
+	RefundAddress    string              `json:"refundaddress"`
+	SecretHash       string              `json:"secrethash"`
+	Locktime         int64               `json:"locktime"`
+	RefundTxHash     string              `json:"refundtxhash"`
+	RefundTx         string              `json:"refundtx"`
+	RedFlags         []string            `json:"redflags,omitempty"`
+	Transactions     []reportTransaction `json:"transactions"`
+}
+
+type reportBalance struct {
+	Asset   string `json:"asset"`
+	Balance string `json:"balance"`
+}
+
+type reportThresholds struct {
+	Low    byte `json:"low"`
+	Medium byte `json:"medium"`
+	High   byte `json:"high"`
+}
+
+type reportSigner struct {
+	Key    string `json:"key"`
+	Type   string `json:"type"`
+	Weight int32  `json:"weight"`
+}
+
+type reportTransaction struct {
+	Hash   string `json:"hash"`
+	Ledger int32  `json:"ledger"`
+	Time   string `json:"time"`
+}
+
+//newAuditReport fills in the holding account details and the transactions that set up the contract
+func newAuditReport(holdingAccount hprotocol.Account, client horizonclient.ClientInterface) (report auditReport, err error) {
+	report.Network = targetNetwork
+	report.GeneratedAt = time.Now().UTC().Format(time.RFC3339)
+	report.ContractAddress = holdingAccount.AccountID
+	for _, balance := range holdingAccount.Balances {
+		asset := "XLM"
+		if balance.Asset.Type != stellar.NativeAssetType {
+			asset = balance.Code + ":" + balance.Issuer
+		}
+		report.Balances = append(report.Balances, reportBalance{Asset: asset, Balance: balance.Balance})
+	}
+	report.Thresholds = reportThresholds{
+		Low:    holdingAccount.Thresholds.LowThreshold,
+		Medium: holdingAccount.Thresholds.MedThreshold,
+		High:   holdingAccount.Thresholds.HighThreshold,
+	}
+	for _, signer := range holdingAccount.Signers {
+		report.Signers = append(report.Signers, reportSigner{Key: signer.Key, Type: signer.Type, Weight: signer.Weight})
+	}
+	transactions, err := client.Transactions(horizonclient.TransactionRequest{ForAccount: holdingAccount.AccountID, Order: horizonclient.OrderAsc, Limit: 200})
+	for ; err == nil; transactions, err = client.NextTransactionsPage(transactions) {
+		for _, tx := range transactions.Embedded.Records {
+			report.Transactions = append(report.Transactions, reportTransaction{
+				Hash:   tx.Hash,
+				Ledger: tx.Ledger,
+				Time:   tx.LedgerCloseTime.UTC().Format(time.RFC3339),
+			})
+		}
+		if len(transactions.Embedded.Records) < 200 {
+			break
+		}
+	}
+	if err != nil {
+		err = fmt.Errorf("Failed to get the holding account transactions: %v", err)
+	}
+	return
+}
+
+func writeAuditReport(path string, report auditReport) error {
+	content, _ := json.MarshalIndent(report, "", "  ")
+	if err := ioutil.WriteFile(path, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("Failed to write the audit report: %v", err)
+	}
+	if !*automatedFlag {
+		fmt.Printf("Audit report written to %s\n", path)
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stellar/go/clients/horizon"
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/txnbuild"
+	"github.com/stellar/go/xdr"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/swapdb"
+)
+
+//setupRetries is the number of times the remaining steps of a setup are retried after a transient failure
+const setupRetries = 3
+
+//setupRetryDelay is the delay before the first retry, it doubles for every next one
+const setupRetryDelay = 5 * time.Second
+
+//resumeCmd finishes the setup of a holding account that was interrupted.
+//Every step first checks on the chain whether it was already done, so it can be run again safely.
+type resumeCmd struct {
+	fundingSigner  stellar.Signer
+	holdingAccount string
+}
+
+func (cmd *resumeCmd) runCommand(client horizonclient.ClientInterface) error {
+	if *swapDBFlag == "" {
+		return errors.New("The swap database is disabled, there is no recorded state to resume from")
+	}
+	var record swapdb.Swap
+	err := withSwapDB(func(db *swapdb.DB) (err error) {
+		record, err = db.Get(cmd.holdingAccount)
+		return
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to get swap %s: %v", cmd.holdingAccount, err)
+	}
+	if record.Status != swapdb.StatusSettingUp && record.Status != swapdb.StatusFailed {
+		return fmt.Errorf("The setup of swap %s is already complete, its status is %s", record.HoldingAccount, record.Status)
+	}
+	if record.Network != networkName(targetNetwork) {
+		return fmt.Errorf("Swap %s is on the %s network", record.HoldingAccount, record.Network)
+	}
+	if record.Funder != cmd.fundingSigner.Address() {
+		return fmt.Errorf("Swap %s is funded by %s, not by %s", record.HoldingAccount, record.Funder, cmd.fundingSigner.Address())
+	}
+	registerSecret(record.Secret, record.HoldingSeed)
+	holdingAccountKeyPair, err := keypair.Parse(record.HoldingSeed)
+	if err != nil {
+		return fmt.Errorf("The holding account seed of swap %s is not recorded", record.HoldingAccount)
+	}
+	holdingAccountFullKeyPair, ok := holdingAccountKeyPair.(*keypair.Full)
+	if !ok || holdingAccountFullKeyPair.Address() != record.HoldingAccount {
+		return fmt.Errorf("The holding account seed of swap %s is not recorded", record.HoldingAccount)
+	}
+	asset, err := parseAssetName(record.Asset)
+	if err != nil {
+		return err
+	}
+	secretHash, err := hex.DecodeString(record.SecretHash)
+	if err != nil {
+		return fmt.Errorf("Invalid secret hash recorded for swap %s: %v", record.HoldingAccount, err)
+	}
+
+	var progress setupProgress
+	progress.setHoldingAccount(holdingAccountFullKeyPair)
+	refundTransaction, err := resumeHoldingAccountSetup(cmd.fundingSigner, holdingAccountFullKeyPair, &record, asset, secretHash, &progress, targetNetwork, client)
+	if err != nil {
+		recordSetup(&record, &progress, nil, err)
+		return progress.wrap(err)
+	}
+	recordSetup(&record, &progress, &refundTransaction, nil)
+
+	serializedRefundTx, err := refundTransaction.Base64()
+	if err != nil {
+		return err
+	}
+	if !*automatedFlag {
+		if record.Secret != "" {
+			fmt.Printf("Secret:      %s\n", record.Secret)
+		}
+		fmt.Printf("Secret hash: %s\n\n", record.SecretHash)
+		fmt.Printf("%s address: %s\n", record.Role, record.Funder)
+		fmt.Printf("holding account address: %s\n", record.HoldingAccount)
+		fmt.Printf("refund transaction:\n%s\n", serializedRefundTx)
+	} else {
+		output := struct {
+			Secret                string   `json:"secret,omitempty"`
+			SecretHash            string   `json:"hash"`
+			HoldingAccountAddress string   `json:"holdingaccount"`
+			RefundTransaction     string   `json:"refundtransaction"`
+			CompletedSteps        []string `json:"completedsteps"`
+		}{record.Secret, record.SecretHash, record.HoldingAccount, serializedRefundTx, progress.completed}
+		jsonoutput, _ := json.Marshal(output)
+		fmt.Println(string(jsonoutput))
+	}
+	if *refundFileFlag != "" {
+		return exportRefundTransaction(*refundFileFlag, refundTransaction)
+	}
+	return nil
+}
+
+//resumeHoldingAccountSetup performs the steps of createAtomicSwapHoldingAccount that are not on the chain yet
+func resumeHoldingAccountSetup(fundingSigner stellar.Signer, holdingAccountKeyPair *keypair.Full, record *swapdb.Swap, asset txnbuild.Asset, secretHash []byte, progress *setupProgress, networkPassphrase string, client horizonclient.ClientInterface) (refundTransaction txnbuild.Transaction, err error) {
+	holdingAccount, exists, err := getOptionalAccount(record.HoldingAccount, client)
+	if err != nil {
+		return
+	}
+	var fundingAccount *horizon.Account
+	if !exists || !hasAssetBalance(holdingAccount, asset) {
+		if fundingAccount, err = stellar.GetAccount(fundingSigner.Address(), client); err != nil {
+			return
+		}
+	}
+	if !exists {
+		xlmAmount := "10"
+		if asset.IsNative() {
+			xlmAmount = record.Amount
+		}
+		return createHoldingAccount(fundingSigner, holdingAccountKeyPair, record.Counterparty, xlmAmount, record.Amount, asset, secretHash, record.Locktime, record.RefundBaseFee, fundingAccount, progress, networkPassphrase, client)
+	}
+	progress.done(stepAccountCreated)
+
+	if !asset.IsNative() {
+		if !hasAssetBalance(holdingAccount, asset) {
+			progress.start(stepAccountFunded)
+			if err = fundHoldingAccount(fundingSigner, fundingAccount, holdingAccountKeyPair, holdingAccount, record.Amount, asset, networkPassphrase, client); err != nil {
+				return
+			}
+			if holdingAccount, err = stellar.GetAccount(record.HoldingAccount, client); err != nil {
+				return
+			}
+		}
+		progress.done(stepAccountFunded)
+	}
+
+	if holdingAccount.Thresholds.HighThreshold == 2 {
+		//The signing options are already set, rebuild the refund transaction they refer to.
+		//The data entries in the account were added by the same transaction, after the refund transaction was built.
+		//When the signing options were set in a transaction of the holding account itself, as older versions did,
+		//that transaction took the sequence number after the one the refund transaction was built on.
+		var sequence xdr.SequenceNumber
+		if sequence, err = holdingAccount.GetSequenceNumber(); err != nil {
+			return
+		}
+		for _, refundSequence := range []int64{int64(sequence), int64(sequence) - 1} {
+			refundAccount := *holdingAccount
+			refundAccount.Sequence = strconv.FormatInt(refundSequence, 10)
+			if refundTransaction, err = createRefundTransaction(&refundAccount, record.Funder, record.Locktime, nil, record.RefundBaseFee, networkPassphrase); err != nil {
+				return
+			}
+			if _, err = auditHoldingAccount(record.HoldingAccount, &refundTransaction, networkPassphrase, client); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			err = fmt.Errorf("The signing options are set but the refund transaction can not be reconstructed: %v", err)
+			return
+		}
+		progress.done(stepRefundTxCreated)
+		progress.done(stepOptionsSet)
+		return
+	}
+
+	dataEntries := holdingAccountDataEntries(secretHash)
+	refundAccount := *holdingAccount
+	if refundTransaction, err = createRefundTransaction(&refundAccount, record.Funder, record.Locktime, dataEntries, record.RefundBaseFee, networkPassphrase); err != nil {
+		return
+	}
+	refundTransactionHash, err := refundTransaction.Hash()
+	if err != nil {
+		return
+	}
+	progress.done(stepRefundTxCreated)
+	progress.start(stepOptionsSet)
+	txe, err := signHoldingAccountSigningOptions(holdingAccountKeyPair, holdingAccount, record.Counterparty, secretHash, refundTransactionHash[:], dataEntries, networkPassphrase, suggestBaseFee(client))
+	if err != nil {
+		return
+	}
+	if _, err = stellar.SubmitTransaction(txe, client); err != nil {
+		err = fmt.Errorf("Failed to publish the signing options transaction : %w", err)
+		return
+	}
+	progress.done(stepOptionsSet)
+	return
+}
+
+//retryHoldingAccountSetup resumes a setup that failed because horizon had a problem or could not be reached,
+//or because another transaction of the funding account took the sequence number (tx_bad_seq).
+//The remaining steps are retried a few times with a growing delay, resuming reloads the accounts and their sequence numbers.
+func retryHoldingAccountSetup(err error, fundingSigner stellar.Signer, holdingAccountKeyPair *keypair.Full, record *swapdb.Swap, asset txnbuild.Asset, secretHash []byte, progress *setupProgress, networkPassphrase string, client horizonclient.ClientInterface) (refundTransaction txnbuild.Transaction, _ error) {
+	delay := setupRetryDelay
+	for attempt := 1; attempt <= setupRetries && (stellar.IsTransientError(err) || stellar.IsBadSequenceError(err)); attempt++ {
+		logger.WithField("holdingaccount", record.HoldingAccount).Warnf("Setting up the holding account failed, retrying the remaining steps in %v (%d/%d): %v", delay, attempt, setupRetries, err)
+		time.Sleep(delay)
+		delay *= 2
+		if refundTransaction, err = resumeHoldingAccountSetup(fundingSigner, holdingAccountKeyPair, record, asset, secretHash, progress, networkPassphrase, client); err == nil {
+			return
+		}
+	}
+	return refundTransaction, err
+}
+
+//getOptionalAccount gets an account, exists is false if horizon does not know it
+func getOptionalAccount(address string, client horizonclient.ClientInterface) (account *horizon.Account, exists bool, err error) {
+	detail, err := client.AccountDetail(horizonclient.AccountRequest{AccountID: address})
+	if stellar.IsNotFoundError(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return &detail, true, nil
+}
+
+//hasAssetBalance returns true if the account holds the asset, the native asset is always held
+func hasAssetBalance(account *horizon.Account, asset txnbuild.Asset) bool {
+	if asset.IsNative() {
+		return true
+	}
+	if account == nil {
+		return false
+	}
+	for _, balance := range account.Balances {
+		if balance.Code == asset.GetCode() && balance.Issuer == asset.GetIssuer() {
+			return true
+		}
+	}
+	return false
+}
+
+//parseAssetName parses the asset names assetName returns
+func parseAssetName(name string) (txnbuild.Asset, error) {
+	if name == "XLM" {
+		return txnbuild.NativeAsset{}, nil
+	}
+	parts := strings.SplitN(name, ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("Invalid asset %q", name)
+	}
+	return txnbuild.CreditAsset{Code: parts[0], Issuer: parts[1]}, nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+)
+
+//printSep7URI prints the redeem transaction, signed with the secret, for the receiver to sign in a wallet.
+//Anyone who sees the URI learns the secret.
+func (cmd *redeemCmd) printSep7URI(client horizonclient.ClientInterface) error {
+	holdingAccount, err := stellar.GetAccount(cmd.holdingAccountAddress, client)
+	if err != nil {
+		return err
+	}
+	redeemTransaction, err := createRedeemTransaction(holdingAccount, cmd.receiverAddress, cmd.secret, targetNetwork, client)
+	if err != nil {
+		return err
+	}
+	txe, err := redeemTransaction.Base64()
+	if err != nil {
+		return fmt.Errorf("Unable to encode the transaction: %v", err)
+	}
+	return printSep7URI(txe, cmd.receiverAddress, "Redeem atomic swap holding account "+cmd.holdingAccountAddress)
+}
+
+func printSep7URI(txe string, pubkey string, msg string) error {
+	uri := stellar.Sep7TransactionURI(txe, targetNetwork, pubkey, msg)
+	if *automatedFlag {
+		jsonoutput, _ := json.Marshal(map[string]string{"uri": uri})
+		fmt.Println(string(jsonoutput))
+	} else {
+		fmt.Println(uri)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+//AWSKMSSigner signs with an ed25519 (ECC_NIST_EDWARDS25519) key held in AWS KMS.
+//Credentials and region are taken from the standard AWS_* environment variables.
+type AWSKMSSigner struct {
+	keyID        string
+	address      string
+	region       string
+	endpoint     string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	client       *http.Client
+}
+
+//NewAWSKMSSigner creates a signer for a KMS key id, alias or ARN and fetches its public key
+func NewAWSKMSSigner(keyID string) (*AWSKMSSigner, error) {
+	s := &AWSKMSSigner{
+		keyID:        keyID,
+		region:       os.Getenv("AWS_REGION"),
+		endpoint:     os.Getenv("AWS_ENDPOINT_URL_KMS"),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		client:       &http.Client{Timeout: 30 * time.Second},
+	}
+	if s.region == "" {
+		s.region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if s.region == "" {
+		return nil, errors.New("AWS_REGION is not set")
+	}
+	if s.accessKey == "" || s.secretKey == "" {
+		return nil, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY need to be set")
+	}
+	if s.endpoint == "" {
+		s.endpoint = fmt.Sprintf("https://kms.%s.amazonaws.com/", s.region)
+	}
+
+	var response struct {
+		PublicKey string
+		KeySpec   string
+	}
+	if err := s.call("GetPublicKey", map[string]string{"KeyId": keyID}, &response); err != nil {
+		return nil, fmt.Errorf("Failed to get the public key of KMS key %s: %v", keyID, err)
+	}
+	der, err := base64.StdEncoding.DecodeString(response.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse the public key of KMS key %s: %v", keyID, err)
+	}
+	edPublicKey, ok := publicKey.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("KMS key %s is a %s key instead of an ed25519 key", keyID, response.KeySpec)
+	}
+	if s.address, err = addressFromPublicKey(edPublicKey); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+//Address returns the stellar address of the KMS key
+func (s *AWSKMSSigner) Address() string {
+	return s.address
+}
+
+//Sign signs the input with the KMS key, the input itself is sent to KMS
+func (s *AWSKMSSigner) Sign(input []byte) ([]byte, error) {
+	request := map[string]string{
+		"KeyId":            s.keyID,
+		"Message":          base64.StdEncoding.EncodeToString(input),
+		"MessageType":      "RAW",
+		"SigningAlgorithm": "ED25519_SHA_512",
+	}
+	var response struct {
+		Signature string
+	}
+	if err := s.call("Sign", request, &response); err != nil {
+		return nil, fmt.Errorf("KMS signing failed: %v", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(response.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid KMS signature: %v", err)
+	}
+	if len(signature) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("Invalid KMS signature of %d bytes", len(signature))
+	}
+	return signature, nil
+}
+
+//call executes a KMS API action, signing the request with AWS signature version 4
+func (s *AWSKMSSigner) call(action string, request interface{}, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+	s.signRequest(req, body, now)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var awsError struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(responseBody, &awsError)
+		return fmt.Errorf("%s: %s %s", resp.Status, awsError.Type, awsError.Message)
+	}
+	return json.Unmarshal(responseBody, response)
+}
+
+func (s *AWSKMSSigner) signRequest(req *http.Request, body []byte, now time.Time) {
+	const service = "kms"
+	date := now.Format("20060102")
+	scope := strings.Join([]string{date, s.region, service, "aws4_request"}, "/")
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hexSHA256(body),
+	}, "\n")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format("20060102T150405Z"),
+		scope,
+		hexSHA256([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", s.accessKey, scope, signedHeaders, signature))
+}
+
+func hexSHA256(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,176 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+//GCPKMSSigner signs with an EC_SIGN_ED25519 key version held in Google Cloud KMS.
+//The OAuth2 access token is taken from the GOOGLE_OAUTH_ACCESS_TOKEN environment variable
+//or, when it is not set, from the metadata server of the GCE instance or GKE pod.
+type GCPKMSSigner struct {
+	keyVersion string
+	address    string
+	endpoint   string
+	client     *http.Client
+
+	tokenLock   sync.Mutex
+	staticToken string
+	token       string
+	tokenExpiry time.Time
+}
+
+//NewGCPKMSSigner creates a signer for the full resource name of a key version,
+//projects/<project>/locations/<location>/keyRings/<ring>/cryptoKeys/<key>/cryptoKeyVersions/<version>,
+//and fetches its public key
+func NewGCPKMSSigner(keyVersion string) (*GCPKMSSigner, error) {
+	if !strings.HasPrefix(keyVersion, "projects/") || !strings.Contains(keyVersion, "/cryptoKeyVersions/") {
+		return nil, fmt.Errorf("Invalid Cloud KMS key version %q, expected projects/.../cryptoKeyVersions/<version>", keyVersion)
+	}
+	s := &GCPKMSSigner{
+		keyVersion:  keyVersion,
+		endpoint:    strings.TrimSuffix(os.Getenv("GOOGLE_KMS_ENDPOINT"), "/"),
+		staticToken: os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"),
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}
+	if s.endpoint == "" {
+		s.endpoint = "https://cloudkms.googleapis.com"
+	}
+
+	var response struct {
+		Pem       string `json:"pem"`
+		Algorithm string `json:"algorithm"`
+	}
+	if err := s.call(http.MethodGet, s.keyVersion+"/publicKey", nil, &response); err != nil {
+		return nil, fmt.Errorf("Failed to get the public key of Cloud KMS key %s: %v", keyVersion, err)
+	}
+	if response.Algorithm != "EC_SIGN_ED25519" {
+		return nil, fmt.Errorf("Cloud KMS key %s is a %s key instead of an ed25519 key", keyVersion, response.Algorithm)
+	}
+	block, _ := pem.Decode([]byte(response.Pem))
+	if block == nil {
+		return nil, fmt.Errorf("Invalid public key for Cloud KMS key %s", keyVersion)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse the public key of Cloud KMS key %s: %v", keyVersion, err)
+	}
+	edPublicKey, ok := publicKey.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("Cloud KMS key %s is not an ed25519 key", keyVersion)
+	}
+	if s.address, err = addressFromPublicKey(edPublicKey); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+//Address returns the stellar address of the Cloud KMS key
+func (s *GCPKMSSigner) Address() string {
+	return s.address
+}
+
+//Sign signs the input with the Cloud KMS key, the input itself is sent to Cloud KMS
+func (s *GCPKMSSigner) Sign(input []byte) ([]byte, error) {
+	request := map[string]string{
+		"data": base64.StdEncoding.EncodeToString(input),
+	}
+	var response struct {
+		Signature string `json:"signature"`
+	}
+	if err := s.call(http.MethodPost, s.keyVersion+":asymmetricSign", request, &response); err != nil {
+		return nil, fmt.Errorf("Cloud KMS signing failed: %v", err)
+	}
+	return base64.StdEncoding.DecodeString(response.Signature)
+}
+
+//accessToken returns the static token or a cached token of the metadata server
+func (s *GCPKMSSigner) accessToken() (string, error) {
+	if s.staticToken != "" {
+		return s.staticToken, nil
+	}
+	s.tokenLock.Lock()
+	defer s.tokenLock.Unlock()
+	if s.token != "" && time.Now().Before(s.tokenExpiry) {
+		return s.token, nil
+	}
+	req, err := http.NewRequest(http.MethodGet, gcpMetadataTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", errors.New("GOOGLE_OAUTH_ACCESS_TOKEN is not set and the metadata server is not reachable")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Failed to get an access token from the metadata server: %s", resp.Status)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	s.token = token.AccessToken
+	//refresh a minute early so a token does not expire during a request
+	s.tokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return s.token, nil
+}
+
+func (s *GCPKMSSigner) call(method string, path string, request interface{}, response interface{}) error {
+	token, err := s.accessToken()
+	if err != nil {
+		return err
+	}
+	var body []byte
+	if request != nil {
+		if body, err = json.Marshal(request); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, fmt.Sprintf("%s/v1/%s", s.endpoint, path), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if request != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var gcpError struct {
+			Error struct {
+				Status  string `json:"status"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.Unmarshal(responseBody, &gcpError)
+		return fmt.Errorf("%s: %s %s", resp.Status, gcpError.Error.Status, gcpError.Error.Message)
+	}
+	return json.Unmarshal(responseBody, response)
+}
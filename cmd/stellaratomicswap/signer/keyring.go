@@ -0,0 +1,43 @@
+package signer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/stellar/go/keypair"
+)
+
+//keyringService is the service name the seeds are stored under in the OS keyring
+const keyringService = "stellaratomicswap"
+
+//SaveToKeyring stores a seed in the OS keyring under an alias so it can later be used as `keyring:<alias>`
+func SaveToKeyring(alias string, seed string) error {
+	if alias == "" {
+		return errors.New("An alias is required")
+	}
+	kp, err := keypair.Parse(seed)
+	if err != nil {
+		return fmt.Errorf("Invalid seed: %v", err)
+	}
+	if _, ok := kp.(*keypair.Full); !ok {
+		return errors.New("An address was given instead of a seed")
+	}
+	return keyringSet(keyringService, alias, seed)
+}
+
+//NewKeyringSigner loads the seed stored under an alias from the OS keyring
+func NewKeyringSigner(alias string) (*keypair.Full, error) {
+	seed, err := keyringGet(keyringService, alias)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get %s from the keyring: %v", alias, err)
+	}
+	kp, err := keypair.Parse(seed)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid seed stored under %s in the keyring: %v", alias, err)
+	}
+	fullKeyPair, ok := kp.(*keypair.Full)
+	if !ok {
+		return nil, fmt.Errorf("No seed stored under %s in the keyring", alias)
+	}
+	return fullKeyPair, nil
+}
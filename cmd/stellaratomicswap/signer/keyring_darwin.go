@@ -0,0 +1,38 @@
+package signer
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+//keyringSet stores the secret in the macOS Keychain.
+//The command is passed to security on stdin, on the command line the secret would be visible to every local user in the process list.
+func keyringSet(service string, account string, secret string) error {
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", securityQuote(service), securityQuote(account), securityQuote(secret)))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+	//the interactive mode of security does not fail when a command fails
+	stored, err := keyringGet(service, account)
+	if err != nil || stored != secret {
+		return fmt.Errorf("the secret was not stored: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+//securityQuote quotes an argument for the interactive mode of security
+func securityQuote(arg string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+//keyringGet gets the secret from the macOS Keychain
+func keyringGet(service string, account string) (string, error) {
+	output, err := exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,27 @@
+package signer
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+//keyringSet stores the secret in the Secret Service keyring (GNOME keyring, KWallet) using secret-tool
+func keyringSet(service string, account string, secret string) error {
+	cmd := exec.Command("secret-tool", "store", "--label="+service+" "+account, "service", service, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+//keyringGet gets the secret from the Secret Service keyring using secret-tool
+func keyringGet(service string, account string) (string, error) {
+	output, err := exec.Command("secret-tool", "lookup", "service", service, "account", account).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,16 @@
+//go:build !darwin && !linux && !windows
+// +build !darwin,!linux,!windows
+
+package signer
+
+import "errors"
+
+var errKeyringUnsupported = errors.New("The OS keyring is not supported on this platform")
+
+func keyringSet(service string, account string, secret string) error {
+	return errKeyringUnsupported
+}
+
+func keyringGet(service string, account string) (string, error) {
+	return "", errKeyringUnsupported
+}
@@ -0,0 +1,45 @@
+//Package signer provides stellar.Signer implementations for keys that are not stored as a seed on disk
+package signer
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"strings"
+
+	"github.com/stellar/go/strkey"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+)
+
+//IsSpec returns true if the argument is a signer specification of the form `backend:key`
+func IsSpec(s string) bool {
+	backend := strings.SplitN(s, ":", 2)[0]
+	_, ok := backends[backend]
+	return ok && strings.Contains(s, ":")
+}
+
+//FromSpec creates a signer from a specification of the form `backend:key`, for example `kms:<key-id>` or `vault:<key-name>`
+func FromSpec(spec string) (stellar.Signer, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("Invalid signer %q, expected backend:key", spec)
+	}
+	newSigner, ok := backends[parts[0]]
+	if !ok {
+		return nil, fmt.Errorf("Unknown signer backend %q", parts[0])
+	}
+	return newSigner(parts[1])
+}
+
+var backends = map[string]func(key string) (stellar.Signer, error){
+	"kms":     func(key string) (stellar.Signer, error) { return NewAWSKMSSigner(key) },
+	"gcpkms":  func(key string) (stellar.Signer, error) { return NewGCPKMSSigner(key) },
+	"vault":   func(key string) (stellar.Signer, error) { return NewVaultSigner(key) },
+	"keyring": func(key string) (stellar.Signer, error) { return NewKeyringSigner(key) },
+}
+
+func addressFromPublicKey(publicKey []byte) (string, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return "", fmt.Errorf("Invalid ed25519 public key of %d bytes", len(publicKey))
+	}
+	return strkey.Encode(strkey.VersionByteAccountID, publicKey)
+}
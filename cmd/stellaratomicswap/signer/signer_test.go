@@ -0,0 +1,225 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/strkey"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVaultSigner(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if !assert.NoError(t, err) {
+		return
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/transit/keys/swap":
+			w.Write([]byte(`{"data":{"type":"ed25519","latest_version":1,"keys":{"1":{"public_key":"` + base64.StdEncoding.EncodeToString(publicKey) + `"}}}}`))
+		case "/v1/transit/keys/short":
+			w.Write([]byte(`{"data":{"type":"ed25519","latest_version":1,"keys":{"1":{"public_key":"` + base64.StdEncoding.EncodeToString(publicKey[:31]) + `"}}}}`))
+		case "/v1/transit/keys/rsa":
+			w.Write([]byte(`{"data":{"type":"rsa-2048","latest_version":1,"keys":{"1":{"public_key":"` + base64.StdEncoding.EncodeToString(publicKey) + `"}}}}`))
+		case "/v1/transit/keys/rotated":
+			w.Write([]byte(`{"data":{"type":"ed25519","latest_version":2,"keys":{"1":{"public_key":"` + base64.StdEncoding.EncodeToString(publicKey) + `"}}}}`))
+		case "/v1/transit/sign/swap":
+			var request struct {
+				Input string `json:"input"`
+			}
+			json.NewDecoder(r.Body).Decode(&request)
+			input, _ := base64.StdEncoding.DecodeString(request.Input)
+			signature := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, input))
+			w.Write([]byte(`{"data":{"signature":"vault:v1:` + signature + `"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	os.Setenv("VAULT_ADDR", server.URL)
+	os.Setenv("VAULT_TOKEN", "token")
+	defer os.Unsetenv("VAULT_ADDR")
+	defer os.Unsetenv("VAULT_TOKEN")
+
+	assert.True(t, IsSpec("vault:swap"))
+	s, err := FromSpec("vault:swap")
+	if !assert.NoError(t, err) {
+		return
+	}
+	kp, err := keypair.Parse(s.Address())
+	if !assert.NoError(t, err) {
+		return
+	}
+	input := []byte("transaction hash")
+	signature, err := s.Sign(input)
+	if assert.NoError(t, err) {
+		assert.NoError(t, kp.Verify(input, signature))
+	}
+
+	_, err = FromSpec("vault:unknown")
+	assert.Error(t, err)
+	_, err = FromSpec("vault:short")
+	assert.EqualError(t, err, "Invalid public key for Vault transit key short: 31 bytes instead of 32")
+	_, err = FromSpec("vault:rsa")
+	assert.EqualError(t, err, "Vault transit key rsa is a rsa-2048 key instead of an ed25519 key")
+	_, err = FromSpec("vault:rotated")
+	assert.Error(t, err)
+}
+
+func TestGCPKMSSigner(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if !assert.NoError(t, err) {
+		return
+	}
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if !assert.NoError(t, err) {
+		return
+	}
+	publicKeyPem, _ := json.Marshal(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+	const keyVersion = "projects/p/locations/global/keyRings/r/cryptoKeys/swap/cryptoKeyVersions/1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/" + keyVersion + "/publicKey":
+			w.Write([]byte(`{"algorithm":"EC_SIGN_ED25519","pem":` + string(publicKeyPem) + `}`))
+		case "/v1/" + keyVersion + ":asymmetricSign":
+			var request struct {
+				Data string `json:"data"`
+			}
+			json.NewDecoder(r.Body).Decode(&request)
+			input, _ := base64.StdEncoding.DecodeString(request.Data)
+			w.Write([]byte(`{"signature":"` + base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, input)) + `"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	os.Setenv("GOOGLE_KMS_ENDPOINT", server.URL)
+	os.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "token")
+	defer os.Unsetenv("GOOGLE_KMS_ENDPOINT")
+	defer os.Unsetenv("GOOGLE_OAUTH_ACCESS_TOKEN")
+
+	s, err := FromSpec("gcpkms:" + keyVersion)
+	if !assert.NoError(t, err) {
+		return
+	}
+	kp, err := keypair.Parse(s.Address())
+	if !assert.NoError(t, err) {
+		return
+	}
+	input := []byte("transaction hash")
+	signature, err := s.Sign(input)
+	if assert.NoError(t, err) {
+		assert.NoError(t, kp.Verify(input, signature))
+	}
+
+	_, err = FromSpec("gcpkms:swap")
+	assert.Error(t, err)
+}
+
+func TestAWSKMSSigner(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if !assert.NoError(t, err) {
+		return
+	}
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ecdsaDer, err := x509.MarshalPKIXPublicKey(&ecdsaKey.PublicKey)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var signedMessage []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var request struct {
+			KeyId            string
+			Message          string
+			SigningAlgorithm string
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.GetPublicKey":
+			switch request.KeyId {
+			case "swap":
+				w.Write([]byte(`{"KeySpec":"ECC_NIST_EDWARDS25519","PublicKey":"` + base64.StdEncoding.EncodeToString(der) + `"}`))
+			case "p256":
+				w.Write([]byte(`{"KeySpec":"ECC_NIST_P256","PublicKey":"` + base64.StdEncoding.EncodeToString(ecdsaDer) + `"}`))
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"__type":"NotFoundException","message":"key not found"}`))
+			}
+		case "TrentService.Sign":
+			if request.SigningAlgorithm != "ED25519_SHA_512" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			signedMessage, _ = base64.StdEncoding.DecodeString(request.Message)
+			w.Write([]byte(`{"Signature":"` + base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, signedMessage)) + `"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	os.Setenv("AWS_ENDPOINT_URL_KMS", server.URL)
+	os.Setenv("AWS_REGION", "eu-west-1")
+	os.Setenv("AWS_ACCESS_KEY_ID", "access")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	defer os.Unsetenv("AWS_ENDPOINT_URL_KMS")
+	defer os.Unsetenv("AWS_REGION")
+	defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+	defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+
+	s, err := FromSpec("kms:swap")
+	if !assert.NoError(t, err) {
+		return
+	}
+	//the address is the raw 32 byte key inside the DER encoded SubjectPublicKeyInfo
+	kp, err := keypair.Parse(s.Address())
+	if !assert.NoError(t, err) {
+		return
+	}
+	rawPublicKey, err := strkey.Decode(strkey.VersionByteAccountID, s.Address())
+	if assert.NoError(t, err) {
+		assert.Equal(t, []byte(publicKey), rawPublicKey)
+	}
+	input := []byte("transaction hash")
+	signature, err := s.Sign(input)
+	if assert.NoError(t, err) {
+		assert.Equal(t, input, signedMessage)
+		assert.Equal(t, ed25519.Sign(privateKey, input), signature)
+		assert.NoError(t, kp.Verify(input, signature))
+	}
+
+	_, err = FromSpec("kms:p256")
+	assert.EqualError(t, err, "KMS key p256 is a ECC_NIST_P256 key instead of an ed25519 key")
+	_, err = FromSpec("kms:unknown")
+	assert.Error(t, err)
+}
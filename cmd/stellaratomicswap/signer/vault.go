@@ -0,0 +1,140 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//VaultSigner signs with an ed25519 key in the HashiCorp Vault transit secrets engine.
+//The Vault address and token are taken from the VAULT_ADDR and VAULT_TOKEN environment variables,
+//the mount path of the transit engine from VAULT_TRANSIT_MOUNT (default transit).
+type VaultSigner struct {
+	keyName    string
+	keyVersion int
+	address    string
+	vaultAddr  string
+	token      string
+	mount      string
+	client     *http.Client
+}
+
+//NewVaultSigner creates a signer for a transit key and fetches the public key of its latest version
+func NewVaultSigner(keyName string) (*VaultSigner, error) {
+	s := &VaultSigner{
+		keyName:   keyName,
+		vaultAddr: strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/"),
+		token:     os.Getenv("VAULT_TOKEN"),
+		mount:     strings.Trim(os.Getenv("VAULT_TRANSIT_MOUNT"), "/"),
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+	if s.vaultAddr == "" || s.token == "" {
+		return nil, errors.New("VAULT_ADDR and VAULT_TOKEN need to be set")
+	}
+	if s.mount == "" {
+		s.mount = "transit"
+	}
+
+	var response struct {
+		Data struct {
+			Type          string `json:"type"`
+			LatestVersion int    `json:"latest_version"`
+			Keys          map[string]struct {
+				PublicKey string `json:"public_key"`
+			} `json:"keys"`
+		} `json:"data"`
+	}
+	if err := s.call(http.MethodGet, "keys/"+keyName, nil, &response); err != nil {
+		return nil, fmt.Errorf("Failed to read Vault transit key %s: %v", keyName, err)
+	}
+	if response.Data.Type != "ed25519" {
+		return nil, fmt.Errorf("Vault transit key %s is a %s key instead of an ed25519 key", keyName, response.Data.Type)
+	}
+	s.keyVersion = response.Data.LatestVersion
+	key, ok := response.Data.Keys[strconv.Itoa(s.keyVersion)]
+	if !ok {
+		return nil, fmt.Errorf("Vault transit key %s has no public key for its latest version %d", keyName, s.keyVersion)
+	}
+	publicKey, err := base64.StdEncoding.DecodeString(key.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid public key for Vault transit key %s: %v", keyName, err)
+	}
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("Invalid public key for Vault transit key %s: %d bytes instead of %d", keyName, len(publicKey), ed25519.PublicKeySize)
+	}
+	if s.address, err = addressFromPublicKey(publicKey); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+//Address returns the stellar address of the transit key
+func (s *VaultSigner) Address() string {
+	return s.address
+}
+
+//Sign signs the input with the latest version of the transit key
+func (s *VaultSigner) Sign(input []byte) ([]byte, error) {
+	request := map[string]interface{}{
+		"input":       base64.StdEncoding.EncodeToString(input),
+		"key_version": s.keyVersion,
+	}
+	var response struct {
+		Data struct {
+			Signature string `json:"signature"`
+		} `json:"data"`
+	}
+	if err := s.call(http.MethodPost, "sign/"+s.keyName, request, &response); err != nil {
+		return nil, fmt.Errorf("Vault signing failed: %v", err)
+	}
+	//The signature is formatted as vault:v<version>:<base64 signature>
+	parts := strings.Split(response.Data.Signature, ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("Unexpected Vault signature format: %s", response.Data.Signature)
+	}
+	return base64.StdEncoding.DecodeString(parts[2])
+}
+
+func (s *VaultSigner) call(method string, path string, request interface{}, response interface{}) error {
+	var body []byte
+	if request != nil {
+		var err error
+		if body, err = json.Marshal(request); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, fmt.Sprintf("%s/v1/%s/%s", s.vaultAddr, s.mount, path), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", s.token)
+	if request != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var vaultError struct {
+			Errors []string `json:"errors"`
+		}
+		json.Unmarshal(responseBody, &vaultError)
+		return fmt.Errorf("%s: %s", resp.Status, strings.Join(vaultError.Errors, ", "))
+	}
+	return json.Unmarshal(responseBody, response)
+}
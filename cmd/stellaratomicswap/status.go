@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/swapdb"
+)
+
+//The status of a holding account on the chain
+const (
+	//contractStatusSettingUp is a holding account without the swap signing conditions
+	contractStatusSettingUp = "settingup"
+	contractStatusFunded    = "funded"
+	//contractStatusExpired is a funded holding account whose locktime passed, it can be refunded
+	contractStatusExpired  = "expired"
+	contractStatusRedeemed = "redeemed"
+	contractStatusRefunded = "refunded"
+)
+
+type statusCmd struct {
+	holdingAccount string
+}
+
+//contractStatus is the state of a holding account derived from horizon
+type contractStatus struct {
+	HoldingAccount string `json:"holdingaccount"`
+	Status         string `json:"status"`
+	//Locktime is only known for swaps in the swap database
+	Locktime *time.Time               `json:"locktime,omitempty"`
+	Balances []stellar.CreditedAmount `json:"balances,omitempty"`
+	//Transaction is the redeem or refund transaction that merged the holding account
+	Transaction string     `json:"transaction,omitempty"`
+	Merged      *time.Time `json:"merged,omitempty"`
+	Receiver    string     `json:"receiver,omitempty"`
+}
+
+//getContractStatus derives the status of a holding account.
+//A merge into the account that created the holding account is a refund, a merge into any other account a redeem.
+func getContractStatus(holdingAccountAddress string, client horizonclient.ClientInterface) (status contractStatus, err error) {
+	status.HoldingAccount = holdingAccountAddress
+	holdingAccount, exists, err := getOptionalAccount(holdingAccountAddress, client)
+	if err != nil {
+		return
+	}
+	if !exists {
+		lifecycle, err := stellar.GetAccountLifecycle(holdingAccountAddress, client)
+		if err != nil {
+			return status, fmt.Errorf("Failed to get the operations of the holding account: %v", err)
+		}
+		if lifecycle.Created == nil || lifecycle.Merged == nil {
+			return status, fmt.Errorf("Holding account %s does not exist", holdingAccountAddress)
+		}
+		status.Status = contractStatusRedeemed
+		if lifecycle.Merged.Into == lifecycle.Created.Funder {
+			status.Status = contractStatusRefunded
+		}
+		status.Transaction = lifecycle.Merged.TransactionHash
+		status.Merged = &lifecycle.Merged.LedgerCloseTime
+		status.Receiver = lifecycle.Merged.Into
+		return status, nil
+	}
+
+	for _, balance := range holdingAccount.Balances {
+		asset := "XLM"
+		if balance.Asset.Type != stellar.NativeAssetType {
+			asset = balance.Code + ":" + balance.Issuer
+		}
+		status.Balances = append(status.Balances, stellar.CreditedAmount{Asset: asset, Amount: balance.Balance})
+	}
+	if holdingAccount.Thresholds.HighThreshold != 2 {
+		status.Status = contractStatusSettingUp
+		return
+	}
+	status.Status = contractStatusFunded
+	if *swapDBFlag == "" {
+		return
+	}
+	var record swapdb.Swap
+	err = withSwapDB(func(db *swapdb.DB) (err error) {
+		record, err = db.Get(holdingAccountAddress)
+		return
+	})
+	if err == swapdb.ErrNotFound {
+		return status, nil
+	}
+	if err != nil {
+		return
+	}
+	status.Locktime = &record.Locktime
+	if time.Now().After(record.Locktime) {
+		status.Status = contractStatusExpired
+	}
+	return
+}
+
+func (cmd *statusCmd) runCommand(client horizonclient.ClientInterface) error {
+	status, err := getContractStatus(cmd.holdingAccount, client)
+	if err != nil {
+		return err
+	}
+	if *automatedFlag {
+		jsonoutput, _ := json.Marshal(status)
+		fmt.Println(string(jsonoutput))
+		return nil
+	}
+	fmt.Printf("Holding account: %s\n", status.HoldingAccount)
+	fmt.Printf("Status: %s\n", status.Status)
+	for _, balance := range status.Balances {
+		fmt.Printf("Balance: %s %s\n", balance.Amount, balance.Asset)
+	}
+	if status.Locktime != nil {
+		fmt.Printf("Locktime: %v\n", status.Locktime.UTC())
+	}
+	if status.Transaction != "" {
+		fmt.Printf("Merged into %s at %v by transaction %s\n", status.Receiver, status.Merged.UTC(), status.Transaction)
+	}
+	return nil
+}
+
+//checkNotMerged fails with the transaction that merged the holding account when it no longer exists,
+//instead of horizon rejecting a refund with op_no_account. If its state can not be determined, horizon decides.
+func checkNotMerged(holdingAccountAddress string, client horizonclient.ClientInterface) error {
+	_, exists, err := getOptionalAccount(holdingAccountAddress, client)
+	if err != nil || exists {
+		return nil
+	}
+	status, err := getContractStatus(holdingAccountAddress, client)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("Holding account %s was already %s into %s at %v by transaction %s", holdingAccountAddress, status.Status, status.Receiver, status.Merged.UTC(), status.Transaction)
+}
@@ -0,0 +1,116 @@
+package stellar
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/stellar/go/network"
+	"github.com/stellar/go/xdr"
+)
+
+//DecodedTransaction is a transaction envelope in a form that is easy to inspect
+type DecodedTransaction struct {
+	//Hash is the hash of the transaction on the network it was decoded for
+	Hash          string             `json:"hash"`
+	SourceAccount string             `json:"sourceaccount"`
+	Sequence      int64              `json:"sequence"`
+	Fee           uint32             `json:"fee"`
+	MinTime       *time.Time         `json:"mintime,omitempty"`
+	MaxTime       *time.Time         `json:"maxtime,omitempty"`
+	Memo          string             `json:"memo,omitempty"`
+	Operations    []DecodedOperation `json:"operations"`
+	Signatures    []DecodedSignature `json:"signatures"`
+}
+
+//DecodedOperation is an operation with its fields in SEP-0011 txrep naming
+type DecodedOperation struct {
+	Type          string            `json:"type"`
+	SourceAccount string            `json:"sourceaccount,omitempty"`
+	Fields        map[string]string `json:"fields,omitempty"`
+}
+
+//DecodedSignature is a signature with what it was matched to
+type DecodedSignature struct {
+	Hint      string `json:"hint"`
+	Signature string `json:"signature"`
+	//Signer is the account of the transaction or its operations with a matching hint
+	Signer string `json:"signer,omitempty"`
+	//HashX is true when the signature is a hash preimage, like the secret of an atomic swap
+	HashX bool `json:"hashx,omitempty"`
+}
+
+//DecodeTransaction decodes a transaction envelope, the hash is calculated for the network passphrase
+func DecodeTransaction(envelope xdr.TransactionEnvelope, networkPassphrase string) (decoded DecodedTransaction, err error) {
+	tx := envelope.Tx
+	hash, err := network.HashTransaction(&tx, networkPassphrase)
+	if err != nil {
+		return
+	}
+	decoded = DecodedTransaction{
+		Hash:          fmt.Sprintf("%x", hash),
+		SourceAccount: tx.SourceAccount.Address(),
+		Sequence:      int64(tx.SeqNum),
+		Fee:           uint32(tx.Fee),
+		Operations:    make([]DecodedOperation, 0, len(tx.Operations)),
+		Signatures:    make([]DecodedSignature, 0, len(envelope.Signatures)),
+	}
+	if tx.TimeBounds != nil {
+		if tx.TimeBounds.MinTime != 0 {
+			minTime := time.Unix(int64(tx.TimeBounds.MinTime), 0).UTC()
+			decoded.MinTime = &minTime
+		}
+		if tx.TimeBounds.MaxTime != 0 {
+			maxTime := time.Unix(int64(tx.TimeBounds.MaxTime), 0).UTC()
+			decoded.MaxTime = &maxTime
+		}
+	}
+	switch tx.Memo.Type {
+	case xdr.MemoTypeMemoText:
+		decoded.Memo = *tx.Memo.Text
+	case xdr.MemoTypeMemoId:
+		decoded.Memo = fmt.Sprintf("%d", *tx.Memo.Id)
+	case xdr.MemoTypeMemoHash:
+		decoded.Memo = fmt.Sprintf("%x", *tx.Memo.Hash)
+	case xdr.MemoTypeMemoReturn:
+		decoded.Memo = fmt.Sprintf("%x", *tx.Memo.RetHash)
+	}
+
+	accounts := []xdr.AccountId{tx.SourceAccount}
+	for _, op := range tx.Operations {
+		operation := DecodedOperation{Type: enumName(op.Body.Type.String(), "OperationType")}
+		if op.SourceAccount != nil {
+			operation.SourceAccount = op.SourceAccount.Address()
+			accounts = append(accounts, *op.SourceAccount)
+		}
+		r := txRepWriter{}
+		//the fields of operations txrep does not support are left out
+		if r.operationBody("", op.Body) == nil {
+			operation.Fields = make(map[string]string)
+			for _, line := range strings.Split(strings.TrimSpace(r.String()), "\n") {
+				parts := strings.SplitN(line, ": ", 2)
+				if len(parts) == 2 {
+					operation.Fields[parts[0]] = parts[1]
+				}
+			}
+		}
+		decoded.Operations = append(decoded.Operations, operation)
+	}
+
+	for _, signature := range envelope.Signatures {
+		decodedSignature := DecodedSignature{Hint: fmt.Sprintf("%x", signature.Hint), Signature: fmt.Sprintf("%x", []byte(signature.Signature))}
+		preimageHash := sha256.Sum256(signature.Signature)
+		decodedSignature.HashX = bytes.Equal(preimageHash[28:], signature.Hint[:])
+		for _, account := range accounts {
+			key, ok := account.GetEd25519()
+			if ok && bytes.Equal(key[28:], signature.Hint[:]) {
+				decodedSignature.Signer = account.Address()
+				break
+			}
+		}
+		decoded.Signatures = append(decoded.Signatures, decodedSignature)
+	}
+	return
+}
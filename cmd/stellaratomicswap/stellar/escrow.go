@@ -0,0 +1,184 @@
+package stellar
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/strkey"
+	"github.com/threefoldtech/atomicswap/swapcrypto"
+	"golang.org/x/crypto/curve25519"
+)
+
+const escrowVersion = 1
+
+//escrowKDFDomain separates the escrow encryption keys from any other use of the shared secret
+const escrowKDFDomain = "stellaratomicswap secret escrow v1"
+
+//EncryptSecret encrypts a swap secret to the key of a stellar address.
+//The ed25519 key of the address is converted to its curve25519 form for an ephemeral Diffie-Hellman,
+//the secret is sealed with AES-256-GCM. The secret hash is included so the recipient can check
+//what the escrow is for before decrypting.
+func EncryptSecret(secret []byte, recipientAddress string) (escrow string, err error) {
+	recipientPublicKey, err := strkey.Decode(strkey.VersionByteAccountID, recipientAddress)
+	if err != nil {
+		return
+	}
+	recipientCurveKey, err := curvePublicKey(recipientPublicKey)
+	if err != nil {
+		return
+	}
+	var ephemeralPrivateKey, ephemeralPublicKey, shared [32]byte
+	if _, err = rand.Read(ephemeralPrivateKey[:]); err != nil {
+		return
+	}
+	curve25519.ScalarBaseMult(&ephemeralPublicKey, &ephemeralPrivateKey)
+	curve25519.ScalarMult(&shared, &ephemeralPrivateKey, &recipientCurveKey)
+	if isZeroKey(shared) {
+		return "", errors.New("The key of the recipient is a low order point, it can not receive an escrow")
+	}
+
+	secretHash := sha256.Sum256(secret)
+	aead, err := escrowCipher(shared, ephemeralPublicKey, recipientPublicKey)
+	if err != nil {
+		return
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return
+	}
+	var b bytes.Buffer
+	b.WriteByte(escrowVersion)
+	b.Write(secretHash[:])
+	b.Write(ephemeralPublicKey[:])
+	b.Write(nonce)
+	b.Write(aead.Seal(nil, nonce, secret, secretHash[:]))
+	return base64.StdEncoding.EncodeToString(b.Bytes()), nil
+}
+
+//EscrowSecretHash returns the hash of the secret in an escrow without decrypting it
+func EscrowSecretHash(escrow string) (secretHash []byte, err error) {
+	raw, err := base64.StdEncoding.DecodeString(escrow)
+	if err != nil {
+		return
+	}
+	if len(raw) < 1+sha256.Size || raw[0] != escrowVersion {
+		return nil, errors.New("Invalid or unsupported escrow")
+	}
+	return raw[1 : 1+sha256.Size], nil
+}
+
+//DecryptSecret decrypts an escrow created with EncryptSecret for the address of the keypair
+//and verifies the secret matches the secret hash in it
+func DecryptSecret(escrow string, recipient *keypair.Full) (secret []byte, err error) {
+	raw, err := base64.StdEncoding.DecodeString(escrow)
+	if err != nil {
+		return
+	}
+	const headerSize = 1 + sha256.Size + 32 + 12
+	if len(raw) < headerSize || raw[0] != escrowVersion {
+		return nil, errors.New("Invalid or unsupported escrow")
+	}
+	secretHash := raw[1 : 1+sha256.Size]
+	var ephemeralPublicKey [32]byte
+	copy(ephemeralPublicKey[:], raw[1+sha256.Size:1+sha256.Size+32])
+	nonce := raw[1+sha256.Size+32 : headerSize]
+
+	rawSeed, err := strkey.Decode(strkey.VersionByteSeed, recipient.Seed())
+	if err != nil {
+		return
+	}
+	recipientPublicKey, err := strkey.Decode(strkey.VersionByteAccountID, recipient.Address())
+	if err != nil {
+		return
+	}
+	curvePrivateKey := curvePrivateKey(rawSeed)
+	var shared [32]byte
+	curve25519.ScalarMult(&shared, &curvePrivateKey, &ephemeralPublicKey)
+	if isZeroKey(shared) {
+		return nil, errors.New("Invalid escrow, its ephemeral key is a low order point")
+	}
+	aead, err := escrowCipher(shared, ephemeralPublicKey, recipientPublicKey)
+	if err != nil {
+		return
+	}
+	secret, err = aead.Open(nil, nonce, raw[headerSize:], secretHash)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to decrypt the escrow with the key of %s", recipient.Address())
+	}
+	if !swapcrypto.SHA256.Verify(secret, secretHash) {
+		return nil, errors.New("The escrowed secret does not match its secret hash")
+	}
+	return
+}
+
+func escrowCipher(shared [32]byte, ephemeralPublicKey [32]byte, recipientPublicKey []byte) (cipher.AEAD, error) {
+	h := sha256.New()
+	h.Write([]byte(escrowKDFDomain))
+	h.Write(ephemeralPublicKey[:])
+	h.Write(recipientPublicKey)
+	h.Write(shared[:])
+	block, err := aes.NewCipher(h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+//isZeroKey returns true if a Diffie-Hellman shared secret is all zeros, the result of a low order public key.
+//Such a secret does not depend on the private key, anyone could compute it.
+func isZeroKey(shared [32]byte) bool {
+	var zero [32]byte
+	return subtle.ConstantTimeCompare(shared[:], zero[:]) == 1
+}
+
+//fieldPrime is 2^255 - 19
+var fieldPrime = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+
+//curvePublicKey converts an ed25519 public key to the curve25519 u coordinate, u = (1 + y) / (1 - y)
+func curvePublicKey(publicKey []byte) (u [32]byte, err error) {
+	if len(publicKey) != 32 {
+		err = errors.New("Invalid ed25519 public key")
+		return
+	}
+	//the key is the little endian y coordinate with the sign of x in the top bit
+	bigEndian := make([]byte, 32)
+	for i := range publicKey {
+		bigEndian[31-i] = publicKey[i]
+	}
+	bigEndian[0] &= 0x7f
+	y := new(big.Int).SetBytes(bigEndian)
+	denominator := new(big.Int).Sub(big.NewInt(1), y)
+	denominator.Mod(denominator, fieldPrime)
+	if denominator.Sign() == 0 {
+		err = errors.New("Invalid ed25519 public key")
+		return
+	}
+	numerator := new(big.Int).Add(big.NewInt(1), y)
+	result := numerator.Mul(numerator, denominator.ModInverse(denominator, fieldPrime))
+	result.Mod(result, fieldPrime)
+	resultBytes := result.Bytes()
+	for i := range resultBytes {
+		u[i] = resultBytes[len(resultBytes)-1-i]
+	}
+	return
+}
+
+//curvePrivateKey converts an ed25519 seed to the curve25519 scalar of the same key
+func curvePrivateKey(seed []byte) (scalar [32]byte) {
+	h := sha512.Sum512(seed)
+	copy(scalar[:], h[:32])
+	scalar[0] &= 248
+	scalar[31] &= 127
+	scalar[31] |= 64
+	return
+}
@@ -0,0 +1,30 @@
+package stellar
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/strkey"
+)
+
+//holdingKeyInfo is the HKDF info prefix for deriving holding account keys, the counterparty address is appended
+const holdingKeyInfo = "stellaratomicswap holding account v1 "
+
+//DeriveHoldingKeyPair derives the holding account keypair of a swap from the funding account's seed,
+//the secret hash and the counterparty with HKDF-SHA256, so it can be derived again when the local state is lost.
+func DeriveHoldingKeyPair(fundingKeyPair *keypair.Full, secretHash []byte, counterPartyAddress string) (pair *keypair.Full, err error) {
+	fundingSeed, err := strkey.Decode(strkey.VersionByteSeed, fundingKeyPair.Seed())
+	if err != nil {
+		return
+	}
+	//HKDF extract with the secret hash as salt, a single expand block gives the 32 byte seed
+	extract := hmac.New(sha256.New, secretHash)
+	extract.Write(fundingSeed)
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	expand.Write([]byte(holdingKeyInfo + counterPartyAddress))
+	expand.Write([]byte{1})
+	var seed [32]byte
+	copy(seed[:], expand.Sum(nil))
+	return keypair.FromRawSeed(seed)
+}
@@ -0,0 +1,80 @@
+package stellar
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/stellar/go/keypair"
+	bip39 "github.com/tyler-smith/go-bip39"
+)
+
+//DefaultDerivationPath is the SEP-0005 path of the first account of a wallet
+const DefaultDerivationPath = "m/44'/148'/0'"
+
+const hardenedOffset = 0x80000000
+
+//IsMnemonic returns true if the argument looks like a mnemonic phrase rather than a strkey encoded seed
+func IsMnemonic(s string) bool {
+	return len(strings.Fields(s)) > 1
+}
+
+//KeyPairFromMnemonic derives a full keypair from a BIP39 mnemonic following SEP-0005
+//(SLIP-0010 ed25519 derivation, only hardened path elements are allowed)
+func KeyPairFromMnemonic(mnemonic string, password string, path string) (pair *keypair.Full, err error) {
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, password)
+	if err != nil {
+		err = fmt.Errorf("Invalid mnemonic: %v", err)
+		return
+	}
+	indexes, err := parseDerivationPath(path)
+	if err != nil {
+		return
+	}
+	key, chainCode := slip10MasterKey(seed)
+	for _, index := range indexes {
+		key, chainCode = slip10HardenedChild(key, chainCode, index)
+	}
+	var rawSeed [32]byte
+	copy(rawSeed[:], key)
+	return keypair.FromRawSeed(rawSeed)
+}
+
+func parseDerivationPath(path string) (indexes []uint32, err error) {
+	elements := strings.Split(path, "/")
+	if len(elements) < 2 || elements[0] != "m" {
+		return nil, fmt.Errorf("Invalid derivation path %q", path)
+	}
+	for _, element := range elements[1:] {
+		if !strings.HasSuffix(element, "'") {
+			return nil, fmt.Errorf("Invalid derivation path %q: ed25519 only supports hardened derivation", path)
+		}
+		index, err := strconv.ParseUint(strings.TrimSuffix(element, "'"), 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid derivation path element %q: %v", element, err)
+		}
+		indexes = append(indexes, uint32(index)+hardenedOffset)
+	}
+	return
+}
+
+func slip10MasterKey(seed []byte) (key []byte, chainCode []byte) {
+	mac := hmac.New(sha512.New, []byte("ed25519 seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	return sum[:32], sum[32:]
+}
+
+func slip10HardenedChild(key []byte, chainCode []byte, index uint32) (childKey []byte, childChainCode []byte) {
+	data := make([]byte, 1+len(key)+4)
+	copy(data[1:], key)
+	binary.BigEndian.PutUint32(data[1+len(key):], index)
+	mac := hmac.New(sha512.New, chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+	return sum[:32], sum[32:]
+}
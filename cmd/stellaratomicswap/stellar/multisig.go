@@ -0,0 +1,60 @@
+package stellar
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/strkey"
+	"github.com/stellar/go/xdr"
+)
+
+//CollectedWeight returns the summed weight of the account signers that are satisfied by the signatures
+//of a transaction with the given hash and the signers that are still missing.
+//A pre-authorized transaction signer only counts for its own transaction and can not sign another one,
+//so it is never missing. A hashX signer is satisfied by attaching its preimage, the secret of a swap.
+func CollectedWeight(signatures []xdr.DecoratedSignature, hash [32]byte, signers []horizon.Signer) (weight int32, missing []horizon.Signer) {
+	for _, signer := range signers {
+		if signer.Weight == 0 {
+			continue
+		}
+		if isSignerSatisfied(signer, signatures, hash) {
+			weight += signer.Weight
+		} else if signer.Type != horizon.KeyTypeNames[strkey.VersionByteHashTx] {
+			missing = append(missing, signer)
+		}
+	}
+	return
+}
+
+func isSignerSatisfied(signer horizon.Signer, signatures []xdr.DecoratedSignature, hash [32]byte) bool {
+	switch signer.Type {
+	case horizon.KeyTypeNames[strkey.VersionByteAccountID]:
+		kp, err := keypair.Parse(signer.Key)
+		if err != nil {
+			return false
+		}
+		hint := kp.Hint()
+		for _, signature := range signatures {
+			if signature.Hint == xdr.SignatureHint(hint) && kp.Verify(hash[:], signature.Signature) == nil {
+				return true
+			}
+		}
+	case horizon.KeyTypeNames[strkey.VersionByteHashX]:
+		signerHash, err := strkey.Decode(strkey.VersionByteHashX, signer.Key)
+		if err != nil {
+			return false
+		}
+		for _, signature := range signatures {
+			preimageHash := sha256.Sum256(signature.Signature)
+			if bytes.Equal(preimageHash[:], signerHash) {
+				return true
+			}
+		}
+	case horizon.KeyTypeNames[strkey.VersionByteHashTx]:
+		signerHash, err := strkey.Decode(strkey.VersionByteHashTx, signer.Key)
+		return err == nil && bytes.Equal(signerHash, hash[:])
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package stellar
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/stellar/go/network"
+)
+
+//Sep7TransactionURI returns a SEP-0007 web+stellar:tx URI that asks a wallet to sign and submit the transaction envelope.
+//pubkey is the account that should sign and can be empty, the network passphrase is only included for other networks than the public one.
+func Sep7TransactionURI(txe string, networkPassphrase string, pubkey string, msg string) string {
+	params := []string{"xdr=" + sep7Escape(txe)}
+	if pubkey != "" {
+		params = append(params, "pubkey="+sep7Escape(pubkey))
+	}
+	if msg != "" {
+		params = append(params, "msg="+sep7Escape(msg))
+	}
+	if networkPassphrase != network.PublicNetworkPassphrase {
+		params = append(params, "network_passphrase="+sep7Escape(networkPassphrase))
+	}
+	return "web+stellar:tx?" + strings.Join(params, "&")
+}
+
+//sep7Escape url encodes a value, with %20 for spaces since wallets do not all decode + as a space
+func sep7Escape(value string) string {
+	return strings.Replace(url.QueryEscape(value), "+", "%20", -1)
+}
@@ -0,0 +1,81 @@
+package stellar
+
+import (
+	"fmt"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/txnbuild"
+	"github.com/stellar/go/xdr"
+)
+
+//Signer signs transaction hashes for a stellar account.
+//A *keypair.Full is a Signer but the key can also be held by an external service.
+type Signer interface {
+	Address() string
+	Sign(input []byte) ([]byte, error)
+}
+
+//SignDecorated signs the input and adds the hint of the signer's public key
+func SignDecorated(signer Signer, input []byte) (sig xdr.DecoratedSignature, err error) {
+	if kp, ok := signer.(*keypair.Full); ok {
+		return kp.SignDecorated(input)
+	}
+	kp, err := keypair.Parse(signer.Address())
+	if err != nil {
+		return
+	}
+	signature, err := signer.Sign(input)
+	if err != nil {
+		return
+	}
+	if err = kp.Verify(input, signature); err != nil {
+		err = fmt.Errorf("Signer %s returned an invalid signature: %v", signer.Address(), err)
+		return
+	}
+	sig = xdr.DecoratedSignature{
+		Hint:      xdr.SignatureHint(kp.Hint()),
+		Signature: xdr.Signature(signature),
+	}
+	return
+}
+
+//SignEncode signs an already built transaction with the signers and returns the base64 encoded envelope
+func SignEncode(tx *txnbuild.Transaction, signers ...Signer) (txe string, err error) {
+	hash, err := tx.Hash()
+	if err != nil {
+		err = fmt.Errorf("Failed to hash the transaction: %v", err)
+		return
+	}
+	txe, err = tx.Base64()
+	if err != nil {
+		return
+	}
+	return SignEnvelope(txe, hash, signers...)
+}
+
+//SignEnvelope adds the signatures of the signers to a base64 encoded transaction envelope with the given transaction hash
+func SignEnvelope(txe string, hash [32]byte, signers ...Signer) (signedTxe string, err error) {
+	var envelope xdr.TransactionEnvelope
+	if err = xdr.SafeUnmarshalBase64(txe, &envelope); err != nil {
+		return
+	}
+	for _, signer := range signers {
+		var sig xdr.DecoratedSignature
+		sig, err = SignDecorated(signer, hash[:])
+		if err != nil {
+			err = fmt.Errorf("Failed to sign the transaction with %s: %v", signer.Address(), err)
+			return
+		}
+		envelope.Signatures = append(envelope.Signatures, sig)
+	}
+	return xdr.MarshalBase64(envelope)
+}
+
+//BuildSignEncode builds the transaction, signs it with the signers and returns the base64 encoded envelope
+func BuildSignEncode(tx *txnbuild.Transaction, signers ...Signer) (txe string, err error) {
+	if err = tx.Build(); err != nil {
+		err = fmt.Errorf("Failed to build the transaction: %v", err)
+		return
+	}
+	return SignEncode(tx, signers...)
+}
@@ -5,10 +5,12 @@ package stellar
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"reflect"
 	"strings"
 
 	"github.com/stellar/go/protocols/horizon/effects"
+	"github.com/stellar/go/protocols/horizon/operations"
 
 	"github.com/stellar/go/clients/horizonclient"
 	"github.com/stellar/go/keypair"
@@ -20,6 +22,12 @@ import (
 //NativeAssetType is the value rturned by the horizon client for a the native asset
 const NativeAssetType = "native"
 
+//MaxHashXPreimageSize is the maximum size of the preimage of a hash(x) signer, the secret of a swap
+const MaxHashXPreimageSize = 64
+
+//pageLimit is the maximum number of records horizon returns in a page
+const pageLimit = 200
+
 //GenerateKeyPair creates a new stellar full keypair
 func GenerateKeyPair() (pair *keypair.Full, err error) {
 
@@ -54,15 +62,29 @@ func getIDFromLink(href string) string {
 	return splittedHref[len(splittedHref)-1]
 }
 
+//IsNotFoundError returns true if horizon answered the request with a 404
+func IsNotFoundError(err error) bool {
+	he, ok := err.(*horizonclient.Error)
+	return ok && he.Problem.Status == http.StatusNotFound
+}
+
 //GetAccountDebitediTransactions returns the transactions that debited the account
 func GetAccountDebitediTransactions(accountAddress string, client horizonclient.ClientInterface) (transactions []horizon.Transaction, err error) {
-	effectRequest := horizonclient.EffectRequest{ForAccount: accountAddress, Limit: 100}
+	effectRequest := horizonclient.EffectRequest{ForAccount: accountAddress, Limit: pageLimit}
 	effect, err := client.Effects(effectRequest)
 	if err != nil {
 		return
 	}
+	records := effect.Embedded.Records
+	//Old accounts can have more effects than fit in a page, follow the pages back to the start
+	for len(effect.Embedded.Records) == pageLimit {
+		if effect, err = client.NextEffectsPage(effect); err != nil {
+			return
+		}
+		records = append(records, effect.Embedded.Records...)
+	}
 	transactions = make([]horizon.Transaction, 0, 1)
-	for _, effectRecord := range effect.Embedded.Records {
+	for _, effectRecord := range records {
 		if effectRecord.GetType() != effects.EffectTypeNames[effects.EffectAccountDebited] {
 			continue
 		}
@@ -86,6 +108,86 @@ func GetAccountDebitediTransactions(accountAddress string, client horizonclient.
 	return
 }
 
+//AccountLifecycle holds the operations that created and merged an account
+type AccountLifecycle struct {
+	Created *operations.CreateAccount
+	//Merged is nil as long as the account exists
+	Merged *operations.AccountMerge
+}
+
+//GetAccountOperations returns all operations involving an account, oldest first, also after it is merged
+func GetAccountOperations(accountAddress string, client horizonclient.ClientInterface) (records []operations.Operation, err error) {
+	operationRequest := horizonclient.OperationRequest{ForAccount: accountAddress, Limit: pageLimit}
+	page, err := client.Operations(operationRequest)
+	if err != nil {
+		return
+	}
+	records = page.Embedded.Records
+	for len(page.Embedded.Records) == pageLimit {
+		if page, err = client.NextOperationsPage(page); err != nil {
+			return
+		}
+		records = append(records, page.Embedded.Records...)
+	}
+	return
+}
+
+//GetAccountLifecycle finds the operations that created and merged an account, also after it is merged
+func GetAccountLifecycle(accountAddress string, client horizonclient.ClientInterface) (lifecycle AccountLifecycle, err error) {
+	records, err := GetAccountOperations(accountAddress, client)
+	if err != nil {
+		return
+	}
+	for _, record := range records {
+		switch operation := record.(type) {
+		case operations.CreateAccount:
+			if operation.Account == accountAddress && operation.TransactionSuccessful {
+				lifecycle.Created = &operation
+			}
+		case operations.AccountMerge:
+			if operation.Account == accountAddress && operation.TransactionSuccessful {
+				lifecycle.Merged = &operation
+			}
+		}
+	}
+	return
+}
+
+//CreditedAmount is an amount of an asset credited to an account
+type CreditedAmount struct {
+	Asset  string `json:"asset"`
+	Amount string `json:"amount"`
+}
+
+//GetCreditedAmounts returns what a transaction credited to an account.
+//For the account merge of a redeem or refund this is what is really received,
+//the contract value minus the fees plus the released reserves.
+func GetCreditedAmounts(transactionHash string, accountAddress string, client horizonclient.ClientInterface) (credited []CreditedAmount, err error) {
+	effectRequest := horizonclient.EffectRequest{ForTransaction: transactionHash, Limit: pageLimit}
+	effect, err := client.Effects(effectRequest)
+	if err != nil {
+		return
+	}
+	for _, effectRecord := range effect.Embedded.Records {
+		if effectRecord.GetType() != effects.EffectTypeNames[effects.EffectAccountCredited] {
+			continue
+		}
+		realEffect, ok := effectRecord.(effects.AccountCredited)
+		if !ok {
+			return nil, fmt.Errorf("effect is not a horizon protocol AccountCredited effect but a %v", reflect.TypeOf(effectRecord))
+		}
+		if realEffect.Account != accountAddress {
+			continue
+		}
+		asset := "XLM"
+		if realEffect.Asset.Type != NativeAssetType {
+			asset = realEffect.Asset.Code + ":" + realEffect.Asset.Issuer
+		}
+		credited = append(credited, CreditedAmount{Asset: asset, Amount: realEffect.Amount})
+	}
+	return
+}
+
 //GetNetworkPassPhrase fetches the networkPassphrase from a client
 func GetNetworkPassPhrase(client horizonclient.Client) (networkpassphrase string, err error) {
 	r, err := client.Root()
@@ -98,7 +200,7 @@ func GetNetworkPassPhrase(client horizonclient.Client) (networkpassphrase string
 }
 
 //CreateAccountTransaction creates the transactio for creating a new account
-func CreateAccountTransaction(newccountAddress string, xlmAmount string, fundingAccount *horizon.Account, network string) (createAccountTransaction txnbuild.Transaction, err error) {
+func CreateAccountTransaction(newccountAddress string, xlmAmount string, fundingAccount *horizon.Account, network string, baseFee uint32, timebounds txnbuild.Timebounds) (createAccountTransaction txnbuild.Transaction, err error) {
 
 	accountCreationOperation := txnbuild.CreateAccount{
 		Destination:   newccountAddress,
@@ -112,21 +214,55 @@ func CreateAccountTransaction(newccountAddress string, xlmAmount string, funding
 			&accountCreationOperation,
 		},
 		Network:    network,
-		Timebounds: txnbuild.NewInfiniteTimeout(), //TODO: Use a real timeout
+		Timebounds: timebounds,
+		BaseFee:    baseFee,
 	}
 
 	return
 }
 
+//SubmitError is returned by SubmitTransaction when horizon did not accept a transaction
+type SubmitError struct {
+	//Transient is true when the transaction was not rejected but horizon failed or could not be reached,
+	//the transaction may still be applied and submitting it again can succeed
+	Transient bool
+	//BadSequence is true when horizon rejected the transaction with tx_bad_seq,
+	//building it again on a freshly loaded source account can succeed
+	BadSequence bool
+	detail      string
+}
+
+func (e *SubmitError) Error() string {
+	return e.detail
+}
+
+//IsTransientError returns true if a transaction submission failed because of a problem with horizon or the connection to it
+func IsTransientError(err error) bool {
+	var se *SubmitError
+	return errors.As(err, &se) && se.Transient
+}
+
+//IsBadSequenceError returns true if a transaction was rejected because the sequence number of its source account moved on
+func IsBadSequenceError(err error) bool {
+	var se *SubmitError
+	return errors.As(err, &se) && se.BadSequence
+}
+
 //SubmitTransaction submits the transactio and provides a better formatted error on failure
 func SubmitTransaction(tx string, client horizonclient.ClientInterface) (txSuccess horizon.TransactionSuccess, err error) {
 
 	txSuccess, err = client.SubmitTransactionXDR(tx)
 	if err != nil {
-		he := err.(*horizonclient.Error)
+		he, ok := err.(*horizonclient.Error)
+		if !ok {
+			err = &SubmitError{Transient: true, detail: err.Error()}
+			return
+		}
 		errordetail := (he.Problem.Detail)
+		badSequence := false
 		if resultcodes, err2 := he.ResultCodes(); err2 == nil {
 			errordetail = fmt.Sprintf("%s\nResultcodes:\n%s\n", errordetail, resultcodes)
+			badSequence = resultcodes.TransactionCode == "tx_bad_seq"
 		}
 
 		errordetail = fmt.Sprintf("%sExtras:\n", errordetail)
@@ -134,7 +270,11 @@ func SubmitTransaction(tx string, client horizonclient.ClientInterface) (txSucce
 			errordetail = fmt.Sprintf("%s%s\n", errordetail, ex)
 		}
 
-		err = errors.New(errordetail)
+		err = &SubmitError{
+			Transient:   he.Problem.Status >= http.StatusInternalServerError || he.Problem.Status == http.StatusTooManyRequests,
+			BadSequence: badSequence,
+			detail:      errordetail,
+		}
 	}
 	return
 }
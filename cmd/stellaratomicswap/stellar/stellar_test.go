@@ -1,13 +1,27 @@
 package stellar
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stellar/go/clients/horizon"
 	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/network"
+	hprotocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/protocols/horizon/effects"
+	"github.com/stellar/go/protocols/horizon/operations"
+	"github.com/stellar/go/strkey"
+	"github.com/stellar/go/support/render/problem"
+	"github.com/stellar/go/txnbuild"
+	"github.com/stellar/go/xdr"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/curve25519"
 )
 
 func TestGenerateKeyPair(t *testing.T) {
@@ -28,3 +42,320 @@ func TestGetAccount(t *testing.T) {
 		assert.Equal(t, address, account.GetAccountID())
 	}
 }
+
+func TestGetAccountLifecycle(t *testing.T) {
+	address := "GAA6DAO4EQAEUK7MWQAIVGAMO3IBCY5WU5YZM6KSDKZJ7ONLRGIRSL7M"
+	funder := "GBWIGRJGNTPJBK4TMJTRGMEZYIGIHI5S4JMD3VJVYLP3HT5SM6SFULWM"
+	page := operations.OperationsPage{}
+	page.Embedded.Records = []operations.Operation{
+		operations.CreateAccount{Base: operations.Base{TransactionSuccessful: true}, Funder: funder, Account: address},
+		operations.SetOptions{Base: operations.Base{TransactionSuccessful: true}},
+		operations.AccountMerge{Base: operations.Base{TransactionSuccessful: true, TransactionHash: "aa"}, Account: address, Into: funder},
+	}
+	client := horizonclient.MockClient{}
+	client.Mock.On("Operations", horizonclient.OperationRequest{ForAccount: address, Limit: pageLimit}).Return(page, nil)
+	lifecycle, err := GetAccountLifecycle(address, &client)
+	if assert.NoError(t, err) && assert.NotNil(t, lifecycle.Created) && assert.NotNil(t, lifecycle.Merged) {
+		assert.Equal(t, funder, lifecycle.Created.Funder)
+		assert.Equal(t, funder, lifecycle.Merged.Into)
+		assert.Equal(t, "aa", lifecycle.Merged.TransactionHash)
+	}
+}
+
+func TestGetAccountDebitediTransactionsPaging(t *testing.T) {
+	address := "GAA6DAO4EQAEUK7MWQAIVGAMO3IBCY5WU5YZM6KSDKZJ7ONLRGIRSL7M"
+	//A full first page without debits, the redeem is on the next page
+	first := effects.EffectsPage{}
+	for i := 0; i < pageLimit; i++ {
+		first.Embedded.Records = append(first.Embedded.Records, effects.Base{Type: effects.EffectTypeNames[effects.EffectAccountCredited]})
+	}
+	debited := effects.AccountDebited{Base: effects.Base{Type: effects.EffectTypeNames[effects.EffectAccountDebited]}}
+	debited.Links.Operation.Href = "https://horizon/operations/42"
+	second := effects.EffectsPage{}
+	second.Embedded.Records = []effects.Effect{debited}
+
+	client := horizonclient.MockClient{}
+	client.Mock.On("Effects", horizonclient.EffectRequest{ForAccount: address, Limit: pageLimit}).Return(first, nil)
+	client.Mock.On("NextEffectsPage", first).Return(second, nil)
+	client.Mock.On("OperationDetail", "42").Return(operations.AccountMerge{Base: operations.Base{TransactionHash: "aa"}}, nil)
+	client.Mock.On("TransactionDetail", "aa").Return(hprotocol.Transaction{Hash: "aa"}, nil)
+	transactions, err := GetAccountDebitediTransactions(address, &client)
+	if assert.NoError(t, err) && assert.Len(t, transactions, 1) {
+		assert.Equal(t, "aa", transactions[0].Hash)
+	}
+	client.Mock.AssertExpectations(t)
+}
+
+func TestSubmitTransactionTransientErrors(t *testing.T) {
+	for _, tc := range []struct {
+		err       error
+		transient bool
+	}{
+		{errors.New("connection refused"), true},
+		{&horizonclient.Error{Problem: problem.P{Status: 504, Detail: "timeout"}}, true},
+		{&horizonclient.Error{Problem: problem.P{Status: 429}}, true},
+		{&horizonclient.Error{Problem: problem.P{Status: 400, Detail: "tx_failed"}}, false},
+	} {
+		client := horizonclient.MockClient{}
+		client.Mock.On("SubmitTransactionXDR", "AAAA").Return(hprotocol.TransactionSuccess{}, tc.err)
+		_, err := SubmitTransaction("AAAA", &client)
+		if assert.Error(t, err) {
+			assert.Equal(t, tc.transient, IsTransientError(fmt.Errorf("Failed to publish: %w", err)), tc.err.Error())
+		}
+	}
+}
+
+func TestSubmitTransactionBadSequence(t *testing.T) {
+	badSeq := &horizonclient.Error{Problem: problem.P{Status: 400, Extras: map[string]interface{}{
+		"result_codes": map[string]interface{}{"transaction": "tx_bad_seq"},
+	}}}
+	client := horizonclient.MockClient{}
+	client.Mock.On("SubmitTransactionXDR", "AAAA").Return(hprotocol.TransactionSuccess{}, badSeq)
+	_, err := SubmitTransaction("AAAA", &client)
+	assert.True(t, IsBadSequenceError(fmt.Errorf("Failed to publish: %w", err)))
+	assert.False(t, IsTransientError(err))
+}
+
+func TestDeriveHoldingKeyPair(t *testing.T) {
+	funder := keypair.MustParse("SBPQUZ6G4FZNWFHKUWC5BEYWF6R52E3SEP7R3GWYSM2XTKGF5LNTWW4R").(*keypair.Full)
+	secretHash := sha256.Sum256([]byte("secret"))
+	counterparty := "GAA6DAO4EQAEUK7MWQAIVGAMO3IBCY5WU5YZM6KSDKZJ7ONLRGIRSL7M"
+	first, err := DeriveHoldingKeyPair(funder, secretHash[:], counterparty)
+	if !assert.NoError(t, err) {
+		return
+	}
+	//HKDF-SHA256 (RFC 5869) of the raw funding seed, salted with the secret hash, with info "stellaratomicswap holding account v1 <counterparty>"
+	assert.Equal(t, "SCJXRMMQPEJECZHHMXRK5TGDVY4JXZYZ33MLESB7NRCEHOFMAJLYGKPW", first.Seed())
+	again, err := DeriveHoldingKeyPair(funder, secretHash[:], counterparty)
+	if assert.NoError(t, err) {
+		assert.Equal(t, first.Seed(), again.Seed())
+	}
+	other, err := DeriveHoldingKeyPair(funder, secretHash[:], funder.Address())
+	if assert.NoError(t, err) {
+		assert.NotEqual(t, first.Address(), other.Address())
+	}
+	assert.NotEqual(t, funder.Address(), first.Address())
+}
+
+func TestSep7TransactionURI(t *testing.T) {
+	uri := Sep7TransactionURI("AAAA+/8=", network.TestNetworkPassphrase, "GAA6DAO4EQAEUK7MWQAIVGAMO3IBCY5WU5YZM6KSDKZJ7ONLRGIRSL7M", "")
+	assert.Equal(t, "web+stellar:tx?xdr=AAAA%2B%2F8%3D&pubkey=GAA6DAO4EQAEUK7MWQAIVGAMO3IBCY5WU5YZM6KSDKZJ7ONLRGIRSL7M&network_passphrase=Test%20SDF%20Network%20%3B%20September%202015", uri)
+	assert.Equal(t, "web+stellar:tx?xdr=AAAA", Sep7TransactionURI("AAAA", network.PublicNetworkPassphrase, "", ""))
+}
+
+func TestKeyPairFromMnemonic(t *testing.T) {
+	mnemonic := "illness spike retreat truth genius clock brain pass fit cave bargain toe"
+	pair, err := KeyPairFromMnemonic(mnemonic, "", DefaultDerivationPath)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "GDRXE2BQUC3AZNPVFSCEZ76NJ3WWL25FYFK6RGZGIEKWE4SOOHSUJUJ6", pair.Address())
+		assert.Equal(t, "SBGWSG6BTNCKCOB3DIFBGCVMUPQFYPA2G4O34RMTB343OYPXU5DJDVMN", pair.Seed())
+	}
+	_, err = KeyPairFromMnemonic(mnemonic, "", "m/44'/148/0'")
+	assert.Error(t, err)
+	_, err = KeyPairFromMnemonic("illness spike retreat", "", DefaultDerivationPath)
+	assert.Error(t, err)
+}
+
+type externalSigner struct {
+	*keypair.Full
+}
+
+func TestSignEncode(t *testing.T) {
+	kp := keypair.MustParse("SBGWSG6BTNCKCOB3DIFBGCVMUPQFYPA2G4O34RMTB343OYPXU5DJDVMN").(*keypair.Full)
+	newTx := func() txnbuild.Transaction {
+		return txnbuild.Transaction{
+			SourceAccount: &txnbuild.SimpleAccount{AccountID: kp.Address(), Sequence: 1},
+			Operations:    []txnbuild.Operation{&txnbuild.BumpSequence{BumpTo: 2}},
+			Timebounds:    txnbuild.NewInfiniteTimeout(),
+			Network:       network.TestNetworkPassphrase,
+		}
+	}
+	tx := newTx()
+	expected, err := tx.BuildSignEncode(kp)
+	if !assert.NoError(t, err) {
+		return
+	}
+	tx = newTx()
+	txe, err := BuildSignEncode(&tx, externalSigner{kp})
+	if assert.NoError(t, err) {
+		assert.Equal(t, expected, txe)
+	}
+}
+
+func TestDecodeTransaction(t *testing.T) {
+	kp := keypair.MustParse("SBGWSG6BTNCKCOB3DIFBGCVMUPQFYPA2G4O34RMTB343OYPXU5DJDVMN").(*keypair.Full)
+	tx := txnbuild.Transaction{
+		SourceAccount: &txnbuild.SimpleAccount{AccountID: kp.Address(), Sequence: 1},
+		Operations:    []txnbuild.Operation{&txnbuild.BumpSequence{BumpTo: 5}},
+		Timebounds:    txnbuild.NewTimebounds(1000, 0),
+		Network:       network.TestNetworkPassphrase,
+	}
+	if !assert.NoError(t, tx.Build()) || !assert.NoError(t, tx.Sign(kp)) || !assert.NoError(t, tx.SignHashX([]byte("secret"))) {
+		return
+	}
+	hash, err := tx.Hash()
+	if !assert.NoError(t, err) {
+		return
+	}
+	decoded, err := DecodeTransaction(*tx.TxEnvelope(), network.TestNetworkPassphrase)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, fmt.Sprintf("%x", hash), decoded.Hash)
+	assert.Equal(t, int64(2), decoded.Sequence)
+	assert.Equal(t, int64(1000), decoded.MinTime.Unix())
+	assert.Nil(t, decoded.MaxTime)
+	if assert.Len(t, decoded.Operations, 1) {
+		assert.Equal(t, "BUMP_SEQUENCE", decoded.Operations[0].Type)
+		assert.Equal(t, "5", decoded.Operations[0].Fields["bumpSequenceOp.bumpTo"])
+	}
+	if assert.Len(t, decoded.Signatures, 2) {
+		assert.Equal(t, kp.Address(), decoded.Signatures[0].Signer)
+		assert.False(t, decoded.Signatures[0].HashX)
+		assert.True(t, decoded.Signatures[1].HashX)
+	}
+}
+
+func TestCollectedWeight(t *testing.T) {
+	kp := keypair.MustParse("SBGWSG6BTNCKCOB3DIFBGCVMUPQFYPA2G4O34RMTB343OYPXU5DJDVMN").(*keypair.Full)
+	preimage := []byte("secret")
+	preimageHash := sha256.Sum256(preimage)
+	hashxAddress, _ := CreateHashxAddress(preimageHash[:])
+	hash := sha256.Sum256([]byte("transaction"))
+	signers := []hprotocol.Signer{
+		{Key: kp.Address(), Weight: 1, Type: "ed25519_public_key"},
+		{Key: hashxAddress, Weight: 1, Type: "sha256_hash"},
+	}
+	weight, missing := CollectedWeight(nil, hash, signers)
+	assert.Equal(t, int32(0), weight)
+	assert.Len(t, missing, 2)
+
+	sig, err := kp.SignDecorated(hash[:])
+	if !assert.NoError(t, err) {
+		return
+	}
+	signatures := []xdr.DecoratedSignature{sig, {Signature: preimage}}
+	weight, missing = CollectedWeight(signatures, hash, signers)
+	assert.Equal(t, int32(2), weight)
+	assert.Empty(t, missing)
+
+	//the refund transaction signer of a holding account can not sign the redeem transaction
+	refundHash := sha256.Sum256([]byte("refund transaction"))
+	refundSigner, err := strkey.Encode(strkey.VersionByteHashTx, refundHash[:])
+	if !assert.NoError(t, err) {
+		return
+	}
+	signers = append(signers, hprotocol.Signer{Key: refundSigner, Weight: 2, Type: "preauth_tx"})
+	weight, missing = CollectedWeight([]xdr.DecoratedSignature{sig}, hash, signers)
+	assert.Equal(t, int32(1), weight)
+	if assert.Len(t, missing, 1) {
+		assert.Equal(t, hashxAddress, missing[0].Key)
+	}
+	weight, missing = CollectedWeight(signatures, hash, signers)
+	assert.Equal(t, int32(2), weight)
+	assert.Empty(t, missing)
+	weight, missing = CollectedWeight(nil, refundHash, signers)
+	assert.Equal(t, int32(2), weight)
+	assert.Len(t, missing, 2)
+}
+
+func TestTxRep(t *testing.T) {
+	source := "GDRXE2BQUC3AZNPVFSCEZ76NJ3WWL25FYFK6RGZGIEKWE4SOOHSUJUJ6"
+	destination := "GAA6DAO4EQAEUK7MWQAIVGAMO3IBCY5WU5YZM6KSDKZJ7ONLRGIRSL7M"
+	holdingAccount := &txnbuild.SimpleAccount{AccountID: source, Sequence: 41}
+	tx := txnbuild.Transaction{
+		SourceAccount: holdingAccount,
+		Operations: []txnbuild.Operation{
+			&txnbuild.ManageData{Name: "atomicswap", SourceAccount: holdingAccount},
+			&txnbuild.AccountMerge{Destination: destination, SourceAccount: holdingAccount},
+		},
+		Timebounds: txnbuild.NewTimebounds(1500000000, 0),
+		Network:    network.TestNetworkPassphrase,
+	}
+	if !assert.NoError(t, tx.Build()) {
+		return
+	}
+	txe, err := tx.Base64()
+	if !assert.NoError(t, err) {
+		return
+	}
+	var envelope xdr.TransactionEnvelope
+	if !assert.NoError(t, xdr.SafeUnmarshalBase64(txe, &envelope)) {
+		return
+	}
+	txrep, err := TxRep(envelope)
+	if assert.NoError(t, err) {
+		assert.Equal(t, `tx.sourceAccount: `+source+`
+tx.fee: 200
+tx.seqNum: 42
+tx.timeBounds._present: true
+tx.timeBounds.minTime: 1500000000
+tx.timeBounds.maxTime: 0
+tx.memo.type: MEMO_NONE
+tx.operations.len: 2
+tx.operations[0].sourceAccount._present: true
+tx.operations[0].sourceAccount: `+source+`
+tx.operations[0].body.type: MANAGE_DATA
+tx.operations[0].body.manageDataOp.dataName: "atomicswap"
+tx.operations[0].body.manageDataOp.dataValue._present: false
+tx.operations[1].sourceAccount._present: true
+tx.operations[1].sourceAccount: `+source+`
+tx.operations[1].body.type: ACCOUNT_MERGE
+tx.operations[1].body.destination: `+destination+`
+tx.ext.v: 0
+signatures.len: 0
+`, txrep)
+	}
+}
+
+func TestEscrow(t *testing.T) {
+	recipient := keypair.MustParse("SBGWSG6BTNCKCOB3DIFBGCVMUPQFYPA2G4O34RMTB343OYPXU5DJDVMN").(*keypair.Full)
+	other, _ := keypair.Random()
+	secret := sha256.Sum256([]byte("secret"))
+	secretHash := sha256.Sum256(secret[:])
+
+	escrow, err := EncryptSecret(secret[:], recipient.Address())
+	if !assert.NoError(t, err) {
+		return
+	}
+	hash, err := EscrowSecretHash(escrow)
+	if assert.NoError(t, err) {
+		assert.Equal(t, secretHash[:], hash)
+	}
+	decrypted, err := DecryptSecret(escrow, recipient)
+	if assert.NoError(t, err) {
+		assert.Equal(t, secret[:], decrypted)
+	}
+	_, err = DecryptSecret(escrow, other)
+	assert.Error(t, err)
+}
+
+func TestCurveKeyConversion(t *testing.T) {
+	//RFC 8032 test 1, the curve25519 key is the X25519 public key of the converted scalar, as libsodium's
+	//crypto_sign_ed25519_pk_to_curve25519 and crypto_sign_ed25519_sk_to_curve25519 return
+	seed, _ := hex.DecodeString("9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60")
+	publicKey, _ := hex.DecodeString("d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a")
+	u, err := curvePublicKey(publicKey)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "d85e07ec22b0ad881537c2f44d662d1a143cf830c57aca4305d85c7a90f6b62e", hex.EncodeToString(u[:]))
+	}
+	scalar := curvePrivateKey(seed)
+	assert.Equal(t, "307c83864f2833cb427a2ef1c00a013cfdff2768d980c0a3a520f006904de94f", hex.EncodeToString(scalar[:]))
+	var derived [32]byte
+	curve25519.ScalarBaseMult(&derived, &scalar)
+	assert.Equal(t, u, derived)
+
+	//y = 1 is the neutral element, it has no curve25519 form
+	identity := make([]byte, 32)
+	identity[0] = 1
+	_, err = curvePublicKey(identity)
+	assert.Error(t, err)
+	//y = -1 is a point of order 2, every shared secret with it is zero
+	lowOrder, _ := hex.DecodeString("ecffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f")
+	address, err := strkey.Encode(strkey.VersionByteAccountID, lowOrder)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = EncryptSecret([]byte("secret"), address)
+	assert.Error(t, err)
+}
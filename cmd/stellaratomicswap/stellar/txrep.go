@@ -0,0 +1,152 @@
+package stellar
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/stellar/go/xdr"
+)
+
+//TxRep returns the SEP-0011 txrep representation of a transaction envelope.
+//Only the operations used by the atomic swaps and a few common ones are supported.
+func TxRep(envelope xdr.TransactionEnvelope) (txrep string, err error) {
+	r := txRepWriter{}
+	tx := envelope.Tx
+	r.line("tx.sourceAccount", tx.SourceAccount.Address())
+	r.line("tx.fee", tx.Fee)
+	r.line("tx.seqNum", tx.SeqNum)
+	r.present("tx.timeBounds", tx.TimeBounds != nil)
+	if tx.TimeBounds != nil {
+		r.line("tx.timeBounds.minTime", tx.TimeBounds.MinTime)
+		r.line("tx.timeBounds.maxTime", tx.TimeBounds.MaxTime)
+	}
+	r.line("tx.memo.type", enumName(tx.Memo.Type.String(), "MemoType"))
+	switch tx.Memo.Type {
+	case xdr.MemoTypeMemoText:
+		r.line("tx.memo.text", strconv.Quote(*tx.Memo.Text))
+	case xdr.MemoTypeMemoId:
+		r.line("tx.memo.id", *tx.Memo.Id)
+	case xdr.MemoTypeMemoHash:
+		r.line("tx.memo.hash", fmt.Sprintf("%x", *tx.Memo.Hash))
+	case xdr.MemoTypeMemoReturn:
+		r.line("tx.memo.retHash", fmt.Sprintf("%x", *tx.Memo.RetHash))
+	}
+	r.line("tx.operations.len", len(tx.Operations))
+	for i, op := range tx.Operations {
+		prefix := fmt.Sprintf("tx.operations[%d].", i)
+		r.present(prefix+"sourceAccount", op.SourceAccount != nil)
+		if op.SourceAccount != nil {
+			r.line(prefix+"sourceAccount", op.SourceAccount.Address())
+		}
+		r.line(prefix+"body.type", enumName(op.Body.Type.String(), "OperationType"))
+		if err = r.operationBody(prefix+"body.", op.Body); err != nil {
+			return
+		}
+	}
+	r.line("tx.ext.v", 0)
+	r.line("signatures.len", len(envelope.Signatures))
+	for i, signature := range envelope.Signatures {
+		r.line(fmt.Sprintf("signatures[%d].hint", i), fmt.Sprintf("%x", signature.Hint))
+		r.line(fmt.Sprintf("signatures[%d].signature", i), fmt.Sprintf("%x", []byte(signature.Signature)))
+	}
+	return r.String(), nil
+}
+
+type txRepWriter struct {
+	strings.Builder
+}
+
+func (r *txRepWriter) line(key string, value interface{}) {
+	fmt.Fprintf(r, "%s: %v\n", key, value)
+}
+
+func (r *txRepWriter) present(key string, present bool) {
+	r.line(key+"._present", present)
+}
+
+func (r *txRepWriter) operationBody(prefix string, body xdr.OperationBody) error {
+	switch body.Type {
+	case xdr.OperationTypeCreateAccount:
+		op := body.MustCreateAccountOp()
+		r.line(prefix+"createAccountOp.destination", op.Destination.Address())
+		r.line(prefix+"createAccountOp.startingBalance", op.StartingBalance)
+	case xdr.OperationTypePayment:
+		op := body.MustPaymentOp()
+		r.line(prefix+"paymentOp.destination", op.Destination.Address())
+		r.line(prefix+"paymentOp.asset", txRepAsset(op.Asset))
+		r.line(prefix+"paymentOp.amount", op.Amount)
+	case xdr.OperationTypeChangeTrust:
+		op := body.MustChangeTrustOp()
+		r.line(prefix+"changeTrustOp.line", txRepAsset(op.Line))
+		r.line(prefix+"changeTrustOp.limit", op.Limit)
+	case xdr.OperationTypeAccountMerge:
+		destination := body.MustDestination()
+		r.line(prefix+"destination", destination.Address())
+	case xdr.OperationTypeManageData:
+		op := body.MustManageDataOp()
+		r.line(prefix+"manageDataOp.dataName", strconv.Quote(string(op.DataName)))
+		r.present(prefix+"manageDataOp.dataValue", op.DataValue != nil)
+		if op.DataValue != nil {
+			r.line(prefix+"manageDataOp.dataValue", fmt.Sprintf("%x", []byte(*op.DataValue)))
+		}
+	case xdr.OperationTypeBumpSequence:
+		op := body.MustBumpSequenceOp()
+		r.line(prefix+"bumpSequenceOp.bumpTo", op.BumpTo)
+	case xdr.OperationTypeSetOptions:
+		op := body.MustSetOptionsOp()
+		prefix += "setOptionsOp."
+		r.present(prefix+"inflationDest", op.InflationDest != nil)
+		if op.InflationDest != nil {
+			r.line(prefix+"inflationDest", op.InflationDest.Address())
+		}
+		r.optionalUint32(prefix+"clearFlags", op.ClearFlags)
+		r.optionalUint32(prefix+"setFlags", op.SetFlags)
+		r.optionalUint32(prefix+"masterWeight", op.MasterWeight)
+		r.optionalUint32(prefix+"lowThreshold", op.LowThreshold)
+		r.optionalUint32(prefix+"medThreshold", op.MedThreshold)
+		r.optionalUint32(prefix+"highThreshold", op.HighThreshold)
+		r.present(prefix+"homeDomain", op.HomeDomain != nil)
+		if op.HomeDomain != nil {
+			r.line(prefix+"homeDomain", strconv.Quote(string(*op.HomeDomain)))
+		}
+		r.present(prefix+"signer", op.Signer != nil)
+		if op.Signer != nil {
+			r.line(prefix+"signer.key", op.Signer.Key.Address())
+			r.line(prefix+"signer.weight", op.Signer.Weight)
+		}
+	default:
+		return fmt.Errorf("txrep of %s operations is not supported", body.Type)
+	}
+	return nil
+}
+
+func (r *txRepWriter) optionalUint32(key string, value *xdr.Uint32) {
+	r.present(key, value != nil)
+	if value != nil {
+		r.line(key, *value)
+	}
+}
+
+func txRepAsset(asset xdr.Asset) string {
+	var assetType xdr.AssetType
+	var code, issuer string
+	if err := asset.Extract(&assetType, &code, &issuer); err != nil || assetType == xdr.AssetTypeAssetTypeNative {
+		return "XLM"
+	}
+	return code + ":" + issuer
+}
+
+//enumName converts an xdr enum name like OperationTypeAccountMerge to ACCOUNT_MERGE
+func enumName(name string, typePrefix string) string {
+	name = strings.TrimPrefix(name, typePrefix)
+	var b strings.Builder
+	for i, c := range name {
+		if i > 0 && unicode.IsUpper(c) {
+			b.WriteRune('_')
+		}
+		b.WriteRune(unicode.ToUpper(c))
+	}
+	return b.String()
+}
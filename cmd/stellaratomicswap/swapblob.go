@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/stellar/go/amount"
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/txnbuild"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/swapdb"
+	"github.com/threefoldtech/atomicswap/swapcrypto"
+)
+
+//maxHoldingSetupOperations is the maximum number of operations of a signing options transaction paid by a native holding account:
+//the data entries, the home domain, the signers and their weights. Setups of older versions submitted it from the holding account.
+const maxHoldingSetupOperations = 7
+
+//setupFeeAllowance is the maximum in stroops a native holding account can have paid in fees for its setup,
+//the signing options transaction bids at most -maxbasefee per operation
+func setupFeeAllowance() int64 {
+	return int64(clampBaseFee(int(*maxBaseFeeFlag))) * maxHoldingSetupOperations
+}
+
+//swapBlob holds everything the counterparty needs to verify a contract and take part in the swap
+type swapBlob struct {
+	Network        string `json:"network"`
+	HoldingAccount string `json:"holdingaccount"`
+	//Funder receives the refund and is the address the counterparty has to lock its funds for
+	Funder            string `json:"funder"`
+	Recipient         string `json:"recipient"`
+	SecretHash        string `json:"secrethash"`
+	RefundTransaction string `json:"refundtransaction"`
+	Locktime          int64  `json:"locktime"`
+	Amount            string `json:"amount"`
+	Asset             string `json:"asset"`
+	//SecretSize is only known when the initiator exports the swap, 0 means the default size
+	SecretSize int `json:"secretsize,omitempty"`
+	//Capabilities of the exporting tool, blobs of older versions do not have them
+	Capabilities *capabilities `json:"capabilities,omitempty"`
+}
+
+func (blob swapBlob) encode() string {
+	jsonBlob, _ := json.Marshal(blob)
+	return base64.StdEncoding.EncodeToString(jsonBlob)
+}
+
+func decodeSwapBlob(encoded string) (blob swapBlob, err error) {
+	jsonBlob, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return
+	}
+	//Unknown fields of newer versions are allowed, the capabilities tell whether the swap is supported
+	err = json.Unmarshal(jsonBlob, &blob)
+	return
+}
+
+type exportSwapCmd struct {
+	holdingAccount string
+}
+
+func (cmd *exportSwapCmd) runCommand(client horizonclient.ClientInterface) error {
+	return cmd.runOfflineCommand()
+}
+
+func (cmd *exportSwapCmd) runOfflineCommand() error {
+	if *swapDBFlag == "" {
+		return errors.New("The swap database is disabled")
+	}
+	var record swapdb.Swap
+	err := withSwapDB(func(db *swapdb.DB) (err error) {
+		record, err = db.Get(cmd.holdingAccount)
+		return
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to get swap %s: %v", cmd.holdingAccount, err)
+	}
+	if record.RefundTransaction == "" {
+		return fmt.Errorf("The setup of swap %s did not complete, its status is %s", record.HoldingAccount, record.Status)
+	}
+	blob := swapBlob{
+		Network:           record.Network,
+		HoldingAccount:    record.HoldingAccount,
+		Funder:            record.Funder,
+		Recipient:         record.Counterparty,
+		SecretHash:        record.SecretHash,
+		RefundTransaction: record.RefundTransaction,
+		Locktime:          record.Locktime.Unix(),
+		Amount:            record.Amount,
+		Asset:             record.Asset,
+		SecretSize:        len(record.Secret) / 2,
+	}
+	own := ownCapabilities()
+	blob.Capabilities = &own
+	if *automatedFlag {
+		jsonoutput, _ := json.Marshal(map[string]string{"swap": blob.encode()})
+		fmt.Println(string(jsonoutput))
+	} else {
+		fmt.Println(blob.encode())
+	}
+	return nil
+}
+
+//importSwapCmd validates a swap blob against the holding account on the chain
+type importSwapCmd struct {
+	blob swapBlob
+}
+
+func (cmd *importSwapCmd) runCommand(client horizonclient.ClientInterface) error {
+	blob := cmd.blob
+	if blob.Network != networkName(targetNetwork) {
+		return fmt.Errorf("The swap is on the %s network", blob.Network)
+	}
+	peer := legacyCapabilities()
+	if blob.Capabilities != nil {
+		peer = *blob.Capabilities
+	}
+	agreed, err := negotiateCapabilities(ownCapabilities(), peer)
+	if err != nil {
+		return err
+	}
+	assetKind := assetKindCredit
+	if blob.Asset == "XLM" {
+		assetKind = assetKindNative
+	}
+	secretSize := swapcrypto.SecretSize
+	if blob.SecretSize != 0 {
+		secretSize = blob.SecretSize
+	}
+	if err = agreed.supports(assetKind, swapcrypto.SHA256.String(), secretSize); err != nil {
+		return err
+	}
+	refundTx, err := txnbuild.TransactionFromXDR(blob.RefundTransaction)
+	if err != nil {
+		return fmt.Errorf("Failed to decode the refund transaction: %v", err)
+	}
+	audit, err := auditHoldingAccount(blob.HoldingAccount, &refundTx, targetNetwork, client)
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(audit.secretHash) != blob.SecretHash {
+		return fmt.Errorf("The holding account has secret hash %x instead of %s", audit.secretHash, blob.SecretHash)
+	}
+	if audit.recipientAddress != blob.Recipient {
+		return fmt.Errorf("The holding account can be redeemed by %s instead of %s", audit.recipientAddress, blob.Recipient)
+	}
+	if audit.refundAddress != blob.Funder {
+		return fmt.Errorf("The holding account is refunded to %s instead of %s", audit.refundAddress, blob.Funder)
+	}
+	if audit.lockTime != blob.Locktime {
+		return fmt.Errorf("The refund transaction has locktime %d instead of %d", audit.lockTime, blob.Locktime)
+	}
+	if err = checkHoldingAccountBalance(audit, blob.Asset, blob.Amount); err != nil {
+		return err
+	}
+
+	if *automatedFlag {
+		jsonoutput, _ := json.Marshal(blob)
+		fmt.Println(string(jsonoutput))
+		return nil
+	}
+	fmt.Printf("Contract address:  %s\n", blob.HoldingAccount)
+	fmt.Printf("Contract value:    %s %s\n", blob.Amount, blob.Asset)
+	fmt.Printf("Recipient address: %s\n", blob.Recipient)
+	fmt.Printf("Refund address:    %s\n\n", blob.Funder)
+	fmt.Printf("Secret hash: %s\n\n", blob.SecretHash)
+	fmt.Printf("Locktime: %v\n", time.Unix(blob.Locktime, 0).UTC())
+	fmt.Printf("Refund transaction:\n%s\n", blob.RefundTransaction)
+	return nil
+}
+
+//checkHoldingAccountBalance verifies the holding account holds the amount of the asset.
+//A native holding account paid the fees of its setup from the amount.
+func checkHoldingAccountBalance(audit contractAudit, asset string, swapAmount string) error {
+	expected, err := amount.ParseInt64(swapAmount)
+	if err != nil {
+		return fmt.Errorf("Invalid amount %s: %v", swapAmount, err)
+	}
+	if asset == "XLM" {
+		expected -= setupFeeAllowance()
+	}
+	for _, balance := range audit.holdingAccount.Balances {
+		name := "XLM"
+		if balance.Asset.Type != stellar.NativeAssetType {
+			name = balance.Code + ":" + balance.Issuer
+		}
+		if name != asset {
+			continue
+		}
+		held, err := amount.ParseInt64(balance.Balance)
+		if err != nil {
+			return err
+		}
+		if held < expected {
+			return fmt.Errorf("The holding account holds %s %s instead of %s", balance.Balance, asset, swapAmount)
+		}
+		return nil
+	}
+	return fmt.Errorf("The holding account does not hold %s", asset)
+}
@@ -0,0 +1,726 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/txnbuild"
+	"github.com/threefoldtech/atomicswap/adapter"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/swapdb"
+)
+
+//swapdTokenVariable is the environment variable holding the bearer token clients of the daemon have to present
+const swapdTokenVariable = "SWAPD_TOKEN"
+
+//maxRequestSize is the maximum size of a request body
+const maxRequestSize = 1 << 20
+
+//swapStatus values
+const (
+	swapStatusInitiating    = "initiating"
+	swapStatusInitiated     = "initiated"
+	swapStatusParticipating = "participating"
+	swapStatusParticipated  = "participated"
+	swapStatusRedeemed      = "redeemed"
+	swapStatusRefunded      = "refunded"
+	swapStatusFailed        = "failed"
+)
+
+//trackedSwap is the state of a swap the daemon created a contract for
+type trackedSwap struct {
+	ID                string            `json:"id"`
+	Role              string            `json:"role"`
+	Status            string            `json:"status"`
+	Error             string            `json:"error,omitempty"`
+	Counterparty      string            `json:"counterparty"`
+	Amount            string            `json:"amount"`
+	SecretHash        string            `json:"secrethash"`
+	Contract          *adapter.Contract `json:"contract,omitempty"`
+	RedeemTransaction string            `json:"redeemtransaction,omitempty"`
+	RefundTransaction string            `json:"refundtransaction,omitempty"`
+	Created           time.Time         `json:"created"`
+	Updated           time.Time         `json:"updated"`
+	//secret is only known to the initiator and only returned by the initiate call
+	secret []byte
+}
+
+type swapdCmd struct {
+	signer        stellar.Signer
+	asset         txnbuild.Asset
+	listenAddress string
+	//tlsCert and tlsKey are the files of the TLS certificate and key, without them the API is only served on loopback
+	tlsCert string
+	tlsKey  string
+	//notifiers are replaced when the configuration is reloaded
+	notifiers     notifiers
+	notifiersLock sync.RWMutex
+	//events feeds the event endpoints, it is one of the notifiers and kept on a reload
+	events *eventStream
+	//feeTopUp keeps the fee account funded, nil if it is not enabled
+	feeTopUp *feeTopUp
+
+	token   string
+	adapter *stellarAdapter
+	//fundingLock serializes the transactions of the funding account to avoid sequence number conflicts
+	fundingLock sync.Mutex
+	swapsLock   sync.RWMutex
+	swaps       map[string]*trackedSwap
+
+	idempotencyLock    sync.Mutex
+	idempotentRequests map[string]*idempotentRequest
+}
+
+func (cmd *swapdCmd) runCommand(client horizonclient.ClientInterface) error {
+	cmd.token = os.Getenv(swapdTokenVariable)
+	if cmd.token == "" {
+		return fmt.Errorf("%s is not set, the daemon does not run without authentication", swapdTokenVariable)
+	}
+	//all requests share the horizon client and its pool of kept alive connections
+	cmd.adapter = &stellarAdapter{signer: cmd.signer, asset: cmd.asset, client: client, network: targetNetwork}
+	cmd.swaps = make(map[string]*trackedSwap)
+	cmd.idempotentRequests = make(map[string]*idempotentRequest)
+	go cmd.evictIdempotentRequests()
+	cmd.events = newEventStream()
+	cmd.notifiers = append(cmd.notifiers, cmd.events)
+	go cmd.reloadOnHangup()
+	if cmd.feeTopUp != nil {
+		go cmd.watchFeeAccount()
+	}
+
+	halt, err := getHalt()
+	if err != nil {
+		return fmt.Errorf("Failed to read the halt switch: %v", err)
+	}
+	if halt.Halted {
+		logger.Warnf("swapd is halted since %s: %s", halt.Since.Format(time.RFC3339), halt.Reason)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/initiate", cmd.handle(http.MethodPost, cmd.initiate))
+	mux.HandleFunc("/participate", cmd.handle(http.MethodPost, cmd.participate))
+	mux.HandleFunc("/redeem", cmd.handle(http.MethodPost, cmd.redeem))
+	mux.HandleFunc("/refund", cmd.handle(http.MethodPost, cmd.refund))
+	mux.HandleFunc("/audit", cmd.handle(http.MethodPost, cmd.audit))
+	mux.HandleFunc("/extractsecret", cmd.handle(http.MethodPost, cmd.extractSecret))
+	mux.HandleFunc("/status", cmd.handle(http.MethodGet, cmd.status))
+	mux.HandleFunc("/halt", cmd.handle(http.MethodPost, cmd.halt))
+	mux.HandleFunc("/unhalt", cmd.handle(http.MethodPost, cmd.unhalt))
+	mux.HandleFunc("/reload", cmd.handle(http.MethodPost, func(r *http.Request) (interface{}, error) {
+		if err := cmd.reloadConfiguration(); err != nil {
+			return nil, err
+		}
+		return struct {
+			Policies map[string]assetPolicy `json:"policies"`
+		}{currentAmountPolicies()}, nil
+	}))
+	mux.HandleFunc("/events", cmd.swapEvents)
+	mux.HandleFunc("/swaps/", cmd.swapEvents)
+	mux.HandleFunc("/health", cmd.handle(http.MethodGet, func(r *http.Request) (interface{}, error) {
+		if _, err := client.Root(); err != nil {
+			return nil, fmt.Errorf("Horizon is not reachable: %v", err)
+		}
+		halt, err := getHalt()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read the halt switch: %v", err)
+		}
+		return struct {
+			Address string      `json:"address"`
+			Halt    swapdb.Halt `json:"halt"`
+		}{cmd.signer.Address(), halt}, nil
+	}))
+	server := &http.Server{
+		Addr:              cmd.listenAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if cmd.tlsCert != "" {
+		server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		logger.WithField("address", cmd.signer.Address()).Infof("swapd listening on https://%s", cmd.listenAddress)
+		return server.ListenAndServeTLS(cmd.tlsCert, cmd.tlsKey)
+	}
+	logger.WithField("address", cmd.signer.Address()).Infof("swapd listening on http://%s", cmd.listenAddress)
+	return server.ListenAndServe()
+}
+
+//isLoopbackAddress returns true if a listen address only accepts connections from the host itself.
+//An empty host listens on all interfaces.
+func isLoopbackAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil || host == "" {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+//apiError is an error with the http status code to return it with
+type apiError struct {
+	code int
+	err  error
+}
+
+func (e apiError) Error() string {
+	return e.err.Error()
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return apiError{code: http.StatusBadRequest, err: fmt.Errorf(format, args...)}
+}
+
+//handle authenticates the request and writes the result or the error of the handler as json
+func (cmd *swapdCmd) handle(method string, handler func(r *http.Request) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cmd.authorize(w, r, method) {
+			return
+		}
+		if key := r.Header.Get(idempotencyKeyHeader); key != "" && method == http.MethodPost {
+			writeResponse(w, cmd.idempotentCall(key, handler, r))
+			return
+		}
+		writeResponse(w, call(handler, r))
+	}
+}
+
+//authorize checks the token and the method of a request and writes the error response if they are wrong
+func (cmd *swapdCmd) authorize(w http.ResponseWriter, r *http.Request, method string) bool {
+	w.Header().Set("Content-Type", "application/json")
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") || subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(cmd.token)) != 1 {
+		writeResponse(w, errorResponse(http.StatusUnauthorized, "unauthorized"))
+		return false
+	}
+	if r.Method != method {
+		writeResponse(w, errorResponse(http.StatusMethodNotAllowed, "method not allowed"))
+		return false
+	}
+	return true
+}
+
+//response is an encoded api response
+type response struct {
+	code int
+	body []byte
+}
+
+func errorResponse(code int, message string) response {
+	body, _ := json.Marshal(map[string]string{"error": message})
+	return response{code: code, body: body}
+}
+
+func writeResponse(w http.ResponseWriter, resp response) {
+	w.WriteHeader(resp.code)
+	w.Write(resp.body)
+	w.Write([]byte("\n"))
+}
+
+func call(handler func(r *http.Request) (interface{}, error), r *http.Request) response {
+	result, err := handler(r)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if apiErr, ok := err.(apiError); ok {
+			code = apiErr.code
+		}
+		logger.WithFields(logrus.Fields{"method": r.Method, "path": r.URL.Path, "status": code}).Warnf("Request failed: %v", err)
+		return errorResponse(code, redact(err.Error()))
+	}
+	body, err := json.Marshal(result)
+	if err != nil {
+		return errorResponse(http.StatusInternalServerError, err.Error())
+	}
+	return response{code: http.StatusOK, body: body}
+}
+
+//idempotencyKeyHeader is the header a client sets to make a POST request safe to retry
+const idempotencyKeyHeader = "Idempotency-Key"
+
+const (
+	//idempotencyKeyTTL is how long the response to a request with an idempotency key is kept
+	idempotencyKeyTTL = 24 * time.Hour
+	//idempotencyEvictInterval is how often expired idempotency keys are removed
+	idempotencyEvictInterval = time.Hour
+)
+
+//idempotentRequest is the outcome of the first request with an idempotency key
+type idempotentRequest struct {
+	requestHash [32]byte
+	expires     time.Time
+	//done is closed when the response is available
+	done     chan struct{}
+	response response
+}
+
+//idempotentCall executes the first request with a key and returns its response for every retry.
+//A retry while the first request is still running waits for its response.
+//Failed requests are not retried either since they might have moved funds before failing,
+//except when the daemon is halted or unavailable: those did not do anything and can be retried later.
+//The responses are stored in the swap database so retries after a restart of the daemon are not executed again.
+func (cmd *swapdCmd) idempotentCall(key string, handler func(r *http.Request) (interface{}, error), r *http.Request) response {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, maxRequestSize))
+	if err != nil {
+		return errorResponse(http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	requestHash := sha256.Sum256(append([]byte(r.URL.Path+"\n"), body...))
+
+	cmd.idempotencyLock.Lock()
+	request, exists := cmd.idempotentRequests[key]
+	if !exists {
+		stored, err := getIdempotentResponse(key)
+		if err == nil {
+			cmd.idempotencyLock.Unlock()
+			if stored.RequestHash != hex.EncodeToString(requestHash[:]) {
+				return errorResponse(http.StatusUnprocessableEntity, "The idempotency key is already used for a different request")
+			}
+			if stored.Code == 0 {
+				return errorResponse(http.StatusConflict, "The first request with this idempotency key was interrupted by a restart of the daemon, check the state of the swap before using a new key")
+			}
+			return response{code: stored.Code, body: stored.Body}
+		}
+		if err != swapdb.ErrNotFound {
+			cmd.idempotencyLock.Unlock()
+			return errorResponse(http.StatusInternalServerError, fmt.Sprintf("Failed to read the idempotency key: %v", err))
+		}
+		request = &idempotentRequest{requestHash: requestHash, expires: time.Now().Add(idempotencyKeyTTL), done: make(chan struct{})}
+		//the key is recorded before executing the request, a retry after a crash must not execute it again
+		if err = putIdempotentResponse(key, swapdb.IdempotentResponse{RequestHash: hex.EncodeToString(requestHash[:]), Expires: request.expires}); err != nil {
+			cmd.idempotencyLock.Unlock()
+			return errorResponse(http.StatusInternalServerError, fmt.Sprintf("Failed to store the idempotency key: %v", err))
+		}
+		cmd.idempotentRequests[key] = request
+	}
+	cmd.idempotencyLock.Unlock()
+	if exists {
+		if request.requestHash != requestHash {
+			return errorResponse(http.StatusUnprocessableEntity, "The idempotency key is already used for a different request")
+		}
+		<-request.done
+		return request.response
+	}
+	request.response = call(handler, r)
+	cmd.idempotencyLock.Lock()
+	if request.response.code == http.StatusServiceUnavailable {
+		delete(cmd.idempotentRequests, key)
+		err = deleteIdempotentResponse(key)
+	} else {
+		err = putIdempotentResponse(key, swapdb.IdempotentResponse{
+			RequestHash: hex.EncodeToString(requestHash[:]),
+			Code:        request.response.code,
+			Body:        request.response.body,
+			Expires:     request.expires,
+		})
+	}
+	cmd.idempotencyLock.Unlock()
+	if err != nil {
+		logger.Warnf("Failed to store the response of idempotency key %s: %v", key, err)
+	}
+	close(request.done)
+	return request.response
+}
+
+//evictIdempotentRequests removes the idempotency keys older than idempotencyKeyTTL, from memory and from the swap database
+func (cmd *swapdCmd) evictIdempotentRequests() {
+	for range time.Tick(idempotencyEvictInterval) {
+		now := time.Now()
+		cmd.idempotencyLock.Lock()
+		for key, request := range cmd.idempotentRequests {
+			select {
+			case <-request.done:
+				if request.expires.Before(now) {
+					delete(cmd.idempotentRequests, key)
+				}
+			default:
+			}
+		}
+		cmd.idempotencyLock.Unlock()
+		if *swapDBFlag == "" {
+			continue
+		}
+		err := withSwapDB(func(db *swapdb.DB) error {
+			deleted, err := db.DeleteExpiredIdempotentResponses(now)
+			if deleted > 0 {
+				logger.Debugf("Removed %d expired idempotency keys", deleted)
+			}
+			return err
+		})
+		if err != nil {
+			logger.Warnf("Failed to remove the expired idempotency keys: %v", err)
+		}
+	}
+}
+
+//getIdempotentResponse returns the stored response of an idempotency key, swapdb.ErrNotFound without the swap database
+func getIdempotentResponse(key string) (response swapdb.IdempotentResponse, err error) {
+	if *swapDBFlag == "" {
+		return response, swapdb.ErrNotFound
+	}
+	err = withSwapDB(func(db *swapdb.DB) (err error) {
+		response, err = db.GetIdempotentResponse(key)
+		return
+	})
+	return
+}
+
+func putIdempotentResponse(key string, response swapdb.IdempotentResponse) error {
+	if *swapDBFlag == "" {
+		return nil
+	}
+	return withSwapDB(func(db *swapdb.DB) error {
+		return db.PutIdempotentResponse(key, response)
+	})
+}
+
+func deleteIdempotentResponse(key string) error {
+	if *swapDBFlag == "" {
+		return nil
+	}
+	return withSwapDB(func(db *swapdb.DB) error {
+		return db.DeleteIdempotentResponse(key)
+	})
+}
+
+func decodeRequest(r *http.Request, request interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxRequestSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(request); err != nil {
+		return badRequest("Invalid request: %v", err)
+	}
+	return nil
+}
+
+func newSwapID() (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id[:]), nil
+}
+
+func (cmd *swapdCmd) track(role string, counterparty string, amount string, status string) (*trackedSwap, error) {
+	id, err := newSwapID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	swap := &trackedSwap{ID: id, Role: role, Status: status, Counterparty: counterparty, Amount: amount, Created: now, Updated: now}
+	cmd.swapsLock.Lock()
+	cmd.swaps[id] = swap
+	cmd.swapsLock.Unlock()
+	return swap, nil
+}
+
+//update changes a tracked swap under the lock
+func (cmd *swapdCmd) update(swap *trackedSwap, change func(swap *trackedSwap)) trackedSwap {
+	cmd.swapsLock.Lock()
+	defer cmd.swapsLock.Unlock()
+	change(swap)
+	swap.Updated = time.Now()
+	return *swap
+}
+
+func (cmd *swapdCmd) lookup(id string) (*trackedSwap, error) {
+	cmd.swapsLock.RLock()
+	defer cmd.swapsLock.RUnlock()
+	swap, ok := cmd.swaps[id]
+	if !ok {
+		return nil, apiError{code: http.StatusNotFound, err: fmt.Errorf("Unknown swap %s", id)}
+	}
+	return swap, nil
+}
+
+//fail marks the swap as failed and returns the error
+func (cmd *swapdCmd) fail(swap *trackedSwap, err error) error {
+	cmd.update(swap, func(swap *trackedSwap) {
+		swap.Status = swapStatusFailed
+		swap.Error = err.Error()
+	})
+	return err
+}
+
+func (cmd *swapdCmd) initiate(r *http.Request) (interface{}, error) {
+	var request struct {
+		Participant string `json:"participant"`
+		Amount      string `json:"amount"`
+	}
+	if err := decodeRequest(r, &request); err != nil {
+		return nil, err
+	}
+	if err := checkNotHalted(); err != nil {
+		return nil, err
+	}
+	if err := parseAddress(request.Participant); err != nil {
+		return nil, badRequest("Invalid participant address: %v", err)
+	}
+	swap, err := cmd.track("initiator", request.Participant, request.Amount, swapStatusInitiating)
+	if err != nil {
+		return nil, err
+	}
+	cmd.fundingLock.Lock()
+	secret, secretHash, contract, err := cmd.adapter.Initiate(request.Participant, request.Amount)
+	cmd.fundingLock.Unlock()
+	if err != nil {
+		return nil, cmd.fail(swap, err)
+	}
+	result := cmd.update(swap, func(swap *trackedSwap) {
+		swap.Status = swapStatusInitiated
+		swap.SecretHash = hex.EncodeToString(secretHash)
+		swap.Contract = &contract
+		swap.secret = secret
+	})
+	cmd.notify(eventInitiated, result)
+	return struct {
+		trackedSwap
+		Secret string `json:"secret"`
+	}{result, hex.EncodeToString(secret)}, nil
+}
+
+func (cmd *swapdCmd) participate(r *http.Request) (interface{}, error) {
+	var request struct {
+		Initiator  string `json:"initiator"`
+		Amount     string `json:"amount"`
+		SecretHash string `json:"secrethash"`
+	}
+	if err := decodeRequest(r, &request); err != nil {
+		return nil, err
+	}
+	if err := checkNotHalted(); err != nil {
+		return nil, err
+	}
+	if err := parseAddress(request.Initiator); err != nil {
+		return nil, badRequest("Invalid initiator address: %v", err)
+	}
+	secretHash, err := hex.DecodeString(request.SecretHash)
+	if err != nil || len(secretHash) != 32 {
+		return nil, badRequest("Invalid secret hash %q", request.SecretHash)
+	}
+	swap, err := cmd.track("participant", request.Initiator, request.Amount, swapStatusParticipating)
+	if err != nil {
+		return nil, err
+	}
+	cmd.update(swap, func(swap *trackedSwap) {
+		swap.SecretHash = request.SecretHash
+	})
+	cmd.fundingLock.Lock()
+	contract, err := cmd.adapter.Participate(request.Initiator, request.Amount, secretHash)
+	cmd.fundingLock.Unlock()
+	if err != nil {
+		return nil, cmd.fail(swap, err)
+	}
+	result := cmd.update(swap, func(swap *trackedSwap) {
+		swap.Status = swapStatusParticipated
+		swap.Contract = &contract
+	})
+	cmd.notify(eventParticipated, result)
+	return result, nil
+}
+
+//redeem claims the counterparty's contract.
+//When the swap id of an initiated swap is given, the secret is optional.
+func (cmd *swapdCmd) redeem(r *http.Request) (interface{}, error) {
+	var request struct {
+		Swap     string           `json:"swap,omitempty"`
+		Contract adapter.Contract `json:"contract"`
+		Secret   string           `json:"secret,omitempty"`
+	}
+	if err := decodeRequest(r, &request); err != nil {
+		return nil, err
+	}
+	if err := checkNotHalted(); err != nil {
+		return nil, err
+	}
+	var swap *trackedSwap
+	var secret []byte
+	if request.Swap != "" {
+		var err error
+		if swap, err = cmd.lookup(request.Swap); err != nil {
+			return nil, err
+		}
+		cmd.swapsLock.RLock()
+		secret = swap.secret
+		cmd.swapsLock.RUnlock()
+	}
+	if request.Secret != "" {
+		var err error
+		registerSecret(request.Secret)
+		if secret, err = hex.DecodeString(request.Secret); err != nil {
+			return nil, badRequest("Invalid secret: %v", err)
+		}
+	}
+	if secret == nil {
+		return nil, badRequest("No secret given and the daemon does not know it")
+	}
+	if err := parseAddress(request.Contract.Address); err != nil {
+		return nil, badRequest("Invalid contract address: %v", err)
+	}
+	cmd.fundingLock.Lock()
+	transactionID, err := cmd.adapter.Redeem(request.Contract, secret)
+	cmd.fundingLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	//the redeem transaction revealed the secret on the chain
+	forgetSecret(hex.EncodeToString(secret))
+	if swap == nil {
+		cmd.notify(eventRedeemed, map[string]string{"contract": request.Contract.Address, "redeemtransaction": transactionID})
+		return map[string]string{"redeemtransaction": transactionID}, nil
+	}
+	result := cmd.update(swap, func(swap *trackedSwap) {
+		swap.Status = swapStatusRedeemed
+		swap.RedeemTransaction = transactionID
+	})
+	cmd.notify(eventRedeemed, result)
+	return result, nil
+}
+
+//refund submits the refund transaction of a tracked swap or of the given contract.
+//Refunds are allowed while the daemon is halted so the locked funds can be recovered.
+func (cmd *swapdCmd) refund(r *http.Request) (interface{}, error) {
+	var request struct {
+		Swap     string            `json:"swap,omitempty"`
+		Contract *adapter.Contract `json:"contract,omitempty"`
+	}
+	if err := decodeRequest(r, &request); err != nil {
+		return nil, err
+	}
+	var swap *trackedSwap
+	contract := request.Contract
+	if request.Swap != "" {
+		var err error
+		if swap, err = cmd.lookup(request.Swap); err != nil {
+			return nil, err
+		}
+		cmd.swapsLock.RLock()
+		contract = swap.Contract
+		cmd.swapsLock.RUnlock()
+	}
+	if contract == nil {
+		return nil, badRequest("Either a swap id or a contract is required")
+	}
+	transactionID, err := cmd.adapter.Refund(*contract)
+	if err != nil {
+		return nil, err
+	}
+	if swap == nil {
+		cmd.notify(eventRefunded, map[string]string{"contract": contract.Address, "refundtransaction": transactionID})
+		return map[string]string{"refundtransaction": transactionID}, nil
+	}
+	var secret []byte
+	result := cmd.update(swap, func(swap *trackedSwap) {
+		swap.Status = swapStatusRefunded
+		swap.RefundTransaction = transactionID
+		secret = swap.secret
+	})
+	if secret != nil {
+		forgetSecret(hex.EncodeToString(secret))
+	}
+	cmd.notify(eventRefunded, result)
+	return result, nil
+}
+
+func (cmd *swapdCmd) audit(r *http.Request) (interface{}, error) {
+	var contract adapter.Contract
+	if err := decodeRequest(r, &contract); err != nil {
+		return nil, err
+	}
+	if err := parseAddress(contract.Address); err != nil {
+		return nil, badRequest("Invalid contract address: %v", err)
+	}
+	audit, err := cmd.adapter.Audit(contract)
+	if err != nil {
+		return nil, err
+	}
+	result := struct {
+		adapter.Audit
+		SecretHash string `json:"secrethash"`
+	}{audit, hex.EncodeToString(audit.SecretHash)}
+	cmd.notify(eventContractAudited, result)
+	return result, nil
+}
+
+func (cmd *swapdCmd) extractSecret(r *http.Request) (interface{}, error) {
+	var request struct {
+		Contract   adapter.Contract `json:"contract"`
+		SecretHash string           `json:"secrethash"`
+	}
+	if err := decodeRequest(r, &request); err != nil {
+		return nil, err
+	}
+	secretHash, err := hex.DecodeString(request.SecretHash)
+	if err != nil || len(secretHash) != 32 {
+		return nil, badRequest("Invalid secret hash %q", request.SecretHash)
+	}
+	secret, err := cmd.adapter.ExtractSecret(request.Contract, secretHash)
+	if err != nil {
+		return nil, err
+	}
+	cmd.notify(eventSecretExtracted, map[string]string{"contract": request.Contract.Address, "secrethash": request.SecretHash})
+	return map[string]string{"secret": hex.EncodeToString(secret)}, nil
+}
+
+//status returns the swap given by the id query parameter or all tracked swaps
+func (cmd *swapdCmd) status(r *http.Request) (interface{}, error) {
+	if id := r.URL.Query().Get("id"); id != "" {
+		swap, err := cmd.lookup(id)
+		if err != nil {
+			return nil, err
+		}
+		cmd.swapsLock.RLock()
+		defer cmd.swapsLock.RUnlock()
+		return *swap, nil
+	}
+	cmd.swapsLock.RLock()
+	swaps := make([]trackedSwap, 0, len(cmd.swaps))
+	for _, swap := range cmd.swaps {
+		swaps = append(swaps, *swap)
+	}
+	cmd.swapsLock.RUnlock()
+	sort.Slice(swaps, func(i, j int) bool { return swaps[i].Created.Before(swaps[j].Created) })
+	return swaps, nil
+}
+
+//halt sets the halt switch, new swaps and redeems are refused until unhalt is called
+func (cmd *swapdCmd) halt(r *http.Request) (interface{}, error) {
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	if err := decodeRequest(r, &request); err != nil {
+		return nil, err
+	}
+	if request.Reason == "" {
+		return nil, badRequest("A reason is required")
+	}
+	halt := newHalt(true, request.Reason)
+	if err := setHalt(halt); err != nil {
+		return nil, fmt.Errorf("Failed to set the halt switch: %v", err)
+	}
+	logger.Warnf("swapd halted: %s", halt.Reason)
+	return halt, nil
+}
+
+func (cmd *swapdCmd) unhalt(r *http.Request) (interface{}, error) {
+	halt := newHalt(false, "")
+	if err := setHalt(halt); err != nil {
+		return nil, fmt.Errorf("Failed to clear the halt switch: %v", err)
+	}
+	logger.Info("swapd resumed")
+	return halt, nil
+}
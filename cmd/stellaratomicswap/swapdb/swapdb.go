@@ -0,0 +1,356 @@
+//Package swapdb stores the state of the swaps created by the tool so it survives process restarts
+package swapdb
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+//The status of a swap
+const (
+	//StatusSettingUp is set before the holding account is created
+	StatusSettingUp = "settingup"
+	//StatusFailed is set when the setup of the holding account did not complete
+	StatusFailed = "failed"
+	//StatusLocked is set when the holding account is set up and holds the swap amount
+	StatusLocked = "locked"
+	//StatusRedeemed is set when the counterparty's contract was redeemed by this tool
+	StatusRedeemed = "redeemed"
+	//StatusRefunded is set when the holding account was refunded
+	StatusRefunded = "refunded"
+)
+
+//ErrNotFound is returned when a swap is not in the database
+var ErrNotFound = errors.New("swap not found")
+
+const swapPrefix = "swap/"
+
+const haltKey = "halt"
+
+//versionKey holds the format version of the database
+const versionKey = "version"
+
+//Version is the format version of the records this package reads and writes.
+//It has to be increased when the layout of the records changes incompatibly, with a migration of the older version in migrations.
+const Version = 1
+
+//migrations[v] converts the records of format version v to version v+1, Open applies them in order
+var migrations = map[int]func(db *leveldb.DB) error{}
+
+const idempotencyPrefix = "idempotency/"
+
+//Swap is the recorded state of a holding account created by the tool
+type Swap struct {
+	//HoldingAccount is the address of the holding account and identifies the swap
+	HoldingAccount string `json:"holdingaccount"`
+	//Role is initiator or participant
+	Role         string `json:"role"`
+	Network      string `json:"network"`
+	Asset        string `json:"asset"`
+	Amount       string `json:"amount"`
+	Funder       string `json:"funder"`
+	Counterparty string `json:"counterparty"`
+	SecretHash   string `json:"secrethash"`
+	//Secret is only known by the initiator
+	Secret string `json:"secret,omitempty"`
+	//HoldingSeed is only kept until the setup of the holding account completes, to be able to resume it
+	HoldingSeed       string    `json:"holdingseed,omitempty"`
+	Locktime          time.Time `json:"locktime"`
+	RefundTransaction string    `json:"refundtransaction,omitempty"`
+	//RefundBaseFee is needed to rebuild the refund transaction when resuming, 0 for swaps recorded before it was set
+	RefundBaseFee  uint32   `json:"refundbasefee,omitempty"`
+	Status         string   `json:"status"`
+	CompletedSteps []string `json:"completedsteps,omitempty"`
+	Error          string   `json:"error,omitempty"`
+	//CounterContract is the counterparty's holding account redeemed by this tool
+	CounterContract   string    `json:"countercontract,omitempty"`
+	RedeemTransaction string    `json:"redeemtransaction,omitempty"`
+	RefundTxHash      string    `json:"refundtxhash,omitempty"`
+	Created           time.Time `json:"created"`
+	Updated           time.Time `json:"updated"`
+}
+
+//Halt is the emergency halt switch of the daemon.
+//While it is set no new swaps are set up and no contracts are redeemed, refunds are still allowed.
+type Halt struct {
+	Halted bool      `json:"halted"`
+	Reason string    `json:"reason,omitempty"`
+	Since  time.Time `json:"since,omitempty"`
+}
+
+//IdempotentResponse is the response of the daemon to the first request with an idempotency key
+type IdempotentResponse struct {
+	//RequestHash is the hex encoded hash of the path and the body of the request
+	RequestHash string `json:"requesthash"`
+	//Code is the http status code, 0 while the request is running
+	Code    int             `json:"code"`
+	Body    json.RawMessage `json:"body,omitempty"`
+	Expires time.Time       `json:"expires"`
+}
+
+//DB is the swap database, only one process can have it open at a time
+type DB struct {
+	db *leveldb.DB
+}
+
+//Open opens or creates the database in the directory at path
+func Open(path string) (*DB, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open the swap database %s: %v", path, err)
+	}
+	if err = checkVersion(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Unable to use the swap database %s: %v", path, err)
+	}
+	return &DB{db: db}, nil
+}
+
+//checkVersion refuses a database written in a newer or unknown format, migrates an older one and records the version in a new one.
+//Databases from before the version key have the layout of version 1.
+func checkVersion(db *leveldb.DB) error {
+	value, err := db.Get([]byte(versionKey), nil)
+	if err == leveldb.ErrNotFound {
+		return db.Put([]byte(versionKey), []byte(strconv.Itoa(Version)), nil)
+	}
+	if err != nil {
+		return err
+	}
+	version, err := strconv.Atoi(string(value))
+	if err != nil || version < 1 {
+		return fmt.Errorf("unknown database format version %q", value)
+	}
+	if version > Version {
+		return fmt.Errorf("the database has format version %d, this version of the tool only reads version %d, upgrade the tool", version, Version)
+	}
+	for ; version < Version; version++ {
+		migrate, ok := migrations[version]
+		if !ok {
+			return fmt.Errorf("no migration from database format version %d", version)
+		}
+		if err = migrate(db); err != nil {
+			return fmt.Errorf("failed to migrate the database from format version %d: %v", version, err)
+		}
+		if err = db.Put([]byte(versionKey), []byte(strconv.Itoa(version+1)), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Close closes the database
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+//Put stores a swap, setting its update time
+func (d *DB) Put(swap *Swap) error {
+	if swap.HoldingAccount == "" {
+		return errors.New("A swap needs a holding account")
+	}
+	swap.Updated = time.Now()
+	if swap.Created.IsZero() {
+		swap.Created = swap.Updated
+	}
+	value, err := json.Marshal(swap)
+	if err != nil {
+		return err
+	}
+	return d.db.Put([]byte(swapPrefix+swap.HoldingAccount), value, nil)
+}
+
+//Get returns the swap of a holding account
+func (d *DB) Get(holdingAccount string) (swap Swap, err error) {
+	value, err := d.db.Get([]byte(swapPrefix+holdingAccount), nil)
+	if err == leveldb.ErrNotFound {
+		err = ErrNotFound
+		return
+	}
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(value, &swap)
+	return
+}
+
+//List returns all swaps, oldest first
+func (d *DB) List() (swaps []Swap, err error) {
+	iter := d.db.NewIterator(util.BytesPrefix([]byte(swapPrefix)), nil)
+	defer iter.Release()
+	for iter.Next() {
+		var swap Swap
+		if err = json.Unmarshal(iter.Value(), &swap); err != nil {
+			return nil, fmt.Errorf("Corrupt swap record %s: %v", iter.Key(), err)
+		}
+		swaps = append(swaps, swap)
+	}
+	if err = iter.Error(); err != nil {
+		return
+	}
+	sort.Slice(swaps, func(i, j int) bool { return swaps[i].Created.Before(swaps[j].Created) })
+	return
+}
+
+//FindBySecretHash returns the swaps with the hex encoded secret hash
+func (d *DB) FindBySecretHash(secretHash string) (swaps []Swap, err error) {
+	all, err := d.List()
+	if err != nil {
+		return
+	}
+	for _, swap := range all {
+		if swap.SecretHash == secretHash {
+			swaps = append(swaps, swap)
+		}
+	}
+	return
+}
+
+//GetHalt returns the halt switch, it is not set if it was never stored
+func (d *DB) GetHalt() (halt Halt, err error) {
+	value, err := d.db.Get([]byte(haltKey), nil)
+	if err == leveldb.ErrNotFound {
+		return Halt{}, nil
+	}
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(value, &halt)
+	return
+}
+
+//PutHalt stores the halt switch
+func (d *DB) PutHalt(halt Halt) error {
+	value, err := json.Marshal(halt)
+	if err != nil {
+		return err
+	}
+	return d.db.Put([]byte(haltKey), value, nil)
+}
+
+//GetIdempotentResponse returns the response stored for an idempotency key, ErrNotFound if there is none
+func (d *DB) GetIdempotentResponse(key string) (response IdempotentResponse, err error) {
+	value, err := d.db.Get([]byte(idempotencyPrefix+key), nil)
+	if err == leveldb.ErrNotFound {
+		err = ErrNotFound
+		return
+	}
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(value, &response)
+	return
+}
+
+//PutIdempotentResponse stores the response for an idempotency key
+func (d *DB) PutIdempotentResponse(key string, response IdempotentResponse) error {
+	value, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	return d.db.Put([]byte(idempotencyPrefix+key), value, nil)
+}
+
+//DeleteIdempotentResponse removes the response stored for an idempotency key
+func (d *DB) DeleteIdempotentResponse(key string) error {
+	return d.db.Delete([]byte(idempotencyPrefix+key), nil)
+}
+
+//DeleteExpiredIdempotentResponses removes the responses that expired before now and returns how many were removed
+func (d *DB) DeleteExpiredIdempotentResponses(now time.Time) (deleted int, err error) {
+	iter := d.db.NewIterator(util.BytesPrefix([]byte(idempotencyPrefix)), nil)
+	defer iter.Release()
+	batch := new(leveldb.Batch)
+	for iter.Next() {
+		var response IdempotentResponse
+		//unreadable entries are removed as well
+		if json.Unmarshal(iter.Value(), &response) != nil || response.Expires.Before(now) {
+			batch.Delete(append([]byte(nil), iter.Key()...))
+			deleted++
+		}
+	}
+	if err = iter.Error(); err != nil {
+		return 0, err
+	}
+	if err = d.db.Write(batch, nil); err != nil {
+		return 0, err
+	}
+	return
+}
+
+//knownStatuses are the statuses a swap record can have
+var knownStatuses = map[string]bool{
+	StatusSettingUp: true,
+	StatusFailed:    true,
+	StatusLocked:    true,
+	StatusRedeemed:  true,
+	StatusRefunded:  true,
+}
+
+//Verify checks that every record in the database can be read by this version of the tool.
+//It returns a description of each record that can not, the database itself is not changed.
+func (d *DB) Verify() (problems []string, err error) {
+	iter := d.db.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		key := string(iter.Key())
+		var problem error
+		switch {
+		case key == versionKey:
+		case key == haltKey:
+			var halt Halt
+			problem = decodeStrict(iter.Value(), &halt)
+		case strings.HasPrefix(key, idempotencyPrefix):
+			var response IdempotentResponse
+			problem = decodeStrict(iter.Value(), &response)
+		case strings.HasPrefix(key, swapPrefix):
+			var swap Swap
+			if problem = decodeStrict(iter.Value(), &swap); problem == nil {
+				problem = verifySwap(strings.TrimPrefix(key, swapPrefix), swap)
+			}
+		default:
+			problem = errors.New("unknown record")
+		}
+		if problem != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", key, problem))
+		}
+	}
+	err = iter.Error()
+	return
+}
+
+//decodeStrict decodes a json record, fields this version does not know mean it was written by a newer one
+func decodeStrict(value []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+//verifySwap checks the fields every swap record has
+func verifySwap(holdingAccount string, swap Swap) error {
+	if swap.HoldingAccount != holdingAccount {
+		return fmt.Errorf("the record is of holding account %q", swap.HoldingAccount)
+	}
+	if swap.Role != "initiator" && swap.Role != "participant" {
+		return fmt.Errorf("unknown role %q", swap.Role)
+	}
+	if !knownStatuses[swap.Status] {
+		return fmt.Errorf("unknown status %q", swap.Status)
+	}
+	if swap.Network == "" {
+		return errors.New("no network")
+	}
+	if _, err := hex.DecodeString(swap.SecretHash); err != nil || swap.SecretHash == "" {
+		return fmt.Errorf("invalid secret hash %q", swap.SecretHash)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package swapdb
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestSwapDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "swapdb")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	db, err := Open(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	_, err = db.Get("GA")
+	assert.Equal(t, ErrNotFound, err)
+
+	first := Swap{HoldingAccount: "GA", SecretHash: "aa", Status: StatusSettingUp}
+	second := Swap{HoldingAccount: "GB", SecretHash: "bb", Status: StatusLocked}
+	assert.NoError(t, db.Put(&first))
+	assert.NoError(t, db.Put(&second))
+	first.Status = StatusLocked
+	assert.NoError(t, db.Put(&first))
+
+	swap, err := db.Get("GA")
+	if assert.NoError(t, err) {
+		assert.Equal(t, StatusLocked, swap.Status)
+		assert.False(t, swap.Created.IsZero())
+	}
+	swaps, err := db.List()
+	if assert.NoError(t, err) && assert.Len(t, swaps, 2) {
+		assert.Equal(t, "GA", swaps[0].HoldingAccount)
+	}
+	swaps, err = db.FindBySecretHash("bb")
+	if assert.NoError(t, err) && assert.Len(t, swaps, 1) {
+		assert.Equal(t, "GB", swaps[0].HoldingAccount)
+	}
+}
+
+func TestHalt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "swapdb")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	db, err := Open(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	halt, err := db.GetHalt()
+	if assert.NoError(t, err) {
+		assert.False(t, halt.Halted)
+	}
+	assert.NoError(t, db.PutHalt(Halt{Halted: true, Reason: "incident"}))
+	halt, err = db.GetHalt()
+	if assert.NoError(t, err) {
+		assert.True(t, halt.Halted)
+		assert.Equal(t, "incident", halt.Reason)
+	}
+	swaps, err := db.List()
+	assert.NoError(t, err)
+	assert.Empty(t, swaps)
+}
+
+func TestIdempotentResponses(t *testing.T) {
+	dir, err := ioutil.TempDir("", "swapdb")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	db, err := Open(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	_, err = db.GetIdempotentResponse("a")
+	assert.Equal(t, ErrNotFound, err)
+	now := time.Now()
+	assert.NoError(t, db.PutIdempotentResponse("a", IdempotentResponse{RequestHash: "aa", Code: 200, Body: []byte(`{"id":"1"}`), Expires: now.Add(-time.Minute)}))
+	assert.NoError(t, db.PutIdempotentResponse("b", IdempotentResponse{RequestHash: "bb", Expires: now.Add(time.Hour)}))
+	response, err := db.GetIdempotentResponse("a")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 200, response.Code)
+		assert.JSONEq(t, `{"id":"1"}`, string(response.Body))
+	}
+
+	deleted, err := db.DeleteExpiredIdempotentResponses(now)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, deleted)
+	}
+	_, err = db.GetIdempotentResponse("a")
+	assert.Equal(t, ErrNotFound, err)
+	response, err = db.GetIdempotentResponse("b")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 0, response.Code)
+	}
+	assert.NoError(t, db.DeleteIdempotentResponse("b"))
+	_, err = db.GetIdempotentResponse("b")
+	assert.Equal(t, ErrNotFound, err)
+	swaps, err := db.List()
+	assert.NoError(t, err)
+	assert.Empty(t, swaps)
+}
+
+func TestVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "swapdb")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	db, err := Open(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	value, err := db.db.Get([]byte(versionKey), nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "1", string(value))
+	}
+	//a database of a newer version of the tool or in an unknown format is not touched
+	for _, version := range []string{"2", "x", "0"} {
+		assert.NoError(t, db.db.Put([]byte(versionKey), []byte(version), nil))
+		assert.NoError(t, db.Close())
+		_, err = Open(dir)
+		assert.Error(t, err, version)
+		raw, err := leveldb.OpenFile(dir, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		db = &DB{db: raw}
+	}
+	assert.NoError(t, db.db.Put([]byte(versionKey), []byte("1"), nil))
+	assert.NoError(t, db.Close())
+	db, err = Open(dir)
+	if assert.NoError(t, err) {
+		db.Close()
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "swapdb")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	db, err := Open(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	assert.NoError(t, db.Put(&Swap{HoldingAccount: "GA", Role: "initiator", Network: "testnet", SecretHash: "aa", Status: StatusLocked}))
+	assert.NoError(t, db.PutHalt(Halt{Halted: true, Reason: "test"}))
+	assert.NoError(t, db.PutIdempotentResponse("key", IdempotentResponse{RequestHash: "bb", Code: 200}))
+	problems, err := db.Verify()
+	if assert.NoError(t, err) {
+		assert.Empty(t, problems)
+	}
+
+	assert.NoError(t, db.Put(&Swap{HoldingAccount: "GB", Role: "initiator", Network: "testnet", SecretHash: "aa", Status: "unknown"}))
+	assert.NoError(t, db.db.Put([]byte(swapPrefix+"GC"), []byte(`{"holdingaccount":"GD","role":"participant","network":"testnet","secrethash":"aa","status":"locked"}`), nil))
+	assert.NoError(t, db.db.Put([]byte(swapPrefix+"GE"), []byte(`{"holdingaccount":"GE","role":"participant","network":"testnet","secrethash":"aa","status":"locked","newfield":1}`), nil))
+	assert.NoError(t, db.db.Put([]byte(swapPrefix+"GF"), []byte(`{`), nil))
+	assert.NoError(t, db.db.Put([]byte("other"), []byte(`{}`), nil))
+	problems, err = db.Verify()
+	if assert.NoError(t, err) && assert.Len(t, problems, 5) {
+		assert.Contains(t, problems[0], "other")
+		assert.Contains(t, problems[1], swapPrefix+"GB")
+		assert.Contains(t, problems[2], swapPrefix+"GC")
+		assert.Contains(t, problems[3], swapPrefix+"GE")
+		assert.Contains(t, problems[4], swapPrefix+"GF")
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/network"
+	"github.com/stellar/go/txnbuild"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/swapdb"
+	"github.com/threefoldtech/atomicswap/swapcrypto"
+)
+
+//defaultSwapDBPath returns the directory of the swap database in the home directory of the user
+func defaultSwapDBPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".stellaratomicswap", "swaps")
+}
+
+//swapDBLock serializes the access to the database within the process, the daemon updates it from multiple requests
+var swapDBLock sync.Mutex
+
+//openSwapDB opens the swap database, retrying for a while when another process has it open
+func openSwapDB() (db *swapdb.DB, err error) {
+	if err = os.MkdirAll(filepath.Dir(*swapDBFlag), 0700); err != nil {
+		return
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		db, err = swapdb.Open(*swapDBFlag)
+		if err == nil || time.Now().After(deadline) {
+			return
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+//withSwapDB opens the swap database for the duration of f
+func withSwapDB(f func(db *swapdb.DB) error) error {
+	swapDBLock.Lock()
+	defer swapDBLock.Unlock()
+	db, err := openSwapDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return f(db)
+}
+
+//updateSwapDB runs update on the swap database unless it is disabled.
+//The transactions are already on the chain when the database is updated, so failures are only reported.
+func updateSwapDB(update func(db *swapdb.DB) error) {
+	if *swapDBFlag == "" {
+		return
+	}
+	if err := withSwapDB(update); err != nil {
+		logger.Warnf("Failed to update the swap database: %v", err)
+	}
+}
+
+//networkName returns public or testnet for the well known networks and the passphrase otherwise
+func networkName(passphrase string) string {
+	switch passphrase {
+	case network.PublicNetworkPassphrase:
+		return "public"
+	case network.TestNetworkPassphrase:
+		return "testnet"
+	}
+	return passphrase
+}
+
+func assetName(asset txnbuild.Asset) string {
+	if asset.IsNative() {
+		return "XLM"
+	}
+	return asset.GetCode() + ":" + asset.GetIssuer()
+}
+
+//newSwapRecord creates the record of a holding account that is about to be set up
+func newSwapRecord(role string, holdingAccount *keypair.Full, funder string, counterparty string, amount string, asset txnbuild.Asset, secret []byte, secretHash []byte, locktime time.Time, networkPassphrase string) *swapdb.Swap {
+	record := &swapdb.Swap{
+		HoldingAccount: holdingAccount.Address(),
+		HoldingSeed:    holdingAccount.Seed(),
+		Role:           role,
+		Network:        networkName(networkPassphrase),
+		Asset:          assetName(asset),
+		Amount:         amount,
+		Funder:         funder,
+		Counterparty:   counterparty,
+		SecretHash:     hex.EncodeToString(secretHash),
+		Locktime:       locktime,
+		Status:         swapdb.StatusSettingUp,
+	}
+	if secret != nil {
+		record.Secret = hex.EncodeToString(secret)
+	}
+	return record
+}
+
+//recordSetup stores the outcome of the setup of a holding account
+func recordSetup(record *swapdb.Swap, progress *setupProgress, refundTransaction *txnbuild.Transaction, setupErr error) {
+	updateSwapDB(func(db *swapdb.DB) error {
+		progress.mu.Lock()
+		record.CompletedSteps = append([]string(nil), progress.completed...)
+		progress.mu.Unlock()
+		if setupErr != nil {
+			record.Status = swapdb.StatusFailed
+			record.Error = setupErr.Error()
+		} else {
+			record.Status = swapdb.StatusLocked
+			record.Error = ""
+			//the master key of the holding account has no weight anymore
+			record.HoldingSeed = ""
+		}
+		if refundTransaction != nil {
+			if txe, err := refundTransaction.Base64(); err == nil {
+				record.RefundTransaction = txe
+			}
+		}
+		return db.Put(record)
+	})
+}
+
+//recordRedeem marks the own swaps with the hash of the secret as redeemed
+func recordRedeem(holdingAccount string, secret []byte, transactionHash string, networkPassphrase string) {
+	updateSwapDB(func(db *swapdb.DB) error {
+		swaps, err := db.FindBySecretHash(hex.EncodeToString(swapcrypto.Sha256Hash(secret)))
+		if err != nil {
+			return err
+		}
+		for _, swap := range swaps {
+			if swap.HoldingAccount == holdingAccount || swap.Network != networkName(networkPassphrase) {
+				continue
+			}
+			swap.Status = swapdb.StatusRedeemed
+			swap.CounterContract = holdingAccount
+			swap.RedeemTransaction = transactionHash
+			if err = db.Put(&swap); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+//recordRefund marks the swap of the holding account as refunded
+func recordRefund(holdingAccount string, transactionHash string) {
+	updateSwapDB(func(db *swapdb.DB) error {
+		swap, err := db.Get(holdingAccount)
+		if err == swapdb.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		swap.Status = swapdb.StatusRefunded
+		swap.RefundTxHash = transactionHash
+		return db.Put(&swap)
+	})
+}
+
+//refundedHoldingAccount returns the holding account merged by a refund transaction
+func refundedHoldingAccount(refundTx *txnbuild.Transaction) string {
+	for _, operation := range refundTx.Operations {
+		if accountMerge, ok := operation.(*txnbuild.AccountMerge); ok && accountMerge.SourceAccount != nil {
+			return accountMerge.SourceAccount.GetAccountID()
+		}
+	}
+	if refundTx.SourceAccount != nil {
+		return refundTx.SourceAccount.GetAccountID()
+	}
+	return ""
+}
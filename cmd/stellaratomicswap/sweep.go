@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/stellar/go/amount"
+	"github.com/stellar/go/clients/horizon"
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/txnbuild"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/swapdb"
+)
+
+//sweepCmd merges a holding account whose setup never completed back to the account that funded it.
+//As long as the signing options are not set, the holding account seed still controls the account.
+type sweepCmd struct {
+	//holdingKeyPair is nil when the holding account is looked up in the swap database
+	holdingKeyPair *keypair.Full
+	holdingAccount string
+}
+
+func (cmd *sweepCmd) runCommand(client horizonclient.ClientInterface) error {
+	funder := ""
+	if cmd.holdingKeyPair == nil {
+		record, err := getSweepRecord(cmd.holdingAccount)
+		if err != nil {
+			return err
+		}
+		if cmd.holdingKeyPair, err = recordedHoldingKeyPair(record); err != nil {
+			return err
+		}
+		funder = record.Funder
+	}
+	holdingAccount, exists, err := getOptionalAccount(cmd.holdingAccount, client)
+	if err != nil {
+		return fmt.Errorf("Failed to get holding account %s: %v", cmd.holdingAccount, err)
+	}
+	if !exists {
+		if err = checkNotMerged(cmd.holdingAccount, client); err != nil {
+			return err
+		}
+		return fmt.Errorf("Holding account %s does not exist, there is nothing to sweep", cmd.holdingAccount)
+	}
+	if masterWeight(holdingAccount) == 0 {
+		return fmt.Errorf("The setup of holding account %s completed, it can only be redeemed or refunded", cmd.holdingAccount)
+	}
+	if funder == "" {
+		lifecycle, err := stellar.GetAccountLifecycle(cmd.holdingAccount, client)
+		if err != nil {
+			return fmt.Errorf("Failed to get the creation of holding account %s: %v", cmd.holdingAccount, err)
+		}
+		if lifecycle.Created == nil {
+			return fmt.Errorf("Unable to find the account that funded holding account %s", cmd.holdingAccount)
+		}
+		funder = lifecycle.Created.Funder
+	}
+
+	tx := txnbuild.Transaction{
+		SourceAccount: holdingAccount,
+		Operations:    createSweepOperations(holdingAccount, funder),
+		Timebounds:    setupTimebounds(),
+		Network:       targetNetwork,
+		BaseFee:       suggestBaseFee(client),
+	}
+	txe, err := tx.BuildSignEncode(cmd.holdingKeyPair)
+	if err != nil {
+		return fmt.Errorf("Failed to sign the sweep transaction: %v", err)
+	}
+	result, err := stellar.SubmitTransaction(txe, client)
+	if err != nil {
+		return err
+	}
+	recordRefund(cmd.holdingAccount, result.Hash)
+	received := getReceivedAmounts(result.Hash, funder, client)
+	if !*automatedFlag {
+		fmt.Printf("Swept holding account %s into %s\n", cmd.holdingAccount, funder)
+		fmt.Println(result.TransactionSuccessToString())
+		printReceivedAmounts(received)
+	} else {
+		output := struct {
+			HoldingAccount   string                   `json:"holdingaccount"`
+			Funder           string                   `json:"funder"`
+			SweepTransaction string                   `json:"sweeptransaction"`
+			Received         []stellar.CreditedAmount `json:"received,omitempty"`
+		}{cmd.holdingAccount, funder, result.Hash, received}
+		jsonoutput, _ := json.Marshal(output)
+		fmt.Println(string(jsonoutput))
+	}
+	return nil
+}
+
+//getSweepRecord gets the recorded swap of a holding account, the holding account seed is only recorded until its setup completes
+func getSweepRecord(holdingAccount string) (record swapdb.Swap, err error) {
+	if *swapDBFlag == "" {
+		err = errors.New("The swap database is disabled, pass the holding account seed instead")
+		return
+	}
+	err = withSwapDB(func(db *swapdb.DB) (err error) {
+		record, err = db.Get(holdingAccount)
+		return
+	})
+	if err != nil {
+		err = fmt.Errorf("Failed to get swap %s: %v", holdingAccount, err)
+		return
+	}
+	if record.Network != networkName(targetNetwork) {
+		err = fmt.Errorf("Swap %s is on the %s network", record.HoldingAccount, record.Network)
+	}
+	return
+}
+
+//recordedHoldingKeyPair parses the holding account seed recorded for a swap
+func recordedHoldingKeyPair(record swapdb.Swap) (*keypair.Full, error) {
+	registerSecret(record.HoldingSeed)
+	holdingKeyPair, err := keypair.Parse(record.HoldingSeed)
+	if err != nil {
+		return nil, fmt.Errorf("The holding account seed of swap %s is not recorded, its setup has completed", record.HoldingAccount)
+	}
+	full, ok := holdingKeyPair.(*keypair.Full)
+	if !ok || full.Address() != record.HoldingAccount {
+		return nil, fmt.Errorf("The holding account seed of swap %s is not recorded, its setup has completed", record.HoldingAccount)
+	}
+	return full, nil
+}
+
+//masterWeight returns the weight of the master key of an account
+func masterWeight(account *horizon.Account) int32 {
+	for _, signer := range account.Signers {
+		if signer.Key == account.AccountID {
+			return signer.Weight
+		}
+	}
+	return 0
+}
+
+//createSweepOperations returns the operations that empty and merge a holding account.
+//A failed setup can leave an empty trustline, a payment of nothing is not valid so only the trustline is removed.
+func createSweepOperations(holdingAccount *horizon.Account, funder string) (sweepOperations []txnbuild.Operation) {
+	for _, operation := range createRedeemOperations(holdingAccount, funder) {
+		if payment, ok := operation.(*txnbuild.Payment); ok {
+			if balance, err := amount.ParseInt64(payment.Amount); err == nil && balance == 0 {
+				continue
+			}
+		}
+		sweepOperations = append(sweepOperations, operation)
+	}
+	return
+}
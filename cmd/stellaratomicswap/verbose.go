@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+//horizonLoggingTransport logs every horizon request and the hash of every submitted transaction at debug level
+type horizonLoggingTransport struct {
+	next http.RoundTripper
+}
+
+func (t horizonLoggingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	start := time.Now()
+	response, err := t.next.RoundTrip(request)
+	fields := logrus.Fields{"method": request.Method, "url": request.URL.String(), "duration": time.Since(start).Round(time.Millisecond)}
+	if err != nil {
+		logger.WithFields(fields).Debugf("Horizon request failed: %v", err)
+		return response, err
+	}
+	fields["status"] = response.StatusCode
+	logger.WithFields(fields).Debug("Horizon request")
+	if request.Method == http.MethodPost && strings.HasSuffix(request.URL.Path, "/transactions") {
+		logSubmittedTransaction(response)
+	}
+	return response, nil
+}
+
+//logSubmittedTransaction logs the hash from the answer to a transaction submission, leaving the body for the client
+func logSubmittedTransaction(response *http.Response) {
+	body, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	var submitted struct {
+		Hash string `json:"hash"`
+	}
+	if json.Unmarshal(body, &submitted) == nil && submitted.Hash != "" {
+		logger.WithField("transaction", submitted.Hash).Debug("Transaction submitted")
+	}
+}
+
+//horizonTransport wraps the transport of a horizon client to retry the failed requests
+//and to log every attempt when -verbose is set
+func horizonTransport(transport http.RoundTripper) http.RoundTripper {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if *verboseFlag {
+		transport = horizonLoggingTransport{next: transport}
+	}
+	if *horizonRetriesFlag > 0 {
+		transport = horizonRetryTransport{next: transport, retries: *horizonRetriesFlag}
+	}
+	return transport
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/swapdb"
+)
+
+//verifyStateCmd checks that every record in the swap database can be read by this version of the tool
+type verifyStateCmd struct{}
+
+//stateVerification is the automated output of state verify
+type stateVerification struct {
+	Version  int      `json:"version"`
+	Problems []string `json:"problems"`
+}
+
+func (cmd *verifyStateCmd) runCommand(client horizonclient.ClientInterface) error {
+	return cmd.runOfflineCommand()
+}
+
+func (cmd *verifyStateCmd) runOfflineCommand() error {
+	if *swapDBFlag == "" {
+		return errors.New("The swap database is disabled")
+	}
+	verification := stateVerification{Version: swapdb.Version, Problems: []string{}}
+	err := withSwapDB(func(db *swapdb.DB) error {
+		problems, err := db.Verify()
+		verification.Problems = append(verification.Problems, problems...)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to verify the swap database: %v", err)
+	}
+	if *automatedFlag {
+		jsonoutput, _ := json.Marshal(verification)
+		fmt.Println(string(jsonoutput))
+	} else {
+		for _, problem := range verification.Problems {
+			fmt.Println(problem)
+		}
+	}
+	if len(verification.Problems) > 0 {
+		return fmt.Errorf("%d records of the swap database can not be read by this version of the tool", len(verification.Problems))
+	}
+	if !*automatedFlag {
+		fmt.Printf("The swap database has format version %d and all records can be read\n", swapdb.Version)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/stellar/go/clients/horizonclient"
+	hprotocol "github.com/stellar/go/protocols/horizon"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+)
+
+//waitRedeemPollInterval is the time between two checks of the holding account
+const waitRedeemPollInterval = 10 * time.Second
+
+//waitRedeemCmd blocks until the holding account is redeemed and prints the revealed secret
+type waitRedeemCmd struct {
+	holdingAccountAddress string
+	secretHash            string
+}
+
+func (cmd *waitRedeemCmd) runCommand(client horizonclient.ClientInterface) error {
+	secret, transaction, err := waitForSecret(cmd.holdingAccountAddress, cmd.secretHash, waitRedeemPollInterval, client)
+	if err != nil {
+		return err
+	}
+	printExtractedSecret(secret, transaction)
+	return nil
+}
+
+//waitForSecret polls the holding account until the secret is revealed.
+//Horizon errors are reported and retried, it stops when the holding account is refunded since the secret is then never revealed.
+func waitForSecret(holdingAccountAddress string, secretHash string, pollInterval time.Duration, client horizonclient.ClientInterface) ([]byte, hprotocol.Transaction, error) {
+	for {
+		secret, transaction, err := extractSecretTransaction(holdingAccountAddress, secretHash, client)
+		if err == nil {
+			return secret, transaction, nil
+		}
+		if err != errNotRedeemed {
+			status, statusErr := getContractStatus(holdingAccountAddress, client)
+			if statusErr == nil && status.Status == contractStatusRefunded {
+				return nil, transaction, fmt.Errorf("The holding account was refunded in transaction %s, the secret is not revealed", status.Transaction)
+			}
+			logger.WithField("holdingaccount", holdingAccountAddress).Warnf("Failed to extract the secret, retrying: %v", err)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+//followSecret streams the transactions of the holding account from its creation on, so a redeem that already happened is found as well,
+//and returns the secret as soon as a transaction reveals it. It stops when the holding account is merged without revealing the secret.
+func followSecret(holdingAccountAddress string, secretHash string, client horizonclient.ClientInterface) (secret []byte, revealing hprotocol.Transaction, err error) {
+	rawSecretHash, err := hex.DecodeString(secretHash)
+	if err != nil {
+		return nil, revealing, fmt.Errorf("Invalid secret hash: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	request := horizonclient.TransactionRequest{ForAccount: holdingAccountAddress, Cursor: "0"}
+	//mergeHash is the transaction that merged the holding account, once it no longer exists
+	mergeHash := ""
+	streamErr := client.StreamTransactions(ctx, request, func(transaction hprotocol.Transaction) {
+		if secret = findSecret(transaction, rawSecretHash); secret != nil {
+			revealing = transaction
+			cancel()
+			return
+		}
+		if mergeHash == "" {
+			if _, exists, accountErr := getOptionalAccount(holdingAccountAddress, client); accountErr == nil && !exists {
+				if lifecycle, lifecycleErr := stellar.GetAccountLifecycle(holdingAccountAddress, client); lifecycleErr == nil && lifecycle.Merged != nil {
+					mergeHash = lifecycle.Merged.TransactionHash
+				}
+			}
+		}
+		//the transaction that merged the holding account without revealing the secret is a refund
+		if transaction.Hash == mergeHash {
+			err = fmt.Errorf("The holding account was merged in transaction %s without revealing the secret", transaction.Hash)
+			cancel()
+		}
+	})
+	if secret != nil || err != nil {
+		return
+	}
+	if streamErr != nil {
+		return nil, revealing, fmt.Errorf("Failed to stream the transactions of the holding account: %v", streamErr)
+	}
+	return nil, revealing, errors.New("The transaction stream of the holding account ended")
+}
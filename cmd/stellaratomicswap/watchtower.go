@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/stellar"
+	"github.com/threefoldtech/atomicswap/cmd/stellaratomicswap/swapdb"
+)
+
+//watchtowerPollInterval is the time between two checks of the recorded swaps
+const watchtowerPollInterval = time.Minute
+
+//watchtowerCmd refunds the recorded swaps of the network as soon as their locktime passes
+type watchtowerCmd struct {
+	notifiers notifiers
+	//alertBefore is how long before the locktime of a swap the operators are alerted
+	alertBefore time.Duration
+	//merged holds the holding accounts that no longer exist, they are not checked again
+	merged map[string]bool
+	//alerted holds the holding accounts the approaching locktime was notified for
+	alerted map[string]bool
+}
+
+func (cmd *watchtowerCmd) runCommand(client horizonclient.ClientInterface) error {
+	if *swapDBFlag == "" {
+		return errors.New("The swap database is disabled, the watchtower has no swaps to watch")
+	}
+	cmd.merged = make(map[string]bool)
+	cmd.alerted = make(map[string]bool)
+	logger.Infof("watchtower watching the %s swaps in %s", networkName(targetNetwork), *swapDBFlag)
+	for {
+		if err := cmd.checkSwaps(client); err != nil {
+			logger.Errorf("Failed to check the swaps: %v", err)
+		}
+		time.Sleep(watchtowerPollInterval)
+	}
+}
+
+//checkSwaps submits the stored refund transaction of every locked swap whose locktime passed
+//and alerts the operators when a locktime approaches or a participant has to redeem.
+//A refund horizon rejects, for example because the ledger time did not reach the locktime yet, is retried the next round.
+func (cmd *watchtowerCmd) checkSwaps(client horizonclient.ClientInterface) error {
+	var swaps []swapdb.Swap
+	err := withSwapDB(func(db *swapdb.DB) (err error) {
+		swaps, err = db.List()
+		return
+	})
+	if err != nil {
+		return err
+	}
+	network := networkName(targetNetwork)
+	for _, swap := range swaps {
+		if swap.Network != network || swap.Status != swapdb.StatusLocked || swap.RefundTransaction == "" || cmd.merged[swap.HoldingAccount] {
+			continue
+		}
+		expired := !time.Now().Before(swap.Locktime)
+		//the holding accounts of participants are always checked, the initiator redeeming them reveals the secret
+		if !expired && swap.Role != "participant" && time.Until(swap.Locktime) > cmd.alertBefore {
+			continue
+		}
+		_, exists, err := getOptionalAccount(swap.HoldingAccount, client)
+		if err != nil {
+			logger.WithField("holdingaccount", swap.HoldingAccount).Warnf("Failed to get the holding account: %v", err)
+			continue
+		}
+		if !exists {
+			//redeemed by the counterparty or refunded by someone else
+			cmd.merged[swap.HoldingAccount] = true
+			if swap.Role == "participant" {
+				cmd.notifiers.notify(eventActionRequired, map[string]string{
+					"holdingaccount": swap.HoldingAccount,
+					"secrethash":     swap.SecretHash,
+					"action":         "The initiator redeemed this holding account, extract the secret and redeem the initiator's contract before its locktime",
+				})
+			}
+			continue
+		}
+		if !expired {
+			if !cmd.alerted[swap.HoldingAccount] && time.Until(swap.Locktime) <= cmd.alertBefore {
+				cmd.alerted[swap.HoldingAccount] = true
+				cmd.notifiers.notify(eventLocktimeApproaching, map[string]string{
+					"holdingaccount": swap.HoldingAccount,
+					"secrethash":     swap.SecretHash,
+					"locktime":       swap.Locktime.UTC().Format(time.RFC3339),
+				})
+			}
+			continue
+		}
+		result, err := stellar.SubmitTransaction(swap.RefundTransaction, client)
+		if err != nil {
+			logger.WithField("holdingaccount", swap.HoldingAccount).Warnf("Failed to refund: %v", err)
+			continue
+		}
+		recordRefund(swap.HoldingAccount, result.Hash)
+		cmd.notifiers.notify(eventRefunded, map[string]string{"holdingaccount": swap.HoldingAccount, "secrethash": swap.SecretHash, "refundtransaction": result.Hash})
+		logger.WithFields(logrus.Fields{"holdingaccount": swap.HoldingAccount, "transaction": result.Hash}).Infof("Refunded to %s", swap.Funder)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+//webhookSecretVariable is the environment variable holding the key the webhook payloads are signed with
+const webhookSecretVariable = "WEBHOOK_SECRET"
+
+//webhookSignatureHeader holds the hex encoded HMAC-SHA256 of the payload
+const webhookSignatureHeader = "X-Atomicswap-Signature"
+
+//webhook posts the events as json
+type webhook struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+func newWebhook(url string) (*webhook, error) {
+	secret := os.Getenv(webhookSecretVariable)
+	if secret == "" {
+		return nil, fmt.Errorf("%s is not set, webhook payloads are always signed", webhookSecretVariable)
+	}
+	return &webhook{url: url, secret: []byte(secret), client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+//deliver posts the signed event
+func (w *webhook) deliver(event swapEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	mac := hmac.New(sha256.New, w.secret)
+	mac.Write(payload)
+	return w.post(payload, hex.EncodeToString(mac.Sum(nil)))
+}
+
+func (w *webhook) post(payload []byte, signature string) error {
+	request, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(webhookSignatureHeader, "sha256="+signature)
+	response, err := w.client.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("The webhook answered %s", response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+//Package swapcrypto contains the cryptographic helpers the swap tools of the different chains share:
+//creating secrets, hashing them with the hash function a chain uses to lock contracts and
+//handling them without leaking them.
+package swapcrypto
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+)
+
+//SecretSize is the default size of the secrets created for a swap
+const SecretSize = 32
+
+//MinSecretSize is the smallest secret that can not be guessed
+const MinSecretSize = 16
+
+//HashFunction is a hash function that locks swap contracts
+type HashFunction int
+
+const (
+	//SHA256 is used by all chains supported so far
+	SHA256 HashFunction = iota
+)
+
+var hashFunctionNames = map[HashFunction]string{
+	SHA256: "sha256",
+}
+
+//ParseHashFunction returns the hash function with the name, like sha256
+func ParseHashFunction(name string) (HashFunction, error) {
+	for h, hashName := range hashFunctionNames {
+		if strings.EqualFold(name, hashName) {
+			return h, nil
+		}
+	}
+	return 0, fmt.Errorf("Unsupported hash function %q", name)
+}
+
+func (h HashFunction) String() string {
+	if name, ok := hashFunctionNames[h]; ok {
+		return name
+	}
+	return fmt.Sprintf("HashFunction(%d)", int(h))
+}
+
+//Size returns the size of the hashes in bytes
+func (h HashFunction) Size() int {
+	switch h {
+	case SHA256:
+		return sha256.Size
+	}
+	panic("swapcrypto: unknown hash function " + h.String())
+}
+
+//Sum returns the hash of x
+func (h HashFunction) Sum(x []byte) []byte {
+	switch h {
+	case SHA256:
+		hash := sha256.Sum256(x)
+		return hash[:]
+	}
+	panic("swapcrypto: unknown hash function " + h.String())
+}
+
+//Verify returns true if secretHash is the hash of secret, the comparison is constant time
+func (h HashFunction) Verify(secret []byte, secretHash []byte) bool {
+	return Equal(h.Sum(secret), secretHash)
+}
+
+//GenerateSecret creates a random secret and its hash
+func (h HashFunction) GenerateSecret() (secret []byte, secretHash []byte, err error) {
+	return h.GenerateSecretOfSize(SecretSize)
+}
+
+//GenerateSecretOfSize creates a random secret of size bytes and its hash, for chains that use other secret sizes
+func (h HashFunction) GenerateSecretOfSize(size int) (secret []byte, secretHash []byte, err error) {
+	if size < MinSecretSize {
+		return nil, nil, fmt.Errorf("A secret of %d bytes is too small, at least %d bytes are needed", size, MinSecretSize)
+	}
+	secret = make([]byte, size)
+	if _, err = rand.Read(secret); err != nil {
+		return nil, nil, err
+	}
+	return secret, h.Sum(secret), nil
+}
+
+//GenerateSecretWithEntropy creates a secret of size bytes from random bytes mixed with entropy supplied by the caller, and its hash.
+//The secret is the SHA-512 hash of both, so it is never weaker than the random bytes alone and it is at most 64 bytes.
+func (h HashFunction) GenerateSecretWithEntropy(size int, entropy []byte) (secret []byte, secretHash []byte, err error) {
+	if size > sha512.Size {
+		return nil, nil, fmt.Errorf("A secret mixed with entropy can be at most %d bytes", sha512.Size)
+	}
+	random, _, err := h.GenerateSecretOfSize(size)
+	if err != nil {
+		return
+	}
+	defer Zero(random)
+	mixer := sha512.New()
+	mixer.Write(random)
+	mixer.Write(entropy)
+	secret = mixer.Sum(nil)[:size]
+	return secret, h.Sum(secret), nil
+}
+
+//Sha256Hash returns the SHA-256 hash of x
+func Sha256Hash(x []byte) []byte {
+	return SHA256.Sum(x)
+}
+
+//Equal compares a and b in constant time, only the length is leaked
+func Equal(a []byte, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
+//Zero overwrites a secret in memory once it is no longer needed
+func Zero(secret []byte) {
+	for i := range secret {
+		secret[i] = 0
+	}
+}
@@ -0,0 +1,49 @@
+package swapcrypto
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashFunction(t *testing.T) {
+	h, err := ParseHashFunction("SHA256")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, SHA256, h)
+	assert.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", hex.EncodeToString(h.Sum(nil)))
+	_, err = ParseHashFunction("md5")
+	assert.Error(t, err)
+
+	secret, secretHash, err := h.GenerateSecret()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, secret, SecretSize)
+	assert.Len(t, secretHash, h.Size())
+	assert.True(t, h.Verify(secret, secretHash))
+	Zero(secret)
+	assert.Equal(t, make([]byte, SecretSize), secret)
+	assert.False(t, h.Verify(secret, secretHash))
+
+	secret, secretHash, err = h.GenerateSecretOfSize(20)
+	if assert.NoError(t, err) {
+		assert.Len(t, secret, 20)
+		assert.True(t, h.Verify(secret, secretHash))
+	}
+	_, _, err = h.GenerateSecretOfSize(MinSecretSize - 1)
+	assert.Error(t, err)
+
+	entropy := []byte("wallet backup")
+	mixed, mixedHash, err := h.GenerateSecretWithEntropy(SecretSize, entropy)
+	if assert.NoError(t, err) {
+		assert.Len(t, mixed, SecretSize)
+		assert.True(t, h.Verify(mixed, mixedHash))
+	}
+	again, _, err := h.GenerateSecretWithEntropy(SecretSize, entropy)
+	if assert.NoError(t, err) {
+		assert.NotEqual(t, mixed, again)
+	}
+}